@@ -0,0 +1,55 @@
+package api
+
+import (
+	"go-burrokuchen/core"
+	"go-burrokuchen/utils"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// scheduleBackups backs up the database and the wallet file to the backup directory at the given interval while the
+// API runs, removing the oldest backups above the configured retention
+func (s *Server) scheduleBackups(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := s.backup()
+		if err != nil {
+			log.WithError(err).Error("Failed to back up the blockchain")
+		}
+	}
+}
+
+// backup takes a backup and applies the retention, which keeps every backup when it is 0
+func (s *Server) backup() error {
+	err := s.withBlockchain(func(bc *core.Blockchain) error {
+		manifest, err := bc.Backup(s.cfg.BackupConfig.Dir)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		log.WithFields(log.Fields{"dir": manifest.Dir, "height": manifest.Height}).Info("Blockchain backed up")
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if s.cfg.BackupConfig.Retention <= 0 {
+		return nil
+	}
+
+	removed, err := core.PruneBackups(s.cfg.BackupConfig.Dir, s.cfg.BackupConfig.Retention)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, dir := range removed {
+		log.WithField("dir", dir).Info("Old backup removed")
+	}
+
+	return nil
+}
@@ -0,0 +1,440 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/utils"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Subscription topics
+const (
+	TopicNewBlock      = "newBlock"
+	TopicNewTx         = "newTx"
+	TopicAddressPrefix = "address:"
+)
+
+// Event types
+const (
+	EventBlockConnected          = "blockConnected"
+	EventBlockDisconnected       = "blockDisconnected"
+	EventTransactionConnected    = "transactionConnected"
+	EventTransactionDisconnected = "transactionDisconnected"
+	EventTransactionAccepted     = "transactionAccepted"
+)
+
+// Event is pushed to the subscribers of a topic
+type Event struct {
+	Topic       string               `json:"topic"`
+	Type        string               `json:"type"`
+	Address     string               `json:"address,omitempty"`
+	BlockHash   string               `json:"block_hash,omitempty"`
+	Block       *BlockResponse       `json:"block,omitempty"`
+	Transaction *TransactionResponse `json:"transaction,omitempty"`
+}
+
+// subscriptionRequest is sent by clients to change their subscriptions
+type subscriptionRequest struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+// subscriptionResponse acknowledges a subscription request
+type subscriptionResponse struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// subscriber is a WebSocket client and the topics it listens to
+type subscriber struct {
+	conn   *wsConn
+	topics map[string]bool
+	send   chan []byte
+	once   sync.Once
+}
+
+// close disconnects the subscriber
+func (sub *subscriber) close() {
+	sub.once.Do(func() {
+		close(sub.send)
+		sub.conn.Close()
+	})
+}
+
+// Hub keeps track of subscribers and fans events out to them
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]bool
+}
+
+// NewHub generates and returns an empty hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*subscriber]bool)}
+}
+
+// Publish sends an event to every subscriber of its topic, dropping subscribers that cannot keep up
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var message []byte
+
+	for sub := range h.subscribers {
+		if !sub.topics[event.Topic] {
+			continue
+		}
+
+		if message == nil {
+			encoded, err := json.Marshal(event)
+			if err != nil {
+				log.WithError(err).Error("Failed to encode event")
+				return
+			}
+
+			message = encoded
+		}
+
+		select {
+		case sub.send <- message:
+		default:
+			log.Warn("Dropping slow websocket subscriber")
+			delete(h.subscribers, sub)
+			sub.close()
+		}
+	}
+}
+
+// add registers a subscriber
+func (h *Hub) add(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subscribers[sub] = true
+}
+
+// remove unregisters and disconnects a subscriber
+func (h *Hub) remove(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers, sub)
+	sub.close()
+}
+
+// update changes the topics of a subscriber and returns the resulting list
+func (h *Hub) update(sub *subscriber, action string, topics []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, topic := range topics {
+		if action == "subscribe" {
+			sub.topics[topic] = true
+		} else {
+			delete(sub.topics, topic)
+		}
+	}
+
+	var current []string
+	for topic := range sub.topics {
+		current = append(current, topic)
+	}
+
+	return current
+}
+
+// handleWebSocket upgrades the connection and serves subscription requests
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r, s.cfg.APIConfig)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	sub := &subscriber{conn: conn, topics: make(map[string]bool), send: make(chan []byte, 64)}
+	s.hub.add(sub)
+	defer s.hub.remove(sub)
+
+	go func() {
+		for message := range sub.send {
+			err := conn.WriteMessage(opText, message)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		response := s.handleSubscriptionRequest(sub, message)
+
+		encoded, err := json.Marshal(response)
+		if err != nil {
+			return
+		}
+
+		err = conn.WriteMessage(opText, encoded)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleSubscriptionRequest validates and applies a subscription request
+func (s *Server) handleSubscriptionRequest(sub *subscriber, message []byte) subscriptionResponse {
+	var request subscriptionRequest
+
+	err := json.Unmarshal(message, &request)
+	if err != nil {
+		return subscriptionResponse{Type: "error", Error: "invalid request"}
+	}
+
+	if request.Action != "subscribe" && request.Action != "unsubscribe" {
+		return subscriptionResponse{Type: "error", Error: "action must be subscribe or unsubscribe"}
+	}
+
+	for _, topic := range request.Topics {
+		err = s.validateTopic(topic)
+		if err != nil {
+			return subscriptionResponse{Type: "error", Error: err.Error()}
+		}
+	}
+
+	topics := s.hub.update(sub, request.Action, request.Topics)
+
+	return subscriptionResponse{Type: request.Action + "d", Topics: topics}
+}
+
+// validateTopic checks that a topic is known and that address topics hold a valid address
+func (s *Server) validateTopic(topic string) error {
+	if topic == TopicNewBlock || topic == TopicNewTx {
+		return nil
+	}
+
+	if address, ok := strings.CutPrefix(topic, TopicAddressPrefix); ok {
+		isValid, err := core.ValidateAddress(s.cfg, address)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if *isValid {
+			return nil
+		}
+
+		return fmt.Errorf("invalid address in topic %s", topic)
+	}
+
+	return fmt.Errorf("unknown topic %s", topic)
+}
+
+// watchChain polls the tip of the blockchain, publishes connected and disconnected blocks and delivers webhooks
+func (s *Server) watchChain(interval time.Duration) {
+	var lastTip []byte
+
+	for {
+		err := s.withBlockchain(func(bc *core.Blockchain) error {
+			if lastTip != nil && !bytes.Equal(lastTip, bc.Tip) {
+				err := s.publishChainChanges(bc, lastTip)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+			}
+
+			lastTip = bc.Tip
+
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to poll the blockchain")
+		}
+
+		err = s.processWebhooks()
+		if err != nil {
+			log.WithError(err).Error("Failed to process webhooks")
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// publishChainChanges publishes the blocks disconnected from the old tip and connected up to the current tip
+func (s *Server) publishChainChanges(bc *core.Blockchain, oldTip []byte) error {
+	var connected []*core.Block
+	newChain := make(map[string]bool)
+
+	bci := bc.InitializeIterator()
+
+	for {
+		block, err := bci.Prev()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if bytes.Equal(block.Hash, oldTip) {
+			break
+		}
+
+		connected = append(connected, block)
+		newChain[string(block.Hash)] = true
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	var disconnected []*core.Block
+
+	if len(connected) > 0 && len(connected[len(connected)-1].PrevBlockHash) == 0 {
+		// The old tip is not an ancestor of the new tip, walk it back to the fork point
+		bci = core.NewBlockchainIterator(s.cfg, oldTip, bc.Store)
+
+		var forkHash []byte
+
+		for {
+			block, err := bci.Prev()
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			if newChain[string(block.Hash)] {
+				forkHash = block.Hash
+				break
+			}
+
+			disconnected = append(disconnected, block)
+
+			if len(block.PrevBlockHash) == 0 {
+				break
+			}
+		}
+
+		for i, block := range connected {
+			if bytes.Equal(block.Hash, forkHash) {
+				connected = connected[:i]
+				break
+			}
+		}
+	}
+
+	height, err := bc.GetBestHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	forkHeight := *height - len(connected)
+
+	for i, block := range disconnected {
+		err = s.publishBlock(block, forkHeight+len(disconnected)-i, EventBlockDisconnected, EventTransactionDisconnected)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	for i := len(connected) - 1; i >= 0; i-- {
+		err = s.publishBlock(connected[i], *height-i, EventBlockConnected, EventTransactionConnected)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	return nil
+}
+
+// publishBlock publishes a block event and an event for every address its transactions touch
+func (s *Server) publishBlock(block *core.Block, height int, blockEvent string, transactionEvent string) error {
+	blockResponse, err := newBlockResponse(s.cfg, block, height)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	s.hub.Publish(Event{Topic: TopicNewBlock, Type: blockEvent, BlockHash: blockResponse.Hash, Block: blockResponse})
+
+	for i, tx := range block.Transactions {
+		transaction := blockResponse.Transactions[i]
+
+		addresses, err := s.transactionAddresses(tx)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, address := range addresses {
+			s.hub.Publish(Event{
+				Topic:       TopicAddressPrefix + address,
+				Type:        transactionEvent,
+				Address:     address,
+				BlockHash:   blockResponse.Hash,
+				Transaction: &transaction,
+			})
+		}
+	}
+
+	return nil
+}
+
+// publishTransaction publishes a transaction accepted into the mempool to the newTx topic and to the addresses it touches
+func (s *Server) publishTransaction(tx *core.Transaction) error {
+	transaction, err := newTransactionResponse(s.cfg, tx)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	s.hub.Publish(Event{Topic: TopicNewTx, Type: EventTransactionAccepted, Transaction: transaction})
+
+	addresses, err := s.transactionAddresses(tx)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, address := range addresses {
+		s.hub.Publish(Event{
+			Topic:       TopicAddressPrefix + address,
+			Type:        EventTransactionAccepted,
+			Address:     address,
+			Transaction: transaction,
+		})
+	}
+
+	return nil
+}
+
+// transactionAddresses returns the addresses whose public key hash is spent from or paid to by a transaction
+func (s *Server) transactionAddresses(tx *core.Transaction) ([]string, error) {
+	var addresses []string
+	seen := make(map[string]bool)
+
+	add := func(pubKeyHash []byte) {
+		address := string(core.EncodeAddress(s.cfg, pubKeyHash))
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	if !tx.IsCoinbase() {
+		for _, in := range tx.InputValue {
+			pubKeyHash, err := core.HashPubKey(in.PubKey)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			add(pubKeyHash)
+		}
+	}
+
+	for _, out := range tx.OutputValue {
+		add(out.PubKeyHash)
+	}
+
+	return addresses, nil
+}
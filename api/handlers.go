@@ -0,0 +1,629 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/utils"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// handleGetBlocks returns a page of blocks starting at the cursor (or the tip) and walking towards the genesis block
+func (s *Server) handleGetBlocks(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var cursor []byte
+	if from := r.URL.Query().Get("from"); from != "" {
+		cursor, err = parseHash(from)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	var page BlocksPageResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		if cursor == nil {
+			cursor = bc.Tip
+		}
+
+		height, err := bc.GetBlockHeight(cursor)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		page.Blocks = []BlockResponse{}
+		bci := core.NewBlockchainIterator(s.cfg, cursor, bc.Store)
+
+		for i := 0; i < limit; i++ {
+			block, err := bci.Prev()
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			blockResponse, err := newBlockResponse(s.cfg, block, *height-i)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			page.Blocks = append(page.Blocks, *blockResponse)
+			page.NextCursor = hex.EncodeToString(block.PrevBlockHash)
+
+			if len(block.PrevBlockHash) == 0 {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleGetBlock returns a block by its hash
+func (s *Server) handleGetBlock(w http.ResponseWriter, r *http.Request) {
+	hash, err := parseHash(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response *BlockResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		response, err = s.getBlockResponse(bc, hash)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetBlockByHeight returns a block by its height
+func (s *Server) handleGetBlockByHeight(w http.ResponseWriter, r *http.Request) {
+	height, err := strconv.Atoi(r.PathValue("height"))
+	if err != nil || height < 0 {
+		writeError(w, &badRequestError{message: "height must be a non-negative integer"})
+		return
+	}
+
+	var response *BlockResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		hash, err := bc.GetBlockHash(height)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response, err = s.getBlockResponse(bc, hash)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetRawBlock returns a serialized block by its hash
+func (s *Server) handleGetRawBlock(w http.ResponseWriter, r *http.Request) {
+	hash, err := parseHash(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response RawBlockResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		block, err := bc.GetBlock(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height, err := bc.GetBlockHeight(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		serializedBlock, err := block.SerializeBlock()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response = RawBlockResponse{Hash: hex.EncodeToString(hash), Height: *height, RawBlock: hex.EncodeToString(serializedBlock)}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetTransaction returns a confirmed transaction by its ID
+func (s *Server) handleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := parseHash(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response *TransactionResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		block, err := bc.FindTransactionBlock(transactionID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, transactionID) {
+				response, err = newTransactionResponse(s.cfg, tx)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+
+				response.BlockHash = hex.EncodeToString(block.Hash)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetTransactionProof returns a Merkle proof that a transaction is included in its block
+func (s *Server) handleGetTransactionProof(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := parseHash(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response *TransactionProofResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		block, err := bc.FindTransactionBlock(transactionID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		proof, err := block.ProveTransaction(transactionID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height, err := bc.GetBlockHeight(block.Hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response = newTransactionProofResponse(proof, *height)
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetHeaders returns block headers in ascending height, starting at the given height
+func (s *Server) handleGetHeaders(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	from := 0
+	if value := r.URL.Query().Get("from"); value != "" {
+		from, err = strconv.Atoi(value)
+		if err != nil || from < 0 {
+			writeError(w, &badRequestError{message: "from must be a non-negative integer"})
+			return
+		}
+	}
+
+	response := HeadersResponse{Headers: []BlockHeaderResponse{}}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		bestHeight, err := bc.GetBestHeight()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for height := from; height <= *bestHeight && height < from+limit; height++ {
+			hash, err := bc.GetBlockHash(height)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			// Headers are kept when blocks are pruned
+			header, err := bc.GetBlockHeader(hash)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			response.Headers = append(response.Headers, newBlockHeaderResponse(header, height))
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetFilters returns block filters in ascending height, starting at the given height
+func (s *Server) handleGetFilters(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	from := 0
+	if value := r.URL.Query().Get("from"); value != "" {
+		from, err = strconv.Atoi(value)
+		if err != nil || from < 0 {
+			writeError(w, &badRequestError{message: "from must be a non-negative integer"})
+			return
+		}
+	}
+
+	response := FiltersResponse{Filters: []BlockFilterResponse{}}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		bestHeight, err := bc.GetBestHeight()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for height := from; height <= *bestHeight && height < from+limit; height++ {
+			hash, err := bc.GetBlockHash(height)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			blockFilter, err := bc.GetBlockFilter(hash)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			response.Filters = append(response.Filters, newBlockFilterResponse(hash, height, blockFilter))
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetFilter returns the filter of a block by its hash
+func (s *Server) handleGetFilter(w http.ResponseWriter, r *http.Request) {
+	hash, err := parseHash(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response BlockFilterResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		blockFilter, err := bc.GetBlockFilter(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height, err := bc.GetBlockHeight(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response = newBlockFilterResponse(hash, *height, blockFilter)
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleSubmitTransaction adds a signed transaction to the mempool or, for requests on the local socket that name a
+// miner, mines it into a new block rewarding the miner. Anyone else could otherwise make the node mine for them
+func (s *Server) handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	var request SubmitTransactionRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, &badRequestError{message: "invalid request body"})
+		return
+	}
+
+	if request.Miner != "" {
+		if !isLocalRequest(r) {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "miner is only accepted on the local socket"})
+			return
+		}
+
+		_, err = s.parseAddress(request.Miner)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	rawTransaction, err := hex.DecodeString(request.RawTransaction)
+	if err != nil {
+		writeError(w, &badRequestError{message: "raw_transaction must be hex encoded"})
+		return
+	}
+
+	transaction, err := core.DeserializeTransaction(rawTransaction)
+	if err != nil || transaction.IsCoinbase() {
+		writeError(w, &badRequestError{message: "raw_transaction is not a valid transaction"})
+		return
+	}
+
+	response := SubmitTransactionResponse{TransactionID: hex.EncodeToString(transaction.ID)}
+
+	if request.Miner == "" {
+		err = s.withBlockchain(func(bc *core.Blockchain) error {
+			_, err := core.NewMempool(s.cfg, bc).Add(transaction)
+
+			return err
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		err = s.publishTransaction(transaction)
+		if err != nil {
+			log.WithError(err).Error("Failed to publish the accepted transaction")
+		}
+
+		writeJSON(w, http.StatusAccepted, response)
+		return
+	}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		utxoSet := core.NewUTXOSet(s.cfg, bc)
+
+		for _, in := range transaction.InputValue {
+			isUnspent, err := utxoSet.IsUnspent(in.TransactionID, in.OutputIndex)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			if !*isUnspent {
+				return &badRequestError{message: fmt.Sprintf("output %x:%d is already spent", in.TransactionID, in.OutputIndex)}
+			}
+		}
+
+		fee, err := bc.TransactionFee(transaction)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		coinbaseTransaction, err := core.NewCoinbaseTXWithFees(s.cfg, request.Miner, "", *fee)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		newBlock, err := bc.MineBlock([]*core.Transaction{coinbaseTransaction, transaction})
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response.BlockHash = hex.EncodeToString(newBlock.Hash)
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetAddressUTXOs returns the unspent outputs locked to an address
+func (s *Server) handleGetAddressUTXOs(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+
+	pubKeyHash, err := s.parseAddress(address)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := AddressUTXOsResponse{Address: address, UTXOs: []UTXOResponse{}}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		UTXOs, err := core.NewUTXOSet(s.cfg, bc).FindUTXOsByPubKeyHash(pubKeyHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, utxo := range UTXOs {
+			response.UTXOs = append(response.UTXOs, UTXOResponse{
+				TransactionID: hex.EncodeToString(utxo.TransactionID),
+				OutputIndex:   utxo.OutputIndex,
+				Value:         utxo.Output.Value,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetAddressBalance returns the balance of an address
+func (s *Server) handleGetAddressBalance(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+
+	pubKeyHash, err := s.parseAddress(address)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := BalanceResponse{Address: address}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		UTXOs, err := core.NewUTXOSet(s.cfg, bc).FindUTXOByPubKeyHash(pubKeyHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, out := range UTXOs.Outputs {
+			response.Balance += out.Value
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetStats returns a summary of the blockchain
+func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	response := StatsResponse{
+		TargetBits: s.cfg.ProofOfWorkConfig.TargetBits,
+		Subsidy:    s.cfg.TransactionConfig.Subsidy,
+	}
+
+	err := s.withBlockchain(func(bc *core.Blockchain) error {
+		height, err := bc.GetBestHeight()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		transactions, outputs, value, err := core.NewUTXOSet(s.cfg, bc).CountTransactions()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response.Height = *height
+		response.TipHash = hex.EncodeToString(bc.Tip)
+		response.PowAlgorithm = bc.PowAlgorithm
+		response.UTXOTransactions = *transactions
+		response.UTXOOutputs = *outputs
+		response.TotalSupply = *value
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// getBlockResponse loads a block and converts it into its JSON representation
+func (s *Server) getBlockResponse(bc *core.Blockchain, hash []byte) (*BlockResponse, error) {
+	block, err := bc.GetBlock(hash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	height, err := bc.GetBlockHeight(hash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	response, err := newBlockResponse(s.cfg, block, *height)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return response, nil
+}
+
+// parseLimit parses the page size, falling back to the configured default
+func (s *Server) parseLimit(value string) (int, error) {
+	if value == "" {
+		return s.cfg.APIConfig.DefaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > s.cfg.APIConfig.MaxPageLimit {
+		return 0, &badRequestError{message: "limit must be between 1 and " + strconv.Itoa(s.cfg.APIConfig.MaxPageLimit)}
+	}
+
+	return limit, nil
+}
+
+// parseAddress validates an address and returns its public key hash
+func (s *Server) parseAddress(address string) ([]byte, error) {
+	isValid, err := core.ValidateAddress(s.cfg, address)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		return nil, &badRequestError{message: "invalid address"}
+	}
+
+	return core.DecodeAddress(s.cfg, address), nil
+}
+
+// parseHash decodes a hex encoded block hash or transaction ID
+func parseHash(value string) ([]byte, error) {
+	hash, err := hex.DecodeString(value)
+	if err != nil || len(hash) != 32 {
+		return nil, &badRequestError{message: "hash must be 32 hex encoded bytes"}
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"go-burrokuchen/core"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerStatusCodes(t *testing.T) {
+	server, address := testNode(t)
+	cfg := server.cfg
+	genesis := hex.EncodeToString(server.bc.Tip)
+
+	for range 3 {
+		coinbase, err := core.NewCoinbaseTX(cfg, address, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = server.bc.MineBlock([]*core.Transaction{coinbase})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The genesis block is the only one deeper than the prune depth
+	cfg.DatabaseConfig.PruneDepth = 3
+
+	_, err := server.bc.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tip := hex.EncodeToString(server.bc.Tip)
+	unknown := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name          string
+		path          string
+		expected      int
+		expectedError string
+	}{
+		{name: "block", path: "/blocks/" + tip, expected: http.StatusOK},
+		{name: "block by height", path: "/blocks/height/3", expected: http.StatusOK},
+		{name: "unknown block", path: "/blocks/" + unknown, expected: http.StatusNotFound, expectedError: core.ErrBlockNotFound.Error()},
+		{name: "unknown raw block", path: "/blocks/raw/" + unknown, expected: http.StatusNotFound, expectedError: core.ErrBlockNotFound.Error()},
+		{name: "unknown height", path: "/blocks/height/4", expected: http.StatusNotFound, expectedError: core.ErrBlockNotFound.Error()},
+		{name: "invalid hash", path: "/blocks/abc", expected: http.StatusBadRequest, expectedError: "hash must be 32 hex encoded bytes"},
+		{name: "negative height", path: "/blocks/height/-1", expected: http.StatusBadRequest, expectedError: "height must be a non-negative integer"},
+		{name: "transaction that may be in a pruned block", path: "/tx/" + unknown, expected: http.StatusGone, expectedError: core.ErrBlockPruned.Error()},
+		{name: "pruned block", path: "/blocks/" + genesis, expected: http.StatusGone, expectedError: core.ErrBlockPruned.Error()},
+		{name: "pruned block by height", path: "/blocks/height/0", expected: http.StatusGone, expectedError: core.ErrBlockPruned.Error()},
+		{name: "balance", path: "/address/" + address + "/balance", expected: http.StatusOK},
+		{name: "balance of an invalid address", path: "/address/abc/balance", expected: http.StatusBadRequest, expectedError: "invalid address"},
+		{name: "UTXOs of an invalid address", path: "/address/abc/utxos", expected: http.StatusBadRequest, expectedError: "invalid address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			if recorder.Code != test.expected {
+				t.Fatalf("status is %d, expected %d", recorder.Code, test.expected)
+			}
+
+			if test.expectedError == "" {
+				return
+			}
+
+			var response errorResponse
+
+			err := json.NewDecoder(recorder.Body).Decode(&response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Error != test.expectedError {
+				t.Errorf("error is %q, expected %q", response.Error, test.expectedError)
+			}
+		})
+	}
+}
+
+func TestHandleGetTransactionNotFound(t *testing.T) {
+	server, _ := testNode(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tx/"+strings.Repeat("ab", 32), nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status is %d, expected %d", recorder.Code, http.StatusNotFound)
+	}
+
+	var response errorResponse
+
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Error != core.ErrTransactionNotFound.Error() {
+		t.Errorf("error is %q, expected %q", response.Error, core.ErrTransactionNotFound.Error())
+	}
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"go-burrokuchen/core"
+	"go-burrokuchen/utils"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// localOnly serves the handler only to the requests that came in on the local socket, as it exposes the state of the
+// node or writes files on its machine
+func localOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isLocalRequest(r) {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "only served on the local socket"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// handleGetMempool returns the transactions waiting to be mined
+func (s *Server) handleGetMempool(w http.ResponseWriter, r *http.Request) {
+	response := MempoolResponse{Entries: []MempoolEntryResponse{}}
+
+	err := s.withBlockchain(func(bc *core.Blockchain) error {
+		entries, err := core.NewMempool(s.cfg, bc).Entries()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, entry := range entries {
+			response.Entries = append(response.Entries, MempoolEntryResponse{
+				TransactionID: hex.EncodeToString(entry.Transaction.ID),
+				Fee:           entry.Fee,
+				Size:          entry.Size,
+				FeeRate:       entry.FeeRate(),
+				AddedAt:       entry.AddedAt,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetWebhooks returns the registered webhooks along with the state of their deliveries
+func (s *Server) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	response := WebhooksResponse{Webhooks: []WebhookResponse{}}
+
+	err := s.withBlockchain(func(bc *core.Blockchain) error {
+		webhooks := core.NewWebhooks(s.cfg, bc)
+
+		registered, err := webhooks.List()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		deliveries, err := webhooks.Deliveries()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, webhook := range registered {
+			webhookResponse := WebhookResponse{
+				ID:            webhook.ID,
+				Address:       webhook.Address,
+				URL:           webhook.URL,
+				Confirmations: webhook.Confirmations,
+				Deliveries:    []DeliveryResponse{},
+			}
+
+			for _, delivery := range deliveries {
+				if delivery.WebhookID != webhook.ID {
+					continue
+				}
+
+				webhookResponse.Deliveries = append(webhookResponse.Deliveries, DeliveryResponse{
+					Key:       delivery.Key(),
+					Status:    delivery.Status,
+					Attempts:  delivery.Attempts,
+					LastError: delivery.LastError,
+				})
+			}
+
+			response.Webhooks = append(response.Webhooks, webhookResponse)
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleTrackAddresses returns the unspent outputs and the history of a set of addresses
+func (s *Server) handleTrackAddresses(w http.ResponseWriter, r *http.Request) {
+	var request TrackAddressesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || len(request.Addresses) == 0 {
+		writeError(w, &badRequestError{message: "addresses must be given"})
+		return
+	}
+
+	var pubKeyHashes [][]byte
+
+	for _, address := range request.Addresses {
+		pubKeyHash, err := s.parseAddress(address)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		pubKeyHashes = append(pubKeyHashes, pubKeyHash)
+	}
+
+	response := TrackAddressesResponse{UTXOs: []UTXOResponse{}, History: []HistoryEntryResponse{}}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		tracker, err := bc.TrackAddresses(pubKeyHashes)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response.PrunedHeight = tracker.PrunedHeight
+
+		for _, utxo := range tracker.UTXOs {
+			response.UTXOs = append(response.UTXOs, UTXOResponse{
+				TransactionID: hex.EncodeToString(utxo.TransactionID),
+				OutputIndex:   utxo.OutputIndex,
+				Value:         utxo.Output.Value,
+			})
+		}
+
+		for _, entry := range tracker.History {
+			response.History = append(response.History, HistoryEntryResponse{
+				TransactionID: hex.EncodeToString(entry.TransactionID),
+				BlockHash:     hex.EncodeToString(entry.BlockHash),
+				Height:        entry.Height,
+				Timestamp:     entry.Timestamp,
+				Received:      entry.Received,
+				Sent:          entry.Sent,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetAnchor returns the earliest transaction anchoring a hash in a data output
+func (s *Server) handleGetAnchor(w http.ResponseWriter, r *http.Request) {
+	hash, err := parseHash(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response AnchorResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		transaction, block, err := bc.FindData(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height, err := bc.GetBlockHeight(block.Hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		bestHeight, err := bc.GetBestHeight()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response = AnchorResponse{
+			TransactionID: hex.EncodeToString(transaction.ID),
+			BlockHash:     hex.EncodeToString(block.Hash),
+			Height:        *height,
+			BestHeight:    *bestHeight,
+			Timestamp:     block.Timestamp,
+		}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleBackup takes a backup of the database and of the wallet file into the given directory
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	dest, err := parseFileRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response BackupResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		manifest, err := bc.Backup(dest)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response = BackupResponse{Dir: manifest.Dir, Height: manifest.Height, TipHash: manifest.TipHash, Wallet: manifest.Wallet != nil}
+
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleExportChain writes every block from the genesis block up to the tip to the given chain file
+func (s *Server) handleExportChain(w http.ResponseWriter, r *http.Request) {
+	path, err := parseFileRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response ExportChainResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		return writeFile(path, func(writer io.Writer) error {
+			return bc.ExportChain(writer, func(height int) {
+				response.Blocks = height + 1
+			})
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleDumpUTXOSnapshot writes the UTXO set to the given snapshot file
+func (s *Server) handleDumpUTXOSnapshot(w http.ResponseWriter, r *http.Request) {
+	path, err := parseFileRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response UTXOSnapshotResponse
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		snapshot, err := core.NewUTXOSet(s.cfg, bc).Snapshot()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response = UTXOSnapshotResponse{
+			Transactions: len(snapshot.Entries),
+			TipHash:      hex.EncodeToString(snapshot.TipHash),
+			Height:       snapshot.Height,
+			Hash:         hex.EncodeToString(snapshot.Hash),
+		}
+
+		return writeFile(path, snapshot.Write)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseFileRequest returns the absolute path of the file a request asks the node to write
+func parseFileRequest(r *http.Request) (string, error) {
+	var request FileRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || !filepath.IsAbs(request.Path) {
+		return "", &badRequestError{message: "path must be an absolute path"}
+	}
+
+	return request.Path, nil
+}
+
+// writeFile creates the file at path and writes it through a buffer, removing it when writing fails
+func writeFile(path string, write func(writer io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	// A file left incomplete could be mistaken for a complete one
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
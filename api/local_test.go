@@ -0,0 +1,242 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testChainConfig returns a configuration with what a blockchain kept in memory needs
+func testChainConfig() *model.Config {
+	cfg := testConfig()
+	cfg.DatabaseConfig = model.DatabaseConfig{
+		BlocksBucket:          "blocks",
+		UTXOSetBucket:         "utxo_set",
+		IndexBucket:           "index",
+		WebhookBucket:         "webhooks",
+		WebhookDeliveryBucket: "webhook_deliveries",
+		HeadersBucket:         "headers",
+		FiltersBucket:         "filters",
+		MempoolBucket:         "mempool",
+		SnapshotBucket:        "snapshot",
+		MetaBucket:            "meta",
+	}
+	cfg.ProofOfWorkConfig = model.ProofOfWorkConfig{TargetBits: 4, Algorithm: core.PowSHA256}
+	cfg.TransactionConfig.GenesisCoinbaseData = "genesis"
+	cfg.MempoolConfig = model.MempoolConfig{MaxAge: time.Hour, MaxSize: 1000000, MaxBlockSize: 100000}
+
+	return cfg
+}
+
+// testNode returns a server with a blockchain in memory whose genesis block pays the returned address
+func testNode(t *testing.T) (*Server, string) {
+	t.Helper()
+
+	cfg := testChainConfig()
+
+	wallet, err := core.NewWallet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := wallet.GetAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc, err := core.NewBlockchainWithStore(cfg, core.NewMemoryChainStore(cfg), string(address))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(cfg)
+	server.bc = bc
+
+	return server, string(address)
+}
+
+// serveSocket serves the server on a local socket and returns a client of it
+func serveSocket(t *testing.T, server *Server) *NodeClient {
+	t.Helper()
+
+	server.cfg.APIConfig.Socket = filepath.Join(t.TempDir(), "node.sock")
+
+	listener, err := listenSocket(server.cfg.APIConfig.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go http.Serve(listener, localHandler(server))
+
+	node := DialNode(server.cfg)
+	if node == nil {
+		t.Fatal("no node answers on the socket")
+	}
+
+	return node
+}
+
+func TestLocalRoutesAreOnlyServedOnTheSocket(t *testing.T) {
+	server, _ := testNode(t)
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodGet, path: "/local/mempool"},
+		{method: http.MethodGet, path: "/local/webhooks"},
+		{method: http.MethodPost, path: "/local/history"},
+		{method: http.MethodPost, path: "/local/backup"},
+		{method: http.MethodPost, path: "/local/export-chain"},
+		{method: http.MethodPost, path: "/local/utxo-snapshot"},
+	}
+
+	for _, request := range requests {
+		httpRequest, err := http.NewRequest(request.method, httpServer.URL+request.path, bytes.NewReader([]byte(`{}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response, err := http.DefaultClient.Do(httpRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s responded with %d, expected %d", request.method, request.path, response.StatusCode, http.StatusForbidden)
+		}
+	}
+}
+
+func TestNodeClient(t *testing.T) {
+	server, address := testNode(t)
+	node := serveSocket(t, server)
+
+	tracker, err := node.TrackAddresses([]string{address})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracker.UTXOs) != 1 || tracker.UTXOs[0].Output.Value != 10 {
+		t.Errorf("tracked outputs %+v, expected the genesis reward", tracker.UTXOs)
+	}
+
+	if len(tracker.History) != 1 || tracker.History[0].Received != 10 || tracker.History[0].Height != 0 {
+		t.Errorf("tracked history %+v, expected the genesis reward", tracker.History)
+	}
+
+	mempool, err := node.GetMempool()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mempool.Entries) != 0 {
+		t.Errorf("mempool has %d entries, expected none", len(mempool.Entries))
+	}
+
+	genesis, err := server.bc.GetBlock(server.bc.Tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofResponse, err := node.GetTransactionProof(genesis.Transactions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := ParseTransactionProof(*proofResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !proof.Verify() {
+		t.Error("proof of the genesis coinbase does not verify")
+	}
+
+	_, err = node.FindAnchor(bytes.Repeat([]byte{1}, 32))
+	if !errors.Is(err, core.ErrTransactionNotFound) {
+		t.Errorf("found an anchor with %v, expected %v", err, core.ErrTransactionNotFound)
+	}
+
+	chainFile := filepath.Join(t.TempDir(), "chain.bin")
+
+	exported, err := node.ExportChain(chainFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exported.Blocks != 1 {
+		t.Errorf("exported %d blocks, expected 1", exported.Blocks)
+	}
+
+	file, err := os.Open(chainFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	_, err = core.ReadChainFileHeader(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := core.ReadChainFileBlock(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(block.Hash, genesis.Hash) {
+		t.Errorf("exported block %x, expected %x", block.Hash, genesis.Hash)
+	}
+}
+
+func TestExportChainRefusesPrunedNode(t *testing.T) {
+	server, address := testNode(t)
+
+	for range 3 {
+		coinbase, err := core.NewCoinbaseTX(server.cfg, address, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = server.bc.MineBlock([]*core.Transaction{coinbase})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server.cfg.DatabaseConfig.PruneDepth = 2
+
+	_, err := server.bc.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := serveSocket(t, server)
+	chainFile := filepath.Join(t.TempDir(), "chain.bin")
+
+	_, err = node.ExportChain(chainFile)
+	if !errors.Is(err, core.ErrBlockPruned) {
+		t.Fatalf("exported with %v, expected %v", err, core.ErrBlockPruned)
+	}
+
+	_, err = os.Stat(chainFile)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the refused export left %s behind", chainFile)
+	}
+}
@@ -0,0 +1,443 @@
+package api
+
+import (
+	"encoding/hex"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+// BlockResponse is the JSON representation of a block
+type BlockResponse struct {
+	Version          int                   `json:"version"`
+	Hash             string                `json:"hash"`
+	PrevBlockHash    string                `json:"prev_block_hash"`
+	Height           int                   `json:"height"`
+	Timestamp        int64                 `json:"timestamp"`
+	Nonce            int                   `json:"nonce"`
+	TransactionCount int                   `json:"transaction_count"`
+	Transactions     []TransactionResponse `json:"transactions"`
+	Pruned           bool                  `json:"pruned,omitempty"`
+}
+
+// BlocksPageResponse is a page of blocks walked from the cursor towards the genesis block
+type BlocksPageResponse struct {
+	Blocks     []BlockResponse `json:"blocks"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// TransactionResponse is the JSON representation of a transaction
+type TransactionResponse struct {
+	ID        string           `json:"id"`
+	Coinbase  bool             `json:"coinbase"`
+	BlockHash string           `json:"block_hash,omitempty"`
+	Inputs    []InputResponse  `json:"inputs"`
+	Outputs   []OutputResponse `json:"outputs"`
+	LockTime  int64            `json:"lock_time,omitempty"`
+}
+
+// InputResponse is the JSON representation of a transaction input
+type InputResponse struct {
+	TransactionID string `json:"transaction_id,omitempty"`
+	OutputIndex   int    `json:"output_index"`
+	Address       string `json:"address,omitempty"`
+	Signature     string `json:"signature,omitempty"`
+	PubKey        string `json:"pub_key,omitempty"`
+	Secret        string `json:"secret,omitempty"`
+	CoinbaseData  string `json:"coinbase_data,omitempty"`
+}
+
+// OutputResponse is the JSON representation of a transaction output
+type OutputResponse struct {
+	Index        int               `json:"index"`
+	Value        int               `json:"value"`
+	Address      string            `json:"address"`
+	RelativeLock int               `json:"relative_lock,omitempty"`
+	Contract     *ContractResponse `json:"contract,omitempty"`
+	Data         string            `json:"data,omitempty"`
+}
+
+// ContractResponse is the JSON representation of the hash time-locked contract of an output
+type ContractResponse struct {
+	SecretHash string `json:"secret_hash"`
+	Recipient  string `json:"recipient"`
+	Refund     string `json:"refund"`
+	Timeout    int64  `json:"timeout"`
+}
+
+// UTXOResponse is the JSON representation of an unspent transaction output
+type UTXOResponse struct {
+	TransactionID string `json:"transaction_id"`
+	OutputIndex   int    `json:"output_index"`
+	Value         int    `json:"value"`
+}
+
+// AddressUTXOsResponse lists the unspent outputs of an address
+type AddressUTXOsResponse struct {
+	Address string         `json:"address"`
+	UTXOs   []UTXOResponse `json:"utxos"`
+}
+
+// BalanceResponse is the balance of an address
+type BalanceResponse struct {
+	Address string `json:"address"`
+	Balance int    `json:"balance"`
+}
+
+// StatsResponse summarizes the state of the blockchain
+type StatsResponse struct {
+	Height           int    `json:"height"`
+	TipHash          string `json:"tip_hash"`
+	TargetBits       int    `json:"target_bits"`
+	PowAlgorithm     string `json:"pow_algorithm"`
+	Subsidy          int    `json:"subsidy"`
+	UTXOTransactions int    `json:"utxo_transactions"`
+	UTXOOutputs      int    `json:"utxo_outputs"`
+	TotalSupply      int    `json:"total_supply"`
+}
+
+// BlockHeaderResponse is the JSON representation of a block header
+type BlockHeaderResponse struct {
+	Version       int    `json:"version"`
+	Hash          string `json:"hash"`
+	PrevBlockHash string `json:"prev_block_hash"`
+	MerkleRoot    string `json:"merkle_root"`
+	Height        int    `json:"height"`
+	Timestamp     int64  `json:"timestamp"`
+	Nonce         int    `json:"nonce"`
+}
+
+// MerkleProofStepResponse is the JSON representation of a sibling hash in a Merkle proof
+type MerkleProofStepResponse struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// TransactionProofResponse proves that a transaction is included in the block of the header
+type TransactionProofResponse struct {
+	TransactionID  string                    `json:"transaction_id"`
+	RawTransaction string                    `json:"raw_transaction"`
+	LeafHash       string                    `json:"leaf_hash"`
+	Index          int                       `json:"index"`
+	Header         BlockHeaderResponse       `json:"header"`
+	Proof          []MerkleProofStepResponse `json:"proof"`
+}
+
+// HeadersResponse is a range of block headers in ascending height
+type HeadersResponse struct {
+	Headers []BlockHeaderResponse `json:"headers"`
+}
+
+// BlockFilterResponse is the JSON representation of the compact filter of a block
+type BlockFilterResponse struct {
+	BlockHash    string `json:"block_hash"`
+	Height       int    `json:"height"`
+	Filter       string `json:"filter"`
+	FilterHeader string `json:"filter_header"`
+}
+
+// FiltersResponse is a range of block filters in ascending height
+type FiltersResponse struct {
+	Filters []BlockFilterResponse `json:"filters"`
+}
+
+// RawBlockResponse is a serialized block, letting clients check it against its header
+type RawBlockResponse struct {
+	Hash     string `json:"hash"`
+	Height   int    `json:"height"`
+	RawBlock string `json:"raw_block"`
+}
+
+// SubmitTransactionRequest submits a signed transaction to wait in the mempool, or to be mined when a miner is given on
+// the local socket
+type SubmitTransactionRequest struct {
+	RawTransaction string `json:"raw_transaction"`
+	Miner          string `json:"miner,omitempty"`
+}
+
+// SubmitTransactionResponse reports the block that includes a submitted transaction, which is empty while it is pending
+type SubmitTransactionResponse struct {
+	TransactionID string `json:"transaction_id"`
+	BlockHash     string `json:"block_hash,omitempty"`
+}
+
+// MempoolEntryResponse is the JSON representation of a transaction waiting in the mempool
+type MempoolEntryResponse struct {
+	TransactionID string  `json:"transaction_id"`
+	Fee           int     `json:"fee"`
+	Size          int     `json:"size"`
+	FeeRate       float64 `json:"fee_rate"`
+	AddedAt       int64   `json:"added_at"`
+}
+
+// MempoolResponse lists the transactions waiting to be mined
+type MempoolResponse struct {
+	Entries []MempoolEntryResponse `json:"entries"`
+}
+
+// DeliveryResponse is the JSON representation of the delivery of a payment to a webhook
+type DeliveryResponse struct {
+	Key       string `json:"key"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// WebhookResponse is the JSON representation of a registered webhook with its deliveries
+type WebhookResponse struct {
+	ID            string             `json:"id"`
+	Address       string             `json:"address"`
+	URL           string             `json:"url"`
+	Confirmations int                `json:"confirmations"`
+	Deliveries    []DeliveryResponse `json:"deliveries"`
+}
+
+// WebhooksResponse lists the registered webhooks
+type WebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// TrackAddressesRequest asks for the unspent outputs and the history of a set of addresses
+type TrackAddressesRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// HistoryEntryResponse is the JSON representation of a transaction paying to or spending from tracked addresses
+type HistoryEntryResponse struct {
+	TransactionID string `json:"transaction_id"`
+	BlockHash     string `json:"block_hash"`
+	Height        int    `json:"height"`
+	Timestamp     int64  `json:"timestamp"`
+	Received      int    `json:"received"`
+	Sent          int    `json:"sent"`
+}
+
+// TrackAddressesResponse lists the unspent outputs and the history of tracked addresses, whose transactions up to the
+// pruned height are missing
+type TrackAddressesResponse struct {
+	UTXOs        []UTXOResponse         `json:"utxos"`
+	History      []HistoryEntryResponse `json:"history"`
+	PrunedHeight int                    `json:"pruned_height"`
+}
+
+// AnchorResponse locates the earliest transaction anchoring a hash in a data output
+type AnchorResponse struct {
+	TransactionID string `json:"transaction_id"`
+	BlockHash     string `json:"block_hash"`
+	Height        int    `json:"height"`
+	BestHeight    int    `json:"best_height"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// FileRequest names a file the node writes, as an absolute path as the node may run from another directory
+type FileRequest struct {
+	Path string `json:"path"`
+}
+
+// BackupResponse reports a backup taken by the node
+type BackupResponse struct {
+	Dir     string `json:"dir"`
+	Height  int    `json:"height"`
+	TipHash string `json:"tip_hash"`
+	Wallet  bool   `json:"wallet"`
+}
+
+// ExportChainResponse reports a chain file written by the node
+type ExportChainResponse struct {
+	Blocks int `json:"blocks"`
+}
+
+// UTXOSnapshotResponse reports a UTXO snapshot written by the node
+type UTXOSnapshotResponse struct {
+	Transactions int    `json:"transactions"`
+	TipHash      string `json:"tip_hash"`
+	Height       int    `json:"height"`
+	Hash         string `json:"hash"`
+}
+
+// newBlockResponse converts a block into its JSON representation
+func newBlockResponse(cfg *model.Config, block *core.Block, height int) (*BlockResponse, error) {
+	response := BlockResponse{
+		Version:          block.Version,
+		Hash:             hex.EncodeToString(block.Hash),
+		PrevBlockHash:    hex.EncodeToString(block.PrevBlockHash),
+		Height:           height,
+		Timestamp:        block.Timestamp,
+		Nonce:            block.Nonce,
+		TransactionCount: len(block.Transactions),
+		Transactions:     []TransactionResponse{},
+		Pruned:           block.IsPruned(),
+	}
+
+	for _, tx := range block.Transactions {
+		txResponse, err := newTransactionResponse(cfg, tx)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		response.Transactions = append(response.Transactions, *txResponse)
+	}
+
+	return &response, nil
+}
+
+// newTransactionResponse converts a transaction into its JSON representation
+func newTransactionResponse(cfg *model.Config, tx *core.Transaction) (*TransactionResponse, error) {
+	response := TransactionResponse{
+		ID:       hex.EncodeToString(tx.ID),
+		Coinbase: tx.IsCoinbase(),
+		Inputs:   []InputResponse{},
+		Outputs:  []OutputResponse{},
+		LockTime: tx.LockTime,
+	}
+
+	for _, in := range tx.InputValue {
+		if tx.IsCoinbase() {
+			response.Inputs = append(response.Inputs, InputResponse{OutputIndex: in.OutputIndex, CoinbaseData: string(in.PubKey)})
+			continue
+		}
+
+		pubKeyHash, err := core.HashPubKey(in.PubKey)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		response.Inputs = append(response.Inputs, InputResponse{
+			TransactionID: hex.EncodeToString(in.TransactionID),
+			OutputIndex:   in.OutputIndex,
+			Address:       string(core.EncodeAddress(cfg, pubKeyHash)),
+			Signature:     hex.EncodeToString(in.Signature),
+			PubKey:        hex.EncodeToString(in.PubKey),
+			Secret:        hex.EncodeToString(in.Secret),
+		})
+	}
+
+	for index, out := range tx.OutputValue {
+		output := OutputResponse{
+			Index:        index,
+			Value:        out.Value,
+			RelativeLock: out.RelativeLock,
+		}
+
+		if out.HTLC != nil {
+			output.Contract = &ContractResponse{
+				SecretHash: hex.EncodeToString(out.HTLC.SecretHash),
+				Recipient:  string(core.EncodeAddress(cfg, out.HTLC.RecipientPubKeyHash)),
+				Refund:     string(core.EncodeAddress(cfg, out.HTLC.RefundPubKeyHash)),
+				Timeout:    out.HTLC.Timeout,
+			}
+		} else if out.IsData() {
+			output.Data = hex.EncodeToString(out.Data)
+		} else {
+			output.Address = string(core.EncodeAddress(cfg, out.PubKeyHash))
+		}
+
+		response.Outputs = append(response.Outputs, output)
+	}
+
+	return &response, nil
+}
+
+// newBlockHeaderResponse converts a block header into its JSON representation
+func newBlockHeaderResponse(header *core.BlockHeader, height int) BlockHeaderResponse {
+	return BlockHeaderResponse{
+		Version:       header.Version,
+		Hash:          hex.EncodeToString(header.Hash),
+		PrevBlockHash: hex.EncodeToString(header.PrevBlockHash),
+		MerkleRoot:    hex.EncodeToString(header.MerkleRoot),
+		Height:        height,
+		Timestamp:     header.Timestamp,
+		Nonce:         header.Nonce,
+	}
+}
+
+// newTransactionProofResponse converts a transaction proof into its JSON representation
+func newTransactionProofResponse(proof *core.TransactionProof, height int) *TransactionProofResponse {
+	response := TransactionProofResponse{
+		TransactionID:  hex.EncodeToString(proof.TransactionID),
+		RawTransaction: hex.EncodeToString(proof.RawTransaction),
+		LeafHash:       hex.EncodeToString(proof.LeafHash),
+		Index:          proof.Index,
+		Header:         newBlockHeaderResponse(&proof.Header, height),
+		Proof:          []MerkleProofStepResponse{},
+	}
+
+	for _, step := range proof.Proof {
+		response.Proof = append(response.Proof, MerkleProofStepResponse{Hash: hex.EncodeToString(step.Hash), Left: step.Left})
+	}
+
+	return &response
+}
+
+// newBlockFilterResponse converts a block filter into its JSON representation
+func newBlockFilterResponse(hash []byte, height int, blockFilter *core.BlockFilter) BlockFilterResponse {
+	return BlockFilterResponse{
+		BlockHash:    hex.EncodeToString(hash),
+		Height:       height,
+		Filter:       hex.EncodeToString(blockFilter.Filter),
+		FilterHeader: hex.EncodeToString(blockFilter.Header),
+	}
+}
+
+// ParseBlockHeader converts the JSON representation of a block header back into a block header
+func ParseBlockHeader(response BlockHeaderResponse) (*core.BlockHeader, error) {
+	var header core.BlockHeader
+	var err error
+
+	header.Hash, err = hex.DecodeString(response.Hash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	header.PrevBlockHash, err = hex.DecodeString(response.PrevBlockHash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	header.MerkleRoot, err = hex.DecodeString(response.MerkleRoot)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	header.Version = response.Version
+	header.Timestamp = response.Timestamp
+	header.Nonce = response.Nonce
+
+	return &header, nil
+}
+
+// ParseTransactionProof converts the JSON representation of a transaction proof back into a transaction proof
+func ParseTransactionProof(response TransactionProofResponse) (*core.TransactionProof, error) {
+	header, err := ParseBlockHeader(response.Header)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	proof := core.TransactionProof{Header: *header, Index: response.Index}
+
+	proof.TransactionID, err = hex.DecodeString(response.TransactionID)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	proof.RawTransaction, err = hex.DecodeString(response.RawTransaction)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	proof.LeafHash, err = hex.DecodeString(response.LeafHash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	for _, step := range response.Proof {
+		hash, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		proof.Proof = append(proof.Proof, core.MerkleProofStep{Hash: hash, Left: step.Left})
+	}
+
+	return &proof, nil
+}
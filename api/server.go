@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoBlockchain is returned when a request needs the blockchain before the server opened it
+var ErrNoBlockchain = errors.New("blockchain is not open")
+
+// Server serves the block explorer API
+type Server struct {
+	cfg      *model.Config
+	mu       sync.Mutex
+	bc       *core.Blockchain
+	mux      *http.ServeMux
+	hub      *Hub
+	webhooks *WebhookDispatcher
+}
+
+// NewServer generates and returns a new API server
+func NewServer(cfg *model.Config) *Server {
+	server := &Server{
+		cfg:      cfg,
+		mux:      http.NewServeMux(),
+		hub:      NewHub(),
+		webhooks: NewWebhookDispatcher(cfg, &http.Client{Timeout: cfg.WebhookConfig.Timeout}),
+	}
+
+	server.mux.HandleFunc("GET /blocks", server.handleGetBlocks)
+	server.mux.HandleFunc("GET /blocks/{hash}", server.handleGetBlock)
+	server.mux.HandleFunc("GET /blocks/height/{height}", server.handleGetBlockByHeight)
+	server.mux.HandleFunc("GET /blocks/raw/{hash}", server.handleGetRawBlock)
+	server.mux.HandleFunc("GET /tx/{id}", server.handleGetTransaction)
+	server.mux.HandleFunc("GET /tx/{id}/proof", server.handleGetTransactionProof)
+	server.mux.HandleFunc("POST /tx", server.handleSubmitTransaction)
+	server.mux.HandleFunc("GET /headers", server.handleGetHeaders)
+	server.mux.HandleFunc("GET /filters", server.handleGetFilters)
+	server.mux.HandleFunc("GET /filters/{hash}", server.handleGetFilter)
+	server.mux.HandleFunc("GET /address/{address}/utxos", server.handleGetAddressUTXOs)
+	server.mux.HandleFunc("GET /address/{address}/balance", server.handleGetAddressBalance)
+	server.mux.HandleFunc("GET /stats", server.handleGetStats)
+	server.mux.HandleFunc("GET /ws", server.handleWebSocket)
+	server.mux.HandleFunc("GET /local/mempool", localOnly(server.handleGetMempool))
+	server.mux.HandleFunc("GET /local/webhooks", localOnly(server.handleGetWebhooks))
+	server.mux.HandleFunc("POST /local/history", localOnly(server.handleTrackAddresses))
+	server.mux.HandleFunc("GET /local/anchors/{hash}", localOnly(server.handleGetAnchor))
+	server.mux.HandleFunc("POST /local/backup", localOnly(server.handleBackup))
+	server.mux.HandleFunc("POST /local/export-chain", localOnly(server.handleExportChain))
+	server.mux.HandleFunc("POST /local/utxo-snapshot", localOnly(server.handleDumpUTXOSnapshot))
+
+	return server
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe starts serving the API on the given address
+func (s *Server) ListenAndServe(address string) error {
+	blockchain, err := core.InitalizeBlockchain(s.cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	s.bc = blockchain
+
+	// Pending transactions are checked again, as blocks may have been mined while the API was down
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		dropped, err := core.NewMempool(s.cfg, bc).Reload()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		log.WithField("dropped", *dropped).Info("Mempool reloaded")
+
+		snapshot, err := bc.PendingSnapshot()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if snapshot != nil {
+			log.WithField("height", snapshot.Height).Info("Validating the blocks below the UTXO snapshot in the background")
+
+			go s.validateSnapshot(snapshot)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if s.cfg.APIConfig.Socket != "" {
+		listener, err := listenSocket(s.cfg.APIConfig.Socket)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+		defer listener.Close()
+
+		log.WithField("socket", s.cfg.APIConfig.Socket).Info("Local commands served on the socket")
+
+		go func() {
+			err := http.Serve(listener, localHandler(s))
+			if err != nil {
+				log.WithError(err).Error("Stopped serving the local socket")
+			}
+		}()
+	}
+
+	log.WithField("address", address).Info("Block explorer API listening")
+
+	go s.watchChain(s.cfg.APIConfig.PollInterval)
+
+	if s.cfg.BackupConfig.Interval > 0 {
+		log.WithFields(log.Fields{"dir": s.cfg.BackupConfig.Dir, "interval": s.cfg.BackupConfig.Interval.String()}).Info("Scheduled backups enabled")
+
+		go s.scheduleBackups(s.cfg.BackupConfig.Interval)
+	}
+
+	err = http.ListenAndServe(address, s)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// withBlockchain runs fn with the blockchain the server keeps open while it runs, one request at a time. Local
+// commands go through the socket instead of opening the database
+func (s *Server) withBlockchain(fn func(bc *core.Blockchain) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bc == nil {
+		return ErrNoBlockchain
+	}
+
+	return fn(s.bc)
+}
+
+// errorResponse is the body returned when a request fails
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes a JSON document with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.WithError(err).Error("Failed to encode API response")
+	}
+}
+
+// writeError writes an error document, mapping known errors to their status code
+func writeError(w http.ResponseWriter, err error) {
+	var requestErr *badRequestError
+
+	switch {
+	case errors.As(err, &requestErr):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: requestErr.message})
+	case errors.Is(err, ErrOriginNotAllowed):
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: ErrOriginNotAllowed.Error()})
+	case errors.Is(err, core.ErrInvalidTransaction):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: core.ErrInvalidTransaction.Error()})
+	case errors.Is(err, core.ErrTransactionLocked):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: core.ErrTransactionLocked.Error()})
+	case errors.Is(err, core.ErrMempoolConflict):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: core.ErrMempoolConflict.Error()})
+	case errors.Is(err, core.ErrMempoolFull):
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: core.ErrMempoolFull.Error()})
+	case errors.Is(err, core.ErrBlockPruned):
+		writeJSON(w, http.StatusGone, errorResponse{Error: core.ErrBlockPruned.Error()})
+	case errors.Is(err, core.ErrBlockNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: core.ErrBlockNotFound.Error()})
+	case errors.Is(err, core.ErrTransactionNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: core.ErrTransactionNotFound.Error()})
+	default:
+		log.WithError(err).Error("API request failed")
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal server error"})
+	}
+}
+
+// badRequestError reports invalid request parameters
+type badRequestError struct {
+	message string
+}
+
+func (e *badRequestError) Error() string {
+	return e.message
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotSource downloads the headers and blocks a node loaded from a UTXO snapshot is missing from the API of another node
+type SnapshotSource struct {
+	cfg        *model.Config
+	httpClient *http.Client
+}
+
+// NewSnapshotSource generates and returns a client of the configured snapshot source
+func NewSnapshotSource(cfg *model.Config) *SnapshotSource {
+	return &SnapshotSource{cfg: cfg, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Headers downloads the headers from the genesis block up to the given height
+func (s *SnapshotSource) Headers(height int) ([]*core.BlockHeader, error) {
+	var headers []*core.BlockHeader
+
+	for len(headers) <= height {
+		var response HeadersResponse
+
+		err := s.get(fmt.Sprintf("/headers?from=%d&limit=%d", len(headers), s.cfg.APIConfig.MaxPageLimit), &response)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if len(response.Headers) == 0 {
+			return nil, fmt.Errorf("snapshot source has no header at height %d", len(headers))
+		}
+
+		for _, headerResponse := range response.Headers {
+			if len(headers) > height {
+				break
+			}
+
+			header, err := ParseBlockHeader(headerResponse)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			headers = append(headers, header)
+		}
+	}
+
+	return headers, nil
+}
+
+// Block downloads the block with the given hash
+func (s *SnapshotSource) Block(hash []byte) (*core.Block, error) {
+	var response RawBlockResponse
+
+	err := s.get("/blocks/raw/"+hex.EncodeToString(hash), &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	rawBlock, err := hex.DecodeString(response.RawBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	block, err := core.DeserializeBlock(rawBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return block, nil
+}
+
+// get requests a path of the snapshot source and decodes its JSON response
+func (s *SnapshotSource) get(path string, v any) error {
+	httpResponse, err := s.httpClient.Get(strings.TrimRight(s.cfg.SnapshotConfig.Source, "/") + path)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		var errResponse errorResponse
+
+		json.NewDecoder(httpResponse.Body).Decode(&errResponse)
+
+		return fmt.Errorf("snapshot source responded with %d: %s", httpResponse.StatusCode, errResponse.Error)
+	}
+
+	err = json.NewDecoder(httpResponse.Body).Decode(v)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// validateSnapshot validates the blocks below the snapshot the blockchain was loaded from, downloading them from the
+// snapshot source while the API keeps serving, and stops at the first block that shows the snapshot is invalid
+func (s *Server) validateSnapshot(snapshot *core.UTXOSnapshot) {
+	validation := core.NewSnapshotValidation(s.cfg, snapshot)
+	source := NewSnapshotSource(s.cfg)
+
+	for !validation.Done() {
+		err := s.validateSnapshotBlock(validation, source)
+		if errors.Is(err, core.ErrSnapshotInvalid) {
+			log.WithError(err).Error("The blocks below the UTXO snapshot do not match it")
+			return
+		}
+		if err != nil {
+			log.WithError(err).WithField("height", validation.Height()).Warn("Failed to validate a block below the UTXO snapshot, retrying")
+			time.Sleep(s.cfg.APIConfig.PollInterval)
+		}
+	}
+
+	log.WithField("height", snapshot.Height).Info("UTXO snapshot validated")
+}
+
+// validateSnapshotBlock downloads the next block below the snapshot and connects it to the validation
+func (s *Server) validateSnapshotBlock(validation *core.SnapshotValidation, source *SnapshotSource) error {
+	var hash []byte
+
+	err := s.withBlockchain(func(bc *core.Blockchain) error {
+		blockHash, err := bc.GetBlockHash(validation.Height())
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		hash = blockHash
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	// The database is not held while the block is downloaded
+	block, err := source.Block(hash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return s.withBlockchain(func(bc *core.Blockchain) error {
+		return validation.ConnectBlock(bc, block)
+	})
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// socketDialTimeout is how long a command waits to connect to the local socket of a running node
+const socketDialTimeout = time.Second
+
+// listenSocket listens on the local Unix socket of the node. A socket left behind by a node that stopped is replaced,
+// one a node still answers on is refused
+func listenSocket(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		conn, err := net.DialTimeout("unix", path, socketDialTimeout)
+		if err == nil {
+			conn.Close()
+
+			return nil, fmt.Errorf("another node is already listening on %s", path)
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// Only the user running the node may talk to it
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		listener.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	return listener, nil
+}
+
+// localContextKey is the context key marking the requests that came in on the local socket
+type localContextKey struct{}
+
+// localHandler marks the requests it serves as coming in on the local socket, which only the user running the node can reach
+func localHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localContextKey{}, true)))
+	})
+}
+
+// isLocalRequest checks whether the request came in on the local socket
+func isLocalRequest(r *http.Request) bool {
+	local, _ := r.Context().Value(localContextKey{}).(bool)
+
+	return local
+}
+
+// NodeClient talks to the node running on this machine through its local socket, so that commands do not need to
+// open the database it uses
+type NodeClient struct {
+	httpClient *http.Client
+}
+
+// DialNode returns a client of the node listening on the configured socket, or nil when no node is running
+func DialNode(cfg *model.Config) *NodeClient {
+	path := cfg.APIConfig.Socket
+	if path == "" {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", path, socketDialTimeout)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+
+	dialer := net.Dialer{Timeout: socketDialTimeout}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}
+
+	return &NodeClient{httpClient: &http.Client{Timeout: 30 * time.Second, Transport: transport}}
+}
+
+// GetBalance returns the balance of an address from the node
+func (c *NodeClient) GetBalance(address string) (*int, error) {
+	var response BalanceResponse
+
+	err := c.get("/address/"+url.PathEscape(address)+"/balance", &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response.Balance, nil
+}
+
+// GetMempool returns the transactions waiting in the mempool of the node
+func (c *NodeClient) GetMempool() (*MempoolResponse, error) {
+	var response MempoolResponse
+
+	err := c.get("/local/mempool", &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// GetWebhooks returns the webhooks registered on the node along with the state of their deliveries
+func (c *NodeClient) GetWebhooks() (*WebhooksResponse, error) {
+	var response WebhooksResponse
+
+	err := c.get("/local/webhooks", &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// TrackAddresses returns the unspent outputs and the history of the addresses from the node
+func (c *NodeClient) TrackAddresses(addresses []string) (*core.AddressTracker, error) {
+	var response TrackAddressesResponse
+
+	err := c.post("/local/history", TrackAddressesRequest{Addresses: addresses}, &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tracker := core.AddressTracker{PrunedHeight: response.PrunedHeight}
+
+	for _, utxo := range response.UTXOs {
+		transactionID, err := hex.DecodeString(utxo.TransactionID)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		tracker.UTXOs = append(tracker.UTXOs, core.UTXO{TransactionID: transactionID, OutputIndex: utxo.OutputIndex, Output: core.TXOutput{Value: utxo.Value}})
+	}
+
+	for _, entry := range response.History {
+		transactionID, err := hex.DecodeString(entry.TransactionID)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		blockHash, err := hex.DecodeString(entry.BlockHash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		tracker.History = append(tracker.History, core.HistoryEntry{
+			TransactionID: transactionID,
+			BlockHash:     blockHash,
+			Height:        entry.Height,
+			Timestamp:     entry.Timestamp,
+			Received:      entry.Received,
+			Sent:          entry.Sent,
+		})
+	}
+
+	return &tracker, nil
+}
+
+// GetTransactionProof returns the Merkle proof of a transaction from the node
+func (c *NodeClient) GetTransactionProof(transactionID []byte) (*TransactionProofResponse, error) {
+	var response TransactionProofResponse
+
+	err := c.get("/tx/"+hex.EncodeToString(transactionID)+"/proof", &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// FindAnchor returns the earliest transaction anchoring the hash from the node
+func (c *NodeClient) FindAnchor(hash []byte) (*AnchorResponse, error) {
+	var response AnchorResponse
+
+	err := c.get("/local/anchors/"+hex.EncodeToString(hash), &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// Backup has the node back up its database and the wallet file into the directory
+func (c *NodeClient) Backup(dest string) (*BackupResponse, error) {
+	var response BackupResponse
+
+	err := c.postFile("/local/backup", dest, &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// ExportChain has the node write its blockchain to the chain file
+func (c *NodeClient) ExportChain(path string) (*ExportChainResponse, error) {
+	var response ExportChainResponse
+
+	err := c.postFile("/local/export-chain", path, &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// DumpUTXOSnapshot has the node write its UTXO set to the snapshot file
+func (c *NodeClient) DumpUTXOSnapshot(path string) (*UTXOSnapshotResponse, error) {
+	var response UTXOSnapshotResponse
+
+	err := c.postFile("/local/utxo-snapshot", path, &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// postFile asks the node to write the file, whose path is made absolute as the node may run from another directory
+func (c *NodeClient) postFile(path string, filePath string, v any) error {
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return c.post(path, FileRequest{Path: absolutePath}, v)
+}
+
+// get fetches a JSON document from the node, the host is ignored as every request goes to the socket
+func (c *NodeClient) get(path string, v any) error {
+	httpResponse, err := c.httpClient.Get("http://node" + path)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer httpResponse.Body.Close()
+
+	return decodeNodeResponse(httpResponse, v)
+}
+
+// post sends a JSON document to the node and decodes its response
+func (c *NodeClient) post(path string, request any, v any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	httpResponse, err := c.httpClient.Post("http://node"+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer httpResponse.Body.Close()
+
+	return decodeNodeResponse(httpResponse, v)
+}
+
+// nodeErrors are the errors a command tells apart, recognized by the message the node responds with
+var nodeErrors = []error{core.ErrTransactionNotFound, core.ErrBlockNotFound, core.ErrBlockPruned}
+
+// decodeNodeResponse decodes the JSON document of a response, or the error the node responded with
+func decodeNodeResponse(httpResponse *http.Response, v any) error {
+	if httpResponse.StatusCode != http.StatusOK {
+		var errResponse errorResponse
+
+		json.NewDecoder(httpResponse.Body).Decode(&errResponse)
+
+		for _, nodeErr := range nodeErrors {
+			if errResponse.Error == nodeErr.Error() {
+				return fmt.Errorf("node responded with %d: %w", httpResponse.StatusCode, nodeErr)
+			}
+		}
+
+		return fmt.Errorf("node responded with %d: %s", httpResponse.StatusCode, errResponse.Error)
+	}
+
+	err := json.NewDecoder(httpResponse.Body).Decode(v)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
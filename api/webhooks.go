@@ -0,0 +1,340 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookSignatureHeader = "X-Burokkuchen-Signature"
+	WebhookDeliveryHeader  = "X-Burokkuchen-Delivery"
+)
+
+// WebhookPayload is the JSON document posted to a webhook when a payment reaches its confirmation threshold
+type WebhookPayload struct {
+	DeliveryID    string `json:"delivery_id"`
+	WebhookID     string `json:"webhook_id"`
+	Address       string `json:"address"`
+	TransactionID string `json:"transaction_id"`
+	OutputIndex   int    `json:"output_index"`
+	Value         int    `json:"value"`
+	BlockHash     string `json:"block_hash"`
+	BlockHeight   int    `json:"block_height"`
+	Confirmations int    `json:"confirmations"`
+	Attempt       int    `json:"attempt"`
+}
+
+// SignWebhookPayload returns the value of the signature header for a payload
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature header of a received payload
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, body)), []byte(signature))
+}
+
+// WebhookDispatcher finds payments that reached the confirmation threshold of a webhook and delivers them
+type WebhookDispatcher struct {
+	cfg    *model.Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookDispatcher generates and returns a webhook dispatcher using the given HTTP client
+func NewWebhookDispatcher(cfg *model.Config, client *http.Client) *WebhookDispatcher {
+	return &WebhookDispatcher{cfg: cfg, client: client, now: time.Now}
+}
+
+// pendingDelivery is a due delivery together with what is needed to send it
+type pendingDelivery struct {
+	delivery *core.Delivery
+	webhook  *core.Webhook
+	payload  WebhookPayload
+}
+
+// Scan records a pending delivery for every payment that reached the confirmation threshold of a webhook
+func (d *WebhookDispatcher) Scan(bc *core.Blockchain) error {
+	webhooks := core.NewWebhooks(d.cfg, bc)
+
+	registered, err := webhooks.List()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	tipHeight, err := bc.GetBestHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	pruneHeight, err := bc.PruneHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, webhook := range registered {
+		pubKeyHash := core.DecodeAddress(d.cfg, webhook.Address)
+		confirmedHeight := *tipHeight - webhook.Confirmations + 1
+		startHeight := max(webhook.ScannedHeight+1, 0)
+
+		// Pruning keeps the blocks webhooks have not scanned, unless they were pruned before the webhook was registered
+		if startHeight <= *pruneHeight {
+			log.WithFields(log.Fields{"webhook": webhook.ID, "from": startHeight, "to": *pruneHeight}).Warn("Pruned blocks skipped by the webhook scan")
+
+			startHeight = *pruneHeight + 1
+		}
+
+		for height := startHeight; height <= confirmedHeight; height++ {
+			hash, err := bc.GetBlockHash(height)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			block, err := bc.GetBlock(hash)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			for _, tx := range block.Transactions {
+				for outIndex, out := range tx.OutputValue {
+					if !out.IsLockedWithKey(pubKeyHash) {
+						continue
+					}
+
+					delivery := &core.Delivery{
+						WebhookID:     webhook.ID,
+						TransactionID: tx.ID,
+						OutputIndex:   outIndex,
+						Value:         out.Value,
+						BlockHash:     block.Hash,
+						Status:        core.DeliveryPending,
+						NextAttempt:   d.now().Unix(),
+					}
+
+					added, err := webhooks.AddDelivery(delivery)
+					if err != nil {
+						return utils.CatchErr(err)
+					}
+
+					if *added {
+						log.WithField("delivery", delivery.Key()).Info("Queued webhook delivery")
+					}
+				}
+			}
+		}
+
+		if confirmedHeight > webhook.ScannedHeight {
+			webhook.ScannedHeight = confirmedHeight
+
+			err = webhooks.Put(webhook)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// due returns the deliveries whose next attempt is due, dropping those whose block left the main chain
+func (d *WebhookDispatcher) due(bc *core.Blockchain) ([]*pendingDelivery, error) {
+	webhooks := core.NewWebhooks(d.cfg, bc)
+
+	registered, err := webhooks.List()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	webhooksByID := make(map[string]*core.Webhook)
+	for _, webhook := range registered {
+		webhooksByID[webhook.ID] = webhook
+	}
+
+	deliveries, err := webhooks.DueDeliveries(d.now().Unix())
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tipHeight, err := bc.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var pending []*pendingDelivery
+
+	for _, delivery := range deliveries {
+		webhook := webhooksByID[delivery.WebhookID]
+		if webhook == nil {
+			continue
+		}
+
+		height, err := bc.GetBlockHeight(delivery.BlockHash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		mainChainHash, err := bc.GetBlockHash(*height)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if !bytes.Equal(mainChainHash, delivery.BlockHash) {
+			delivery.Status = core.DeliveryFailed
+			delivery.LastError = "the block of the payment is no longer in the main chain"
+
+			err = webhooks.PutDelivery(delivery)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			continue
+		}
+
+		pending = append(pending, &pendingDelivery{
+			delivery: delivery,
+			webhook:  webhook,
+			payload: WebhookPayload{
+				DeliveryID:    delivery.Key(),
+				WebhookID:     webhook.ID,
+				Address:       webhook.Address,
+				TransactionID: hex.EncodeToString(delivery.TransactionID),
+				OutputIndex:   delivery.OutputIndex,
+				Value:         delivery.Value,
+				BlockHash:     hex.EncodeToString(delivery.BlockHash),
+				BlockHeight:   *height,
+				Confirmations: *tipHeight - *height + 1,
+				Attempt:       delivery.Attempts + 1,
+			},
+		})
+	}
+
+	return pending, nil
+}
+
+// send posts a signed payload to the webhook URL
+func (d *WebhookDispatcher) send(pending *pendingDelivery) error {
+	body, err := json.Marshal(pending.payload)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, pending.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(pending.webhook.Secret, body))
+	request.Header.Set(WebhookDeliveryHeader, pending.payload.DeliveryID)
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+// record updates the state of a delivery after an attempt, scheduling a retry with exponential backoff on failure
+func (d *WebhookDispatcher) record(delivery *core.Delivery, sendErr error) {
+	delivery.Attempts++
+
+	if sendErr == nil {
+		delivery.Status = core.DeliveryDelivered
+		delivery.DeliveredAt = d.now().Unix()
+		delivery.LastError = ""
+
+		return
+	}
+
+	delivery.LastError = sendErr.Error()
+
+	if delivery.Attempts >= d.cfg.WebhookConfig.MaxAttempts {
+		delivery.Status = core.DeliveryFailed
+
+		return
+	}
+
+	delay := d.cfg.WebhookConfig.RetryBaseDelay << (delivery.Attempts - 1)
+	if delay <= 0 || delay > d.cfg.WebhookConfig.RetryMaxDelay {
+		delay = d.cfg.WebhookConfig.RetryMaxDelay
+	}
+
+	delivery.NextAttempt = d.now().Add(delay).Unix()
+}
+
+// processWebhooks queues newly confirmed payments and delivers the due ones, without holding the database during requests
+func (s *Server) processWebhooks() error {
+	var pending []*pendingDelivery
+
+	err := s.withBlockchain(func(bc *core.Blockchain) error {
+		err := s.webhooks.Scan(bc)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		pending, err = s.webhooks.due(bc)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	for _, p := range pending {
+		sendErr := s.webhooks.send(p)
+		if sendErr != nil {
+			log.WithError(sendErr).WithField("delivery", p.payload.DeliveryID).Warn("Webhook delivery failed")
+		}
+
+		s.webhooks.record(p.delivery, sendErr)
+	}
+
+	err = s.withBlockchain(func(bc *core.Blockchain) error {
+		webhooks := core.NewWebhooks(s.cfg, bc)
+
+		for _, p := range pending {
+			err := webhooks.PutDelivery(p.delivery)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the payloads posted to it and answers with the next of its status codes, then 200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	payloads []WebhookPayload
+	server   *httptest.Server
+}
+
+// newWebhookReceiver starts a receiver checking the signature of every payload with the secret
+func newWebhookReceiver(t *testing.T, secret string, statuses ...int) *webhookReceiver {
+	t.Helper()
+
+	receiver := &webhookReceiver{statuses: statuses}
+
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if !VerifyWebhookSignature(secret, body, r.Header.Get(WebhookSignatureHeader)) {
+			t.Errorf("invalid signature %q", r.Header.Get(WebhookSignatureHeader))
+		}
+
+		var payload WebhookPayload
+
+		err = json.Unmarshal(body, &payload)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if r.Header.Get(WebhookDeliveryHeader) != payload.DeliveryID {
+			t.Errorf("delivery header %q does not match the payload %q", r.Header.Get(WebhookDeliveryHeader), payload.DeliveryID)
+		}
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+
+		receiver.payloads = append(receiver.payloads, payload)
+
+		status := http.StatusOK
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.server.Close)
+
+	return receiver
+}
+
+// received returns the payloads posted so far
+func (r *webhookReceiver) received() []WebhookPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]WebhookPayload{}, r.payloads...)
+}
+
+// testAddress returns the address of a new wallet
+func testAddress(t *testing.T, cfg *model.Config) string {
+	t.Helper()
+
+	wallet, err := core.NewWallet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := wallet.GetAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(address)
+}
+
+// mineReward mines a block paying the subsidy to the address
+func mineReward(t *testing.T, server *Server, address string) *core.Block {
+	t.Helper()
+
+	coinbase, err := core.NewCoinbaseTX(server.cfg, address, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := server.bc.MineBlock([]*core.Transaction{coinbase})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+// testDeliveries returns the stored webhook deliveries
+func testDeliveries(t *testing.T, server *Server) []*core.Delivery {
+	t.Helper()
+
+	deliveries, err := core.NewWebhooks(server.cfg, server.bc).Deliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return deliveries
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	server, minerAddress := testNode(t)
+	address := testAddress(t, server.cfg)
+	receiver := newWebhookReceiver(t, "secret")
+
+	webhook, err := core.NewWebhooks(server.cfg, server.bc).Add(address, receiver.server.URL, 2, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := mineReward(t, server, address)
+
+	err = server.processWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(receiver.received()) != 0 {
+		t.Fatalf("delivered %d payloads before the payment had 2 confirmations", len(receiver.received()))
+	}
+
+	mineReward(t, server, minerAddress)
+
+	err = server.processWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := receiver.received()
+	if len(payloads) != 1 {
+		t.Fatalf("delivered %d payloads, expected 1", len(payloads))
+	}
+
+	expected := WebhookPayload{
+		DeliveryID:    payloads[0].DeliveryID,
+		WebhookID:     webhook.ID,
+		Address:       address,
+		TransactionID: hex.EncodeToString(block.Transactions[0].ID),
+		OutputIndex:   0,
+		Value:         10,
+		BlockHash:     hex.EncodeToString(block.Hash),
+		BlockHeight:   1,
+		Confirmations: 2,
+		Attempt:       1,
+	}
+
+	if payloads[0] != expected {
+		t.Errorf("delivered %+v, expected %+v", payloads[0], expected)
+	}
+
+	// A delivered payment is not posted again
+	err = server.processWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(receiver.received()) != 1 {
+		t.Errorf("delivered %d payloads, expected the payment only once", len(receiver.received()))
+	}
+
+	deliveries := testDeliveries(t, server)
+	if len(deliveries) != 1 || deliveries[0].Status != core.DeliveryDelivered {
+		t.Errorf("stored deliveries %+v, expected a delivered one", deliveries)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	server, _ := testNode(t)
+	server.cfg.WebhookConfig.MaxAttempts = 3
+	server.cfg.WebhookConfig.RetryBaseDelay = 5 * time.Second
+	server.cfg.WebhookConfig.RetryMaxDelay = time.Minute
+
+	now := time.Unix(1700000000, 0)
+	server.webhooks.now = func() time.Time { return now }
+
+	receiver := newWebhookReceiver(t, "secret", http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusInternalServerError)
+
+	address := testAddress(t, server.cfg)
+
+	_, err := core.NewWebhooks(server.cfg, server.bc).Add(address, receiver.server.URL, 1, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mineReward(t, server, address)
+
+	steps := []struct {
+		advance  time.Duration
+		posted   int
+		status   string
+		attempts int
+	}{
+		{advance: 0, posted: 1, status: core.DeliveryPending, attempts: 1},
+		// The retry waits for the base delay
+		{advance: 4 * time.Second, posted: 1, status: core.DeliveryPending, attempts: 1},
+		{advance: time.Second, posted: 2, status: core.DeliveryPending, attempts: 2},
+		// The delay doubles after every failed attempt
+		{advance: 5 * time.Second, posted: 2, status: core.DeliveryPending, attempts: 2},
+		{advance: 5 * time.Second, posted: 3, status: core.DeliveryFailed, attempts: 3},
+		// A failed delivery is not retried
+		{advance: time.Hour, posted: 3, status: core.DeliveryFailed, attempts: 3},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.advance)
+
+		err = server.processWebhooks()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(receiver.received()) != step.posted {
+			t.Errorf("step %d: posted %d times, expected %d", i, len(receiver.received()), step.posted)
+		}
+
+		deliveries := testDeliveries(t, server)
+		if len(deliveries) != 1 {
+			t.Fatalf("step %d: stored %d deliveries, expected 1", i, len(deliveries))
+		}
+
+		if deliveries[0].Status != step.status || deliveries[0].Attempts != step.attempts {
+			t.Errorf("step %d: delivery is %s after %d attempts, expected %s after %d", i, deliveries[0].Status, deliveries[0].Attempts, step.status, step.attempts)
+		}
+	}
+
+	for i, payload := range receiver.received() {
+		if payload.Attempt != i+1 {
+			t.Errorf("post %d carries attempt %d", i, payload.Attempt)
+		}
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"value":10}`)
+	signature := SignWebhookPayload("secret", body)
+
+	if !VerifyWebhookSignature("secret", body, signature) {
+		t.Error("signature of the payload does not verify")
+	}
+
+	if VerifyWebhookSignature("other", body, signature) {
+		t.Error("signature verifies with another secret")
+	}
+
+	if VerifyWebhookSignature("secret", []byte(`{"value":11}`), signature) {
+		t.Error("signature verifies with another payload")
+	}
+}
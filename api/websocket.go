@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WebSocket opcodes (RFC 6455 section 5.2)
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const (
+	webSocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxWebSocketMessage = 1 << 20
+	maxControlPayload   = 125
+)
+
+// ErrOriginNotAllowed is returned when a browser page from another origin opens a WebSocket connection
+var ErrOriginNotAllowed = errors.New("websocket origin is not allowed")
+
+// wsConn is a server side WebSocket connection
+type wsConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+}
+
+// upgradeWebSocket performs the WebSocket opening handshake and takes over the connection. Browsers send the origin of
+// the page opening the connection, which must be the API itself or one of the allowed origins
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, apiConfig model.APIConfig) (*wsConn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, &badRequestError{message: "expected a websocket upgrade request"}
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, &badRequestError{message: "unsupported websocket version"}
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decodedKey) != 16 {
+		return nil, &badRequestError{message: "invalid Sec-WebSocket-Key header"}
+	}
+
+	if !isOriginAllowed(r, apiConfig.AllowedOrigins, apiConfig.AllowMissingOrigin) {
+		return nil, ErrOriginNotAllowed
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("websocket upgrade is not supported by the response writer")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	accept := sha1.Sum([]byte(key + webSocketGUID))
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"
+
+	_, err = rw.WriteString(response)
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	return &wsConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// isOriginAllowed checks the Origin header of a request against the host it was sent to and the allowed origins.
+// Requests without an Origin header, which browsers always send, come from other clients and are only allowed when
+// configured
+func isOriginAllowed(r *http.Request, allowedOrigins []string, allowMissingOrigin bool) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return allowMissingOrigin
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(originURL.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadMessage reads the next data message, answering control frames along the way
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	fragmented := false
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		switch opcode {
+		case opPing:
+			err = c.WriteMessage(opPong, payload)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.WriteMessage(opClose, payload)
+
+			return nil, io.EOF
+		case opContinuation:
+			if !fragmented {
+				return nil, fmt.Errorf("websocket continuation frame without a message")
+			}
+
+			message = append(message, payload...)
+		case opText, opBinary:
+			if fragmented {
+				return nil, fmt.Errorf("websocket data frame inside a fragmented message")
+			}
+
+			message = append(message, payload...)
+		default:
+			return nil, fmt.Errorf("unknown websocket opcode %d", opcode)
+		}
+
+		if len(message) > maxWebSocketMessage {
+			return nil, fmt.Errorf("websocket message too large")
+		}
+
+		if fin {
+			return message, nil
+		}
+
+		fragmented = true
+	}
+}
+
+// readFrame reads a single frame from the client, which must be masked, without extensions and, for control frames,
+// unfragmented with a short payload
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("websocket extensions are not supported")
+	}
+
+	if opcode >= opClose && (!fin || length > maxControlPayload) {
+		return false, 0, nil, fmt.Errorf("websocket control frames must not be fragmented or longer than %d bytes", maxControlPayload)
+	}
+
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+
+		_, err = io.ReadFull(c.reader, extended)
+		if err != nil {
+			return false, 0, nil, err
+		}
+
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+
+		_, err = io.ReadFull(c.reader, extended)
+		if err != nil {
+			return false, 0, nil, err
+		}
+
+		length = binary.BigEndian.Uint64(extended)
+	}
+
+	if !masked {
+		return false, 0, nil, fmt.Errorf("client websocket frames must be masked")
+	}
+
+	if length > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("websocket frame too large")
+	}
+
+	mask := make([]byte, 4)
+
+	_, err = io.ReadFull(c.reader, mask)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage writes a single unmasked frame
+func (c *wsConn) WriteMessage(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	frame := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	frame = append(frame, payload...)
+
+	_, err := c.conn.Write(frame)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying connection
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
+
+// headerContains checks whether a comma separated header contains a token, ignoring case
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testWebSocketKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// testConfig returns a configuration with what the websocket handlers need
+func testConfig() *model.Config {
+	return &model.Config{
+		TransactionConfig: model.TransactionConfig{Subsidy: 10},
+		WalletConfig:      model.WalletConfig{CheckSumLength: 4},
+		APIConfig:         model.APIConfig{AllowedOrigins: []string{"https://explorer.example.com"}},
+	}
+}
+
+// testClient is the client side of a WebSocket connection
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialWebSocket sends an opening handshake with the given headers to the server and returns the connection and response
+func dialWebSocket(t *testing.T, server *httptest.Server, headers map[string]string) (*testClient, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Headers with an empty value are left out
+	request := "GET /ws HTTP/1.1\r\nHost: " + server.Listener.Addr().String() + "\r\n"
+	for name, value := range headers {
+		if value != "" {
+			request += name + ": " + value + "\r\n"
+		}
+	}
+
+	_, err = conn.Write([]byte(request + "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testClient{conn: conn, reader: reader}, response
+}
+
+// upgradeHeaders returns the headers of a valid opening handshake
+func upgradeHeaders() map[string]string {
+	return map[string]string{
+		"Connection":            "keep-alive, Upgrade",
+		"Upgrade":               "websocket",
+		"Sec-WebSocket-Version": "13",
+		"Sec-WebSocket-Key":     testWebSocketKey,
+		"Origin":                "https://explorer.example.com",
+	}
+}
+
+// connectWebSocket completes an opening handshake with the server
+func connectWebSocket(t *testing.T, server *httptest.Server) *testClient {
+	t.Helper()
+
+	client, response := dialWebSocket(t, server, upgradeHeaders())
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake answered %d, expected %d", response.StatusCode, http.StatusSwitchingProtocols)
+	}
+
+	return client
+}
+
+// writeFrame writes a single frame masked as a client must
+func (c *testClient) writeFrame(t *testing.T, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+
+	frame := []byte{first}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := c.conn.Write(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFrame reads a single unmasked frame from the server
+func (c *testClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, 2)
+
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		t.Fatalf("server sent a fragmented or masked frame %x", header)
+	}
+
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(c.reader, extended)
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		_, err = io.ReadFull(c.reader, extended)
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, length)
+
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return header[0] & 0x0F, payload
+}
+
+// readJSON reads a text frame and decodes it
+func (c *testClient) readJSON(t *testing.T, v any) {
+	t.Helper()
+
+	opcode, payload := c.readFrame(t)
+	if opcode != opText {
+		t.Fatalf("received opcode %d, expected a text frame", opcode)
+	}
+
+	err := json.Unmarshal(payload, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectClosed checks that the server closed the connection without sending anything else
+func (c *testClient) expectClosed(t *testing.T) {
+	t.Helper()
+
+	_, err := c.reader.ReadByte()
+	if err != io.EOF {
+		t.Fatalf("read %v, expected the connection to be closed", err)
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	server := httptest.NewServer(NewServer(testConfig()))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "valid handshake", headers: map[string]string{}, status: http.StatusSwitchingProtocols},
+		{name: "origin of the API", headers: map[string]string{"Origin": server.URL}, status: http.StatusSwitchingProtocols},
+		{name: "allowed origin", headers: map[string]string{"Origin": "https://explorer.example.com"}, status: http.StatusSwitchingProtocols},
+		{name: "other origin", headers: map[string]string{"Origin": "https://attacker.example.com"}, status: http.StatusForbidden},
+		{name: "missing origin", headers: map[string]string{"Origin": ""}, status: http.StatusForbidden},
+		{name: "missing upgrade", headers: map[string]string{"Upgrade": ""}, status: http.StatusBadRequest},
+		{name: "unsupported version", headers: map[string]string{"Sec-WebSocket-Version": "8"}, status: http.StatusBadRequest},
+		{name: "missing key", headers: map[string]string{"Sec-WebSocket-Key": ""}, status: http.StatusBadRequest},
+		{name: "key of the wrong length", headers: map[string]string{"Sec-WebSocket-Key": "c2hvcnQ="}, status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			headers := upgradeHeaders()
+			for name, value := range test.headers {
+				headers[name] = value
+			}
+
+			_, response := dialWebSocket(t, server, headers)
+
+			if response.StatusCode != test.status {
+				t.Fatalf("handshake answered %d, expected %d", response.StatusCode, test.status)
+			}
+
+			if test.status != http.StatusSwitchingProtocols {
+				return
+			}
+
+			// Accept value of the sample handshake of RFC 6455 section 1.3
+			accept := response.Header.Get("Sec-WebSocket-Accept")
+			if accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+				t.Errorf("Sec-WebSocket-Accept is %s", accept)
+			}
+		})
+	}
+}
+
+func TestWebSocketFraming(t *testing.T) {
+	server := httptest.NewServer(NewServer(testConfig()))
+	defer server.Close()
+
+	request := []byte(`{"action":"subscribe","topics":["newBlock"]}`)
+
+	t.Run("fragmented message with an interleaved ping", func(t *testing.T) {
+		client := connectWebSocket(t, server)
+
+		client.writeFrame(t, false, opText, request[:10])
+		client.writeFrame(t, true, opPing, []byte("ping"))
+		client.writeFrame(t, false, opContinuation, request[10:20])
+		client.writeFrame(t, true, opContinuation, request[20:])
+
+		opcode, payload := client.readFrame(t)
+		if opcode != opPong || string(payload) != "ping" {
+			t.Fatalf("received opcode %d with %q, expected the pong", opcode, payload)
+		}
+
+		var response subscriptionResponse
+		client.readJSON(t, &response)
+
+		if response.Type != "subscribed" || len(response.Topics) != 1 || response.Topics[0] != TopicNewBlock {
+			t.Errorf("received %+v, expected the subscription to newBlock", response)
+		}
+	})
+
+	t.Run("message with an extended length", func(t *testing.T) {
+		client := connectWebSocket(t, server)
+
+		padded := append(bytes.Repeat([]byte(" "), 300), request...)
+		client.writeFrame(t, true, opText, padded)
+
+		var response subscriptionResponse
+		client.readJSON(t, &response)
+
+		if response.Type != "subscribed" {
+			t.Errorf("received %+v, expected the subscription to newBlock", response)
+		}
+	})
+
+	t.Run("close handshake", func(t *testing.T) {
+		client := connectWebSocket(t, server)
+
+		client.writeFrame(t, true, opClose, []byte{0x03, 0xE8})
+
+		opcode, payload := client.readFrame(t)
+		if opcode != opClose || !bytes.Equal(payload, []byte{0x03, 0xE8}) {
+			t.Fatalf("received opcode %d with %x, expected the close frame to be echoed", opcode, payload)
+		}
+
+		client.expectClosed(t)
+	})
+
+	invalidFrames := []struct {
+		name  string
+		write func(t *testing.T, client *testClient)
+	}{
+		{name: "unmasked frame", write: func(t *testing.T, client *testClient) {
+			client.conn.Write(append([]byte{0x80 | opText, byte(len(request))}, request...))
+		}},
+		{name: "reserved bits", write: func(t *testing.T, client *testClient) {
+			client.writeFrame(t, true, 0x40|opText, request)
+		}},
+		{name: "fragmented control frame", write: func(t *testing.T, client *testClient) {
+			client.writeFrame(t, false, opPing, []byte("ping"))
+		}},
+		{name: "control frame too long", write: func(t *testing.T, client *testClient) {
+			client.writeFrame(t, true, opPing, bytes.Repeat([]byte("p"), maxControlPayload+1))
+		}},
+		{name: "continuation without a message", write: func(t *testing.T, client *testClient) {
+			client.writeFrame(t, true, opContinuation, request)
+		}},
+		{name: "new message inside a fragmented one", write: func(t *testing.T, client *testClient) {
+			client.writeFrame(t, false, opText, request[:10])
+			client.writeFrame(t, true, opText, request)
+		}},
+		{name: "unknown opcode", write: func(t *testing.T, client *testClient) {
+			client.writeFrame(t, true, 0x3, request)
+		}},
+	}
+
+	for _, test := range invalidFrames {
+		t.Run(test.name, func(t *testing.T) {
+			client := connectWebSocket(t, server)
+
+			test.write(t, client)
+
+			client.expectClosed(t)
+		})
+	}
+}
+
+func TestWebSocketPublishesAcceptedTransactions(t *testing.T) {
+	cfg := testConfig()
+	apiServer := NewServer(cfg)
+	server := httptest.NewServer(apiServer)
+	defer server.Close()
+
+	wallet, err := core.NewWallet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := wallet.GetAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := core.NewCoinbaseTX(cfg, string(address), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := connectWebSocket(t, server)
+	topics := []string{TopicNewTx, TopicAddressPrefix + string(address)}
+
+	request, err := json.Marshal(subscriptionRequest{Action: "subscribe", Topics: topics})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.writeFrame(t, true, opText, request)
+
+	var response subscriptionResponse
+	client.readJSON(t, &response)
+
+	if response.Type != "subscribed" {
+		t.Fatalf("received %+v, expected the subscription", response)
+	}
+
+	err = apiServer.publishTransaction(transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, topic := range topics {
+		var event Event
+		client.readJSON(t, &event)
+
+		if event.Topic != topic || event.Type != EventTransactionAccepted {
+			t.Errorf("received a %s event on %s, expected %s on %s", event.Type, event.Topic, EventTransactionAccepted, topic)
+		}
+
+		if event.Transaction == nil || event.Transaction.ID != hex.EncodeToString(transaction.ID) {
+			t.Errorf("event carries %+v, expected transaction %x", event.Transaction, transaction.ID)
+		}
+	}
+}
+
+func TestWebSocketAllowMissingOrigin(t *testing.T) {
+	cfg := testConfig()
+	cfg.APIConfig.AllowMissingOrigin = true
+
+	server := httptest.NewServer(NewServer(cfg))
+	defer server.Close()
+
+	headers := upgradeHeaders()
+	delete(headers, "Origin")
+
+	_, response := dialWebSocket(t, server, headers)
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("handshake without an origin answered %d, expected %d", response.StatusCode, http.StatusSwitchingProtocols)
+	}
+}
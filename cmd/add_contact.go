@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewAddContactCmd(cfg *model.Config) *cobra.Command {
+	addContactCmd := &cobra.Command{
+		Use:   "add-contact",
+		Short: "Adds an address to the address book",
+		Long:  "This command will store an external address in the address book under a name, which can then be used as the recipient of a send",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := addContact(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	addContactCmd.Flags().StringVarP(&contactName, "name", "n", "", "Name of the contact. (required)")
+	addContactCmd.MarkFlagRequired("name")
+	addContactCmd.Flags().StringVarP(&address, "address", "a", "", "Address of the contact. (required)")
+	addContactCmd.MarkFlagRequired("address")
+
+	return addContactCmd
+}
+
+func addContact(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.AddContact(contactName, address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SaveToFile()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Added contact '%s': %s", contactName, address)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewAddWebhookCmd(cfg *model.Config) *cobra.Command {
+	addWebhookCmd := &cobra.Command{
+		Use:   "add-webhook",
+		Short: "Registers a webhook for incoming payments",
+		Long:  "This command will register a URL that the block explorer API notifies once payments to the address reach the required number of confirmations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := addWebhook(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	addWebhookCmd.Flags().StringVarP(&address, "address", "a", "", "Address of the wallet receiving the payments. (required)")
+	addWebhookCmd.MarkFlagRequired("address")
+	addWebhookCmd.Flags().StringVarP(&webhookURL, "url", "u", "", "URL the payments are posted to. (required)")
+	addWebhookCmd.MarkFlagRequired("url")
+	addWebhookCmd.Flags().IntVarP(&confirmations, "confirmations", "c", 1, "Number of confirmations a payment needs before it is posted.")
+	addWebhookCmd.Flags().StringVarP(&secret, "secret", "s", "", "Secret used to sign the payloads, generated when empty.")
+
+	return addWebhookCmd
+}
+
+func addWebhook(cfg *model.Config) error {
+	isValidate, err := core.ValidateAddress(cfg, address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !(*isValidate) {
+		fmt.Printf("Address is not valid!")
+
+		return nil
+	}
+
+	if confirmations < 1 {
+		err := fmt.Errorf("confirmations must be at least 1")
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	webhook, err := core.NewWebhooks(cfg, blockchain).Add(address, webhookURL, confirmations, secret)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Registered webhook %s\nSecret: %s", webhook.ID, webhook.Secret)
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewAnchorCmd(cfg *model.Config) *cobra.Command {
+	anchorCmd := &cobra.Command{
+		Use:   "anchor",
+		Short: "Anchors the hash of a file in the blockchain",
+		Long:  "This command will commit the SHA-256 hash of a file in a data output of a new block, proving the file existed at the time of the block",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := anchor(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	anchorCmd.Flags().StringVarP(&from, "from", "f", "", "Address of the wallet paying for the transaction. (required)")
+	anchorCmd.MarkFlagRequired("from")
+	anchorCmd.Flags().StringVarP(&filePath, "file", "p", "", "Path of the file being anchored. (required)")
+	anchorCmd.MarkFlagRequired("file")
+
+	return anchorCmd
+}
+
+func anchor(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("anchor needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	fileHash, err := hashFile(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	utxoSet := core.NewUTXOSet(cfg, blockchain)
+
+	transaction, err := core.NewDataTransaction(*utxoSet, from, fileHash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	newBlock, err := mineTransaction(cfg, blockchain, transaction, from)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Anchored %x in transaction %x, block %x", fileHash, transaction.ID, newBlock.Hash)
+
+	return nil
+}
+
+// hashFile returns the SHA-256 hash of the content of a file
+func hashFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return hasher.Sum(nil), nil
+}
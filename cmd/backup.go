@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewBackupCmd(cfg *model.Config) *cobra.Command {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Backs up the blockchain and the wallet file",
+		Long:  "This command will take a consistent copy of the database and of the wallet file into a new directory named after the current time, along with a manifest of their checksums. The node can keep running while the copy is taken",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := backup(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	backupCmd.Flags().StringVarP(&destPath, "dest", "d", "", "Directory the backup is written to. (default: the configured backup directory)")
+
+	return backupCmd
+}
+
+func backup(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("backup needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	if destPath == "" {
+		destPath = cfg.BackupConfig.Dir
+	}
+
+	// A running node takes the backup with the database it keeps open
+	if node := api.DialNode(cfg); node != nil {
+		response, err := node.Backup(destPath)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		fmt.Printf("Backed up the blockchain at height %d, tip %s, to %s\n", response.Height, response.TipHash, response.Dir)
+
+		if !response.Wallet {
+			fmt.Println("No wallet file found, only the database was backed up")
+		}
+
+		return nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	manifest, err := blockchain.Backup(destPath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Backed up the blockchain at height %d, tip %s, to %s\n", manifest.Height, manifest.TipHash, manifest.Dir)
+
+	if manifest.Wallet == nil {
+		fmt.Println("No wallet file found, only the database was backed up")
+	}
+
+	return nil
+}
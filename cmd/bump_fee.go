@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewBumpFeeCmd(cfg *model.Config) *cobra.Command {
+	bumpFeeCmd := &cobra.Command{
+		Use:   "bump-fee",
+		Short: "Replaces a pending transaction with one paying a higher fee",
+		Long:  "This command will replace a transaction waiting in the mempool with one spending the same inputs and paying a higher fee out of its change, which the mempool accepts when it beats the fee and fee rate of the transactions it replaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := bumpFee(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	bumpFeeCmd.Flags().StringVarP(&transactionID, "txid", "i", "", "ID of the pending transaction. (required)")
+	bumpFeeCmd.MarkFlagRequired("txid")
+	bumpFeeCmd.Flags().IntVarP(&fee, "fee", "e", 0, "New fee of the transaction. (required)")
+	bumpFeeCmd.MarkFlagRequired("fee")
+
+	return bumpFeeCmd
+}
+
+func bumpFee(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("bump-fee needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	ID, err := hex.DecodeString(transactionID)
+	if err != nil {
+		err := fmt.Errorf("transaction ID must be hex encoded")
+		return utils.CatchErr(err)
+	}
+
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	mempool := core.NewMempool(cfg, blockchain)
+
+	entry, err := mempool.Get(ID)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	replacement, err := core.NewFeeBumpTransaction(entry, fee)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	privKeys, err := inputKeys(cfg, wallets, replacement)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = blockchain.SignTransactionInputs(replacement, privKeys)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	_, err = mempool.Add(replacement)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Replaced transaction %x with %x paying a fee of %d\n", entry.Transaction.ID, replacement.ID, fee)
+
+	return nil
+}
+
+// inputKeys returns the private key of the wallet owning the public key of each input of the transaction
+func inputKeys(cfg *model.Config, wallets *core.Wallets, transaction *core.Transaction) ([]ecdsa.PrivateKey, error) {
+	var privKeys []ecdsa.PrivateKey
+
+	for _, vin := range transaction.InputValue {
+		pubKeyHash, err := core.HashPubKey(vin.PubKey)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		wallet, err := wallets.GetSigningWallet(string(core.EncodeAddress(cfg, pubKeyHash)))
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if !bytes.Equal(wallet.PublicKey, vin.PubKey) {
+			return nil, fmt.Errorf("input %x:%d is not signed by a key of the wallet", vin.TransactionID, vin.OutputIndex)
+		}
+
+		privKeys = append(privKeys, wallet.PrivateKey)
+	}
+
+	return privKeys, nil
+}
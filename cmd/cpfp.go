@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCPFPCmd(cfg *model.Config) *cobra.Command {
+	cpfpCmd := &cobra.Command{
+		Use:   "cpfp",
+		Short: "Pays the fee of a pending incoming transaction by spending its output",
+		Long:  "This command will spend an output of a transaction waiting in the mempool back to the address it pays, with a fee high enough for miners to mine both transactions together. Fees that would not raise the fee rate of the pending transaction are refused",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := cpfp(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	cpfpCmd.Flags().StringVarP(&transactionID, "txid", "i", "", "ID of the pending transaction. (required)")
+	cpfpCmd.MarkFlagRequired("txid")
+	cpfpCmd.Flags().IntVarP(&outputIndex, "vout", "o", 0, "Index of the output paying the wallet. (required)")
+	cpfpCmd.MarkFlagRequired("vout")
+	cpfpCmd.Flags().IntVarP(&fee, "fee", "e", 0, "Fee of the spending transaction. (required)")
+	cpfpCmd.MarkFlagRequired("fee")
+
+	return cpfpCmd
+}
+
+func cpfp(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("cpfp needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	ID, err := hex.DecodeString(transactionID)
+	if err != nil {
+		err := fmt.Errorf("transaction ID must be hex encoded")
+		return utils.CatchErr(err)
+	}
+
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	mempool := core.NewMempool(cfg, blockchain)
+
+	parent, err := mempool.Get(ID)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if outputIndex < 0 || outputIndex >= len(parent.Transaction.OutputValue) {
+		err := fmt.Errorf("transaction %s has no output %d", transactionID, outputIndex)
+		return utils.CatchErr(err)
+	}
+
+	address := string(core.EncodeAddress(cfg, parent.Transaction.OutputValue[outputIndex].PubKeyHash))
+
+	wallet, err := wallets.GetSigningWallet(address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	child, err := core.NewCPFPTransaction(cfg, &parent.Transaction, outputIndex, wallet, fee)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	packageRate, err := core.CheckCPFPFeeRate(parent, child, fee)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	_, err = mempool.Add(child)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Transaction %x pays for %x at a combined fee rate of %.4f\n", child.ID, parent.Transaction.ID, *packageRate)
+
+	return nil
+}
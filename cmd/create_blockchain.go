@@ -37,18 +37,16 @@ func createBlockchain(cfg *model.Config) error {
 		return utils.CatchErr(err)
 	}
 
-	blockchain, err := core.NewBlockchain(cfg, address)
-	if err != nil {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("light clients only sync headers, create the blockchain on a full node")
 		return utils.CatchErr(err)
 	}
-	defer blockchain.Db.Close()
 
-	UTXOSet := core.NewUTXOSet(cfg, blockchain)
-
-	err = UTXOSet.Reindex()
+	blockchain, err := core.NewBlockchain(cfg, address)
 	if err != nil {
 		return utils.CatchErr(err)
 	}
+	defer blockchain.Close()
 
 	fmt.Println("Generated new blockhain. Sent rewards to: ", address)
 
@@ -24,6 +24,9 @@ func NewCreateWalletCmd(cfg *model.Config) *cobra.Command {
 		},
 	}
 
+	createWalletCmd.Flags().StringVarP(&label, "label", "l", "", "Label of the new address.")
+	createWalletCmd.Flags().StringVarP(&account, "account", "c", "", "Account the new address belongs to.")
+
 	return createWalletCmd
 }
 
@@ -38,6 +41,16 @@ func createWallet(cfg *model.Config) error {
 		return utils.CatchErr(err)
 	}
 
+	err = wallets.SetLabel(*address, label)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SetAccount(*address, account)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
 	err = wallets.SaveToFile()
 	if err != nil {
 		return utils.CatchErr(err)
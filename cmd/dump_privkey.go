@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDumpPrivKeyCmd(cfg *model.Config) *cobra.Command {
+	dumpPrivKeyCmd := &cobra.Command{
+		Use:   "dump-privkey",
+		Short: "Prints the private key of one of your addresses",
+		Long:  "This command will print the private key of an address of the wallet as checksummed Base58, so that it can be imported into another wallet",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := dumpPrivKey(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	dumpPrivKeyCmd.Flags().StringVarP(&address, "address", "a", "", "Address whose private key is printed. (required)")
+	dumpPrivKeyCmd.MarkFlagRequired("address")
+
+	return dumpPrivKeyCmd
+}
+
+func dumpPrivKey(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	wallet, err := wallets.GetSigningWallet(address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("%s", wallet.EncodePrivateKey())
+
+	return nil
+}
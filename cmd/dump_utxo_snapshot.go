@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDumpUTXOSnapshotCmd(cfg *model.Config) *cobra.Command {
+	dumpUTXOSnapshotCmd := &cobra.Command{
+		Use:   "dump-utxo-snapshot",
+		Short: "Writes the UTXO set to a snapshot file",
+		Long:  "This command will write the UTXO set, the tip it was built up to and its content hash to a file, from which new nodes can start without replaying the blockchain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := dumpUTXOSnapshot(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	dumpUTXOSnapshotCmd.Flags().StringVarP(&filePath, "file", "p", "", "Path of the snapshot file. (required)")
+	dumpUTXOSnapshotCmd.MarkFlagRequired("file")
+
+	return dumpUTXOSnapshotCmd
+}
+
+func dumpUTXOSnapshot(cfg *model.Config) error {
+	// A running node writes the file from the database it keeps open
+	if node := api.DialNode(cfg); node != nil {
+		response, err := node.DumpUTXOSnapshot(filePath)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		fmt.Printf("Wrote the UTXO set of %d transactions at block %s (height %d) to %s\n", response.Transactions, response.TipHash, response.Height, filePath)
+		fmt.Printf("Content hash: %s\n", response.Hash)
+
+		return nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	snapshot, err := core.NewUTXOSet(cfg, blockchain).Snapshot()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	err = snapshot.Write(writer)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Wrote the UTXO set of %d transactions at block %x (height %d) to %s\n", len(snapshot.Entries), snapshot.TipHash, snapshot.Height, filePath)
+	fmt.Printf("Content hash: %x\n", snapshot.Hash)
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// progressInterval is how many blocks are exported or imported between two progress reports
+const progressInterval = 100
+
+func NewExportChainCmd(cfg *model.Config) *cobra.Command {
+	exportChainCmd := &cobra.Command{
+		Use:   "export-chain",
+		Short: "Writes the blockchain to a block file",
+		Long:  "This command will write every block from the genesis block up to the tip to a file, which import-chain reads into another data directory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := exportChain(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	exportChainCmd.Flags().StringVarP(&filePath, "out", "o", "", "Path of the block file. (required)")
+	exportChainCmd.MarkFlagRequired("out")
+
+	return exportChainCmd
+}
+
+func exportChain(cfg *model.Config) error {
+	// A running node writes the file from the database it keeps open
+	if node := api.DialNode(cfg); node != nil {
+		response, err := node.ExportChain(filePath)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		fmt.Printf("Exported %d blocks to %s\n", response.Blocks, filePath)
+
+		return nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	bestHeight, err := blockchain.GetBestHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	err = blockchain.ExportChain(writer, func(height int) {
+		if height%progressInterval == 0 {
+			fmt.Printf("Exported block %d of %d\n", height, *bestHeight)
+		}
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	// A file left incomplete could be mistaken for a complete one
+	if err != nil {
+		file.Close()
+		os.Remove(filePath)
+
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Exported %d blocks to %s\n", *bestHeight+1, filePath)
+
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"go-burrokuchen/api"
 	"go-burrokuchen/core"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
@@ -12,8 +13,8 @@ import (
 func NewGetBalanceCmd(cfg *model.Config) *cobra.Command {
 	getBalanceCmd := &cobra.Command{
 		Use:   "get-balance",
-		Short: "Gets the balance of an address",
-		Long:  "This command will get the balance of the address that is specified",
+		Short: "Gets the balance of an address or account",
+		Long:  "This command will get the balance of the address that is specified, or the combined balance of the addresses of an account",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := getBalance(cfg)
 			if err != nil {
@@ -24,13 +25,19 @@ func NewGetBalanceCmd(cfg *model.Config) *cobra.Command {
 		},
 	}
 
-	getBalanceCmd.Flags().StringVarP(&address, "address", "a", "", "Address of the wallet in the blockchain. (required)")
-	getBalanceCmd.MarkFlagRequired("address")
+	getBalanceCmd.Flags().StringVarP(&address, "address", "a", "", "Address of the wallet in the blockchain.")
+	getBalanceCmd.Flags().StringVarP(&account, "account", "c", "", "Account whose addresses are added up.")
+	getBalanceCmd.MarkFlagsOneRequired("address", "account")
+	getBalanceCmd.MarkFlagsMutuallyExclusive("address", "account")
 
 	return getBalanceCmd
 }
 
 func getBalance(cfg *model.Config) error {
+	if account != "" {
+		return getAccountBalance(cfg)
+	}
+
 	isValidate, err := core.ValidateAddress(cfg, address)
 	if err != nil {
 		return utils.CatchErr(err)
@@ -42,11 +49,27 @@ func getBalance(cfg *model.Config) error {
 		return nil
 	}
 
-	blockchain, err := core.InitalizeBlockchain(cfg)
+	if cfg.LightClientConfig.Enabled {
+		return getLightBalance(cfg)
+	}
+
+	// A running node is asked instead of opening the database it uses
+	if node := api.DialNode(cfg); node != nil {
+		balance, err := node.GetBalance(address)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		fmt.Printf("Balance of address '%s': %d", address, *balance)
+
+		return nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
 	if err != nil {
 		return utils.CatchErr(err)
 	}
-	defer blockchain.Db.Close()
+	defer blockchain.Close()
 
 	UTXSOSet := core.NewUTXOSet(cfg, blockchain)
 
@@ -70,3 +93,87 @@ func getBalance(cfg *model.Config) error {
 
 	return nil
 }
+
+func getAccountBalance(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	addresses, err := wallets.GetAccountAddresses(account)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	balances, err := addressBalances(cfg, addresses)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	balance := 0
+	for _, accountAddress := range addresses {
+		balance += balances[accountAddress]
+	}
+
+	fmt.Printf("Balance of account '%s': %d", account, balance)
+
+	return nil
+}
+
+// addressBalances returns the balance of every address, from the UTXO set or through the light client
+func addressBalances(cfg *model.Config, addresses []string) (map[string]int, error) {
+	balances := make(map[string]int)
+
+	if cfg.LightClientConfig.Enabled {
+		client, err := openLightClient(cfg)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+		defer client.Headers.Close()
+
+		for _, balanceAddress := range addresses {
+			balance, err := client.GetBalance(balanceAddress)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			balances[balanceAddress] = *balance
+		}
+
+		return balances, nil
+	}
+
+	if node := api.DialNode(cfg); node != nil {
+		for _, balanceAddress := range addresses {
+			balance, err := node.GetBalance(balanceAddress)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			balances[balanceAddress] = *balance
+		}
+
+		return balances, nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	utxoSet := core.NewUTXOSet(cfg, blockchain)
+
+	for _, balanceAddress := range addresses {
+		UTXOs, err := utxoSet.FindUTXOByPubKeyHash(core.DecodeAddress(cfg, balanceAddress))
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		for _, out := range UTXOs.Outputs {
+			balances[balanceAddress] += out.Value
+		}
+	}
+
+	return balances, nil
+}
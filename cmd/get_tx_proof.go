@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewGetTxProofCmd(cfg *model.Config) *cobra.Command {
+	getTxProofCmd := &cobra.Command{
+		Use:   "get-tx-proof",
+		Short: "Gets a Merkle proof of a transaction",
+		Long:  "This command will print the block header and the Merkle path proving that the transaction is included in its block",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := getTxProof(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	getTxProofCmd.Flags().StringVarP(&transactionID, "txid", "i", "", "ID of the transaction. (required)")
+	getTxProofCmd.MarkFlagRequired("txid")
+
+	return getTxProofCmd
+}
+
+func getTxProof(cfg *model.Config) error {
+	ID, err := hex.DecodeString(transactionID)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	proof, height, err := transactionProof(cfg, ID)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Block:       %x (height %d)\n", proof.Header.Hash, *height)
+	fmt.Printf("Merkle root: %x\n", proof.Header.MerkleRoot)
+	fmt.Printf("Transaction: %x (index %d)\n", proof.TransactionID, proof.Index)
+	fmt.Printf("Leaf hash:   %x\n", proof.LeafHash)
+	fmt.Println("Proof:")
+
+	for _, step := range proof.Proof {
+		side := "right"
+		if step.Left {
+			side = "left"
+		}
+
+		fmt.Printf("  %-5s %x\n", side, step.Hash)
+	}
+
+	if !proof.Verify() {
+		err := fmt.Errorf("generated proof does not match the Merkle root")
+		return utils.CatchErr(err)
+	}
+
+	fmt.Println("Proof is valid")
+
+	return nil
+}
+
+// transactionProof returns the Merkle proof of a transaction and the height of its block, from the running node or
+// from the database
+func transactionProof(cfg *model.Config, ID []byte) (*core.TransactionProof, *int, error) {
+	if node := api.DialNode(cfg); node != nil {
+		response, err := node.GetTransactionProof(ID)
+		if err != nil {
+			return nil, nil, utils.CatchErr(err)
+		}
+
+		proof, err := api.ParseTransactionProof(*response)
+		if err != nil {
+			return nil, nil, utils.CatchErr(err)
+		}
+
+		return proof, &response.Header.Height, nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	block, err := blockchain.FindTransactionBlock(ID)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	height, err := blockchain.GetBlockHeight(block.Hash)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	proof, err := block.ProveTransaction(ID)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	return proof, height, nil
+}
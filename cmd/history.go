@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewHistoryCmd(cfg *model.Config) *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Lists the transactions of an address or account",
+		Long:  "This command will list the confirmed transactions that paid to or spent from the address or the addresses of the account that is specified, including watch-only addresses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := history(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	historyCmd.Flags().StringVarP(&address, "address", "a", "", "Address whose transactions are listed.")
+	historyCmd.Flags().StringVarP(&account, "account", "c", "", "Account whose transactions are listed.")
+	historyCmd.MarkFlagsOneRequired("address", "account")
+	historyCmd.MarkFlagsMutuallyExclusive("address", "account")
+
+	return historyCmd
+}
+
+func history(cfg *model.Config) error {
+	addresses := []string{address}
+
+	if account != "" {
+		wallets, err := core.NewWallets(cfg)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		addresses, err = wallets.GetAccountAddresses(account)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	} else {
+		isValidate, err := core.ValidateAddress(cfg, address)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if !(*isValidate) {
+			fmt.Printf("Address is not valid!")
+
+			return nil
+		}
+	}
+
+	tracker, err := trackAddresses(cfg, addresses)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if tracker.PrunedHeight >= 0 {
+		fmt.Printf("Blocks up to height %d were pruned, their transactions are not listed\n", tracker.PrunedHeight)
+	}
+
+	balance := 0
+
+	for _, entry := range tracker.History {
+		balance += entry.Received - entry.Sent
+
+		fmt.Printf("%d %s %x received %d sent %d balance %d\n",
+			entry.Height, time.Unix(entry.Timestamp, 0).Format(time.RFC3339), entry.TransactionID, entry.Received, entry.Sent, balance)
+	}
+
+	return nil
+}
+
+// trackAddresses follows the addresses through the main chain of the running node or of the database, or through the blocks matching their filters in light client mode
+func trackAddresses(cfg *model.Config, addresses []string) (*core.AddressTracker, error) {
+	if cfg.LightClientConfig.Enabled {
+		if !cfg.LightClientConfig.UseFilters {
+			err := fmt.Errorf("history needs light_client.use_filters in light client mode")
+			return nil, utils.CatchErr(err)
+		}
+
+		client, err := openLightClient(cfg)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+		defer client.Headers.Close()
+
+		tracker, err := client.ScanAddresses(addresses)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return tracker, nil
+	}
+
+	if node := api.DialNode(cfg); node != nil {
+		tracker, err := node.TrackAddresses(addresses)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return tracker, nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	var pubKeyHashes [][]byte
+	for _, trackedAddress := range addresses {
+		pubKeyHashes = append(pubKeyHashes, core.DecodeAddress(cfg, trackedAddress))
+	}
+
+	tracker, err := blockchain.TrackAddresses(pubKeyHashes)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return tracker, nil
+}
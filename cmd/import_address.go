@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewImportAddressCmd(cfg *model.Config) *cobra.Command {
+	importAddressCmd := &cobra.Command{
+		Use:   "import-address",
+		Short: "Imports a watch-only address",
+		Long:  "This command will add an address to the wallet without its private key, so that its balance and history can be tracked but it cannot send",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := importAddress(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	importAddressCmd.Flags().StringVarP(&address, "address", "a", "", "Address being watched. (required)")
+	importAddressCmd.MarkFlagRequired("address")
+	importAddressCmd.Flags().StringVarP(&label, "label", "l", "", "Label of the address.")
+	importAddressCmd.Flags().StringVarP(&account, "account", "c", "", "Account the address belongs to.")
+
+	return importAddressCmd
+}
+
+func importAddress(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.ImportAddress(address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = saveImportedAddress(wallets, address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Watching address: %s", address)
+
+	return nil
+}
+
+// saveImportedAddress applies the label and account flags that were given to an imported address and saves the wallets,
+// so that a watch-only address upgraded by its private key keeps its label and account
+func saveImportedAddress(wallets *core.Wallets, importedAddress string) error {
+	if label != "" {
+		err := wallets.SetLabel(importedAddress, label)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	if account != "" {
+		err := wallets.SetAccount(importedAddress, account)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	err := wallets.SaveToFile()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
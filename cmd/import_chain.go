@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewImportChainCmd(cfg *model.Config) *cobra.Command {
+	importChainCmd := &cobra.Command{
+		Use:   "import-chain",
+		Short: "Reads the blockchain from a block file",
+		Long:  "This command will read the blocks of a file written by export-chain, checking their proof of work with the algorithm named in the file, signatures and linkage, and connect them with their UTXO changes. An interrupted import resumes when it is run again with the same file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := importChain(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	importChainCmd.Flags().StringVarP(&filePath, "in", "i", "", "Path of the block file. (required)")
+	importChainCmd.MarkFlagRequired("in")
+
+	return importChainCmd
+}
+
+func importChain(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("light clients only sync headers, import the chain on a full node")
+		return utils.CatchErr(err)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	header, err := core.ReadChainFileHeader(reader)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.OpenImportBlockchain(cfg, header)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	if blockchain.Tip != nil {
+		bestHeight, err := blockchain.GetBestHeight()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		fmt.Printf("Resuming after block %d\n", *bestHeight)
+	}
+
+	imported, err := blockchain.ImportChain(reader, func(height int) {
+		if height%progressInterval == 0 {
+			fmt.Printf("Imported block %d\n", height)
+		}
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	bestHeight, err := blockchain.GetBestHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Imported %d blocks, the tip is at height %d\n", *imported, *bestHeight)
+
+	return nil
+}
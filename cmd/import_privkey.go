@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func NewImportPrivKeyCmd(cfg *model.Config) *cobra.Command {
+	importPrivKeyCmd := &cobra.Command{
+		Use:   "import-privkey",
+		Short: "Imports a private key",
+		Long:  "This command will add the address of a private key printed by dump-privkey to the wallet and rescan the chain for its unspent outputs. The key is read from a prompt, or from the first line of the standard input when it is not a terminal",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := importPrivKey(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	importPrivKeyCmd.Flags().StringVarP(&privKey, "privkey", "p", "", "Base58 encoded private key, only read with --allow-privkey-flag.")
+	importPrivKeyCmd.Flags().BoolVar(&allowPrivKeyFlag, "allow-privkey-flag", false, "Read the key from --privkey, which exposes it in the process list and shell history.")
+	importPrivKeyCmd.Flags().StringVarP(&label, "label", "l", "", "Label of the address.")
+	importPrivKeyCmd.Flags().StringVarP(&account, "account", "c", "", "Account the address belongs to.")
+
+	return importPrivKeyCmd
+}
+
+func importPrivKey(cfg *model.Config) error {
+	encodedKey, err := readPrivKey()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	wallet, err := core.DecodePrivateKey(cfg, encodedKey)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	importedAddress, err := wallets.ImportPrivateKey(wallet)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = saveImportedAddress(wallets, *importedAddress)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Imported address: %s\n", *importedAddress)
+
+	UTXOCount, balance, err := rescanAddress(cfg, *importedAddress)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if UTXOCount != nil {
+		fmt.Printf("Rescan found %d unspent output(s), balance %d\n", *UTXOCount, *balance)
+	} else {
+		fmt.Printf("Rescan found balance %d\n", *balance)
+	}
+
+	return nil
+}
+
+// readPrivKey returns the private key given with --privkey when it is allowed, otherwise it prompts for the key without
+// echoing it, or reads the first line of the standard input when it is not a terminal
+func readPrivKey() (string, error) {
+	if privKey != "" {
+		if !allowPrivKeyFlag {
+			return "", fmt.Errorf("--privkey exposes the key in the process list and shell history, pass --allow-privkey-flag to use it or leave it out to be prompted")
+		}
+
+		return privKey, nil
+	}
+
+	stdin := int(os.Stdin.Fd())
+
+	if term.IsTerminal(stdin) {
+		fmt.Fprint(os.Stderr, "Private key: ")
+
+		encodedKey, err := term.ReadPassword(stdin)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", utils.CatchErr(err)
+		}
+
+		return strings.TrimSpace(string(encodedKey)), nil
+	}
+
+	encodedKey, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", utils.CatchErr(err)
+	}
+
+	encodedKey = strings.TrimSpace(encodedKey)
+	if encodedKey == "" {
+		return "", fmt.Errorf("no private key on the standard input")
+	}
+
+	return encodedKey, nil
+}
+
+// rescanAddress follows an address through the chain and returns the number of its unspent outputs and its balance.
+// Light clients without filters can only ask the full node for the balance, so the count is nil for them.
+func rescanAddress(cfg *model.Config, rescannedAddress string) (*int, *int, error) {
+	if cfg.LightClientConfig.Enabled && !cfg.LightClientConfig.UseFilters {
+		balances, err := addressBalances(cfg, []string{rescannedAddress})
+		if err != nil {
+			return nil, nil, utils.CatchErr(err)
+		}
+
+		balance := balances[rescannedAddress]
+
+		return nil, &balance, nil
+	}
+
+	tracker, err := trackAddresses(cfg, []string{rescannedAddress})
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	UTXOCount := len(tracker.UTXOs)
+	balance := 0
+	for _, utxo := range tracker.UTXOs {
+		balance += utxo.Output.Value
+	}
+
+	return &UTXOCount, &balance, nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewImportPubKeyCmd(cfg *model.Config) *cobra.Command {
+	importPubKeyCmd := &cobra.Command{
+		Use:   "import-pubkey",
+		Short: "Imports a public key as a watch-only address",
+		Long:  "This command will add the address of a hex encoded public key to the wallet without its private key, so that its balance and history can be tracked but it cannot send",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := importPubKey(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	importPubKeyCmd.Flags().StringVarP(&pubKey, "pubkey", "k", "", "Hex encoded public key being watched. (required)")
+	importPubKeyCmd.MarkFlagRequired("pubkey")
+	importPubKeyCmd.Flags().StringVarP(&label, "label", "l", "", "Label of the address.")
+	importPubKeyCmd.Flags().StringVarP(&account, "account", "c", "", "Account the address belongs to.")
+
+	return importPubKeyCmd
+}
+
+func importPubKey(cfg *model.Config) error {
+	decodedPubKey, err := hex.DecodeString(pubKey)
+	if err != nil {
+		err := fmt.Errorf("public key must be hex encoded")
+		return utils.CatchErr(err)
+	}
+
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	importedAddress, err := wallets.ImportPubKey(decodedPubKey)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = saveImportedAddress(wallets, *importedAddress)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Watching address: %s", *importedAddress)
+
+	return nil
+}
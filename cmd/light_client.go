@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/spv"
+	"go-burrokuchen/utils"
+)
+
+// openLightClient opens the header store and catches its headers and filters up with the full node
+func openLightClient(cfg *model.Config) (*spv.Client, error) {
+	headers, err := core.OpenHeaderStore(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	client := spv.NewClient(cfg, headers)
+
+	_, err = client.SyncHeaders()
+	if err != nil {
+		headers.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	if cfg.LightClientConfig.UseFilters {
+		_, err = client.SyncFilters()
+		if err != nil {
+			headers.Close()
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	return client, nil
+}
+
+func getLightBalance(cfg *model.Config) error {
+	client, err := openLightClient(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer client.Headers.Close()
+
+	balance, err := client.GetBalance(address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Balance of address '%s': %d", address, *balance)
+
+	return nil
+}
+
+func lightSend(cfg *model.Config, wallets *core.Wallets, options core.TransactionOptions) error {
+	client, err := openLightClient(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer client.Headers.Close()
+
+	var response *api.SubmitTransactionResponse
+
+	if account != "" {
+		response, err = client.SendFromAccount(wallets, account, to, amount, options)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	} else {
+		wallet, err := wallets.GetSigningWallet(from)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		response, err = client.Send(*wallet, from, to, amount, options)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	// The full node only mines transactions for commands on its local socket, so it keeps this one in its mempool
+	fmt.Printf("Transaction %s is waiting in the mempool of the full node\n", response.TransactionID)
+
+	return nil
+}
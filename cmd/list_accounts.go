@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewListAccountsCmd(cfg *model.Config) *cobra.Command {
+	listAccountsCmd := &cobra.Command{
+		Use:   "list-accounts",
+		Short: "Lists your accounts",
+		Long:  "This command will list the accounts of the wallet along with the number of addresses and the balance of each",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := listAccounts(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	return listAccountsCmd
+}
+
+func listAccounts(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	balances, err := addressBalances(cfg, wallets.GetAddresses())
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, walletAccount := range wallets.GetAccounts() {
+		addresses, err := wallets.GetAccountAddresses(walletAccount)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		balance := 0
+		for _, accountAddress := range addresses {
+			balance += balances[accountAddress]
+		}
+
+		fmt.Printf("%s: %d address(es), balance %d\n", walletAccount, len(addresses), balance)
+	}
+
+	return nil
+}
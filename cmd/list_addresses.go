@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewListAddressesCmd(cfg *model.Config) *cobra.Command {
+	listAddressesCmd := &cobra.Command{
+		Use:   "list-addresses",
+		Short: "Lists your addresses",
+		Long:  "This command will list the addresses of the wallet along with their account and label, marking watch-only addresses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := listAddresses(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	listAddressesCmd.Flags().StringVarP(&account, "account", "c", "", "Only list the addresses of this account.")
+
+	return listAddressesCmd
+}
+
+func listAddresses(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, walletAddress := range wallets.GetAddresses() {
+		walletAccount := wallets.GetAccount(walletAddress)
+		if account != "" && walletAccount != account {
+			continue
+		}
+
+		fmt.Printf("%s [%s]", walletAddress, walletAccount)
+		if wallets.IsWatchOnly(walletAddress) {
+			fmt.Printf(" (watch-only)")
+		}
+		if walletLabel := wallets.GetLabel(walletAddress); walletLabel != "" {
+			fmt.Printf(" %s", walletLabel)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
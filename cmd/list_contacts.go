@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+func NewListContactsCmd(cfg *model.Config) *cobra.Command {
+	listContactsCmd := &cobra.Command{
+		Use:   "list-contacts",
+		Short: "Lists the address book",
+		Long:  "This command will list the contacts of the address book",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := listContacts(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	return listContactsCmd
+}
+
+func listContacts(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	var names []string
+	for name := range wallets.Contacts {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, wallets.Contacts[name])
+	}
+
+	return nil
+}
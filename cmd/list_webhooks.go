@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewListWebhooksCmd(cfg *model.Config) *cobra.Command {
+	listWebhooksCmd := &cobra.Command{
+		Use:   "list-webhooks",
+		Short: "Lists the registered webhooks",
+		Long:  "This command will list the registered webhooks along with the state of their deliveries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := listWebhooks(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	return listWebhooksCmd
+}
+
+func listWebhooks(cfg *model.Config) error {
+	if node := api.DialNode(cfg); node != nil {
+		response, err := node.GetWebhooks()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, webhook := range response.Webhooks {
+			fmt.Printf("%s %s -> %s (%d confirmations)\n", webhook.ID, webhook.Address, webhook.URL, webhook.Confirmations)
+
+			for _, delivery := range webhook.Deliveries {
+				printDelivery(delivery.Key, delivery.Status, delivery.Attempts, delivery.LastError)
+			}
+		}
+
+		return nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	webhooks := core.NewWebhooks(cfg, blockchain)
+
+	registered, err := webhooks.List()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	deliveries, err := webhooks.Deliveries()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, webhook := range registered {
+		fmt.Printf("%s %s -> %s (%d confirmations)\n", webhook.ID, webhook.Address, webhook.URL, webhook.Confirmations)
+
+		for _, delivery := range deliveries {
+			if delivery.WebhookID != webhook.ID {
+				continue
+			}
+
+			printDelivery(delivery.Key(), delivery.Status, delivery.Attempts, delivery.LastError)
+		}
+	}
+
+	return nil
+}
+
+// printDelivery prints the state of the delivery of a payment to a webhook
+func printDelivery(key string, status string, attempts int, lastError string) {
+	fmt.Printf("  %s %s, %d attempt(s)", key, status, attempts)
+	if lastError != "" {
+		fmt.Printf(", last error: %s", lastError)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewLoadUTXOSnapshotCmd(cfg *model.Config) *cobra.Command {
+	loadUTXOSnapshotCmd := &cobra.Command{
+		Use:   "load-utxo-snapshot",
+		Short: "Creates the blockchain from a UTXO snapshot",
+		Long:  "This command will create the blockchain from a snapshot file whose content hash is trusted and the headers up to its tip, downloaded from the snapshot source. The content hash is compiled in for known snapshots, any other snapshot needs a trusted hash. The blocks below the snapshot are validated in the background once the API is started",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := loadUTXOSnapshot(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	loadUTXOSnapshotCmd.Flags().StringVarP(&filePath, "file", "p", "", "Path of the snapshot file. (required)")
+	loadUTXOSnapshotCmd.Flags().StringVarP(&trustedHash, "trusted-hash", "t", cfg.SnapshotConfig.TrustedHash, "Content hash the snapshot must have when none is compiled in for its tip.")
+	loadUTXOSnapshotCmd.MarkFlagRequired("file")
+
+	return loadUTXOSnapshotCmd
+}
+
+func loadUTXOSnapshot(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("light clients only sync headers, load the snapshot on a full node")
+		return utils.CatchErr(err)
+	}
+
+	var hash []byte
+
+	if trustedHash != "" {
+		decodedHash, err := hex.DecodeString(trustedHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		hash = decodedHash
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	snapshot, err := core.ReadUTXOSnapshot(bufio.NewReader(file))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	headers, err := api.NewSnapshotSource(cfg).Headers(snapshot.Height)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.LoadUTXOSnapshot(cfg, snapshot, hash, headers)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	fmt.Printf("Loaded the UTXO set of %d transactions at block %x (height %d)\n", len(snapshot.Entries), snapshot.TipHash, snapshot.Height)
+	fmt.Println("The blocks below the snapshot are validated once the API is started")
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewMempoolCmd(cfg *model.Config) *cobra.Command {
+	mempoolCmd := &cobra.Command{
+		Use:   "mempool",
+		Short: "Lists the transactions waiting to be mined",
+		Long:  "This command will list the transactions in the mempool with their fee, size, fee rate and how long they have been waiting",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := listMempool(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	return mempoolCmd
+}
+
+func listMempool(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("mempool needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	entries, err := mempoolEntries(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	size := 0
+
+	for _, entry := range entries {
+		age := time.Since(time.Unix(0, entry.AddedAt)).Truncate(time.Second)
+		size += entry.Size
+
+		fmt.Printf("%s fee: %d size: %d fee rate: %.4f waiting: %s\n", entry.TransactionID, entry.Fee, entry.Size, entry.FeeRate, age)
+	}
+
+	fmt.Printf("%d pending transactions, %d of %d bytes\n", len(entries), size, cfg.MempoolConfig.MaxSize)
+
+	return nil
+}
+
+// mempoolEntries returns the transactions in the mempool, from the running node or from the database
+func mempoolEntries(cfg *model.Config) ([]api.MempoolEntryResponse, error) {
+	if node := api.DialNode(cfg); node != nil {
+		response, err := node.GetMempool()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return response.Entries, nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	entries, err := core.NewMempool(cfg, blockchain).Entries()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var responses []api.MempoolEntryResponse
+
+	for _, entry := range entries {
+		responses = append(responses, api.MempoolEntryResponse{
+			TransactionID: hex.EncodeToString(entry.Transaction.ID),
+			Fee:           entry.Fee,
+			Size:          entry.Size,
+			FeeRate:       entry.FeeRate(),
+			AddedAt:       entry.AddedAt,
+		})
+	}
+
+	return responses, nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewMigrateCmd(cfg *model.Config) *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrades the database to the current schema",
+		Long:  "This command will back up the database and upgrade it from its schema version to the current one, one migration at a time. An interrupted migration resumes from the last completed one",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := migrate(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	migrateCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "List the pending migrations without changing the database.")
+	migrateCmd.Flags().StringVarP(&backupPath, "backup", "b", "", "Path of the backup taken before migrating. (default: the database name followed by the schema version)")
+
+	return migrateCmd
+}
+
+func migrate(cfg *model.Config) error {
+	if !utils.DbExists(cfg.DatabaseConfig.DbName) {
+		err := fmt.Errorf("no existing blockchain found, generate one first")
+		return utils.CatchErr(err)
+	}
+
+	store, err := core.OpenBoltChainStore(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer store.Close()
+
+	version, err := core.ReadSchemaVersion(cfg, store)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if *version > core.SchemaVersion {
+		err = fmt.Errorf("%w: version %d, up to %d is supported", core.ErrSchemaTooNew, *version, core.SchemaVersion)
+		return utils.CatchErr(err)
+	}
+
+	pending := core.PendingMigrations(*version)
+	if len(pending) == 0 {
+		fmt.Printf("Database schema is up to date at version %d\n", *version)
+		return nil
+	}
+
+	if backupPath == "" {
+		backupPath = core.MigrationBackupPath(cfg, *version)
+	}
+
+	fmt.Printf("Database schema is at version %d, %d migrations to version %d are pending:\n", *version, len(pending), core.SchemaVersion)
+
+	for _, migration := range pending {
+		fmt.Printf("  %d: %s\n", migration.Version, migration.Description)
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run, nothing was changed. The database would be backed up to %s first\n", backupPath)
+		return nil
+	}
+
+	fmt.Printf("Backing up the database to %s\n", backupPath)
+
+	err = core.MigrateStore(cfg, store, backupPath, func(migration core.Migration) {
+		fmt.Printf("Migrated to version %d: %s\n", migration.Version, migration.Description)
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
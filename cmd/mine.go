@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewMineCmd(cfg *model.Config) *cobra.Command {
+	mineCmd := &cobra.Command{
+		Use:   "mine",
+		Short: "Mines the pending transactions into a new block",
+		Long:  "This command will verify the transactions waiting in the mempool again and mine them into a new block, highest fee rates first up to the maximum block size, rewarding the given address with the subsidy and their fees",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := mine(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	mineCmd.Flags().StringVarP(&address, "address", "a", "", "Address receiving the mining reward. (required)")
+	mineCmd.MarkFlagRequired("address")
+
+	return mineCmd
+}
+
+func mine(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("mine needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	isValid, err := core.ValidateAddress(cfg, address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		err := fmt.Errorf("address %s is not valid", address)
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	mempool := core.NewMempool(cfg, blockchain)
+
+	// Transactions that expired or were invalidated since they were added are dropped first
+	_, err = mempool.Reload()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	transactions, fees, err := mempool.BlockTransactions()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	coinbaseTransaction, err := core.NewCoinbaseTXWithFees(cfg, address, "", *fees)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	newBlock, err := blockchain.MineBlock(append([]*core.Transaction{coinbaseTransaction}, transactions...))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Mined block %x with %d pending transactions and %d in fees\n", newBlock.Hash, len(transactions), *fees)
+
+	return nil
+}
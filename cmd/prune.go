@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewPruneCmd(cfg *model.Config) *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Deletes the transactions of old blocks",
+		Long:  "This command will delete the transactions of the blocks deeper than the prune depth, keeping their headers, the height index and the UTXO set. Pruned blocks can no longer be served or searched for transactions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := prune(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	pruneCmd.Flags().IntVarP(&pruneDepth, "depth", "d", cfg.DatabaseConfig.PruneDepth, "Number of recent blocks whose transactions are kept.")
+
+	return pruneCmd
+}
+
+func prune(cfg *model.Config) error {
+	if pruneDepth <= 0 {
+		err := fmt.Errorf("prune depth must be positive")
+		return utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	cfg.DatabaseConfig.PruneDepth = pruneDepth
+
+	pruned, err := blockchain.Prune()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	pruneHeight, err := blockchain.PruneHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Pruned %d blocks, blocks up to height %d keep only their header\n", *pruned, *pruneHeight)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRemoveContactCmd(cfg *model.Config) *cobra.Command {
+	removeContactCmd := &cobra.Command{
+		Use:   "remove-contact",
+		Short: "Removes an address from the address book",
+		Long:  "This command will remove a contact from the address book",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := removeContact(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	removeContactCmd.Flags().StringVarP(&contactName, "name", "n", "", "Name of the contact. (required)")
+	removeContactCmd.MarkFlagRequired("name")
+
+	return removeContactCmd
+}
+
+func removeContact(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.RemoveContact(contactName)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SaveToFile()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Removed contact '%s'", contactName)
+
+	return nil
+}
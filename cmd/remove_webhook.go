@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRemoveWebhookCmd(cfg *model.Config) *cobra.Command {
+	removeWebhookCmd := &cobra.Command{
+		Use:   "remove-webhook",
+		Short: "Removes a webhook",
+		Long:  "This command will remove a webhook along with the state of its deliveries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := removeWebhook(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	removeWebhookCmd.Flags().StringVarP(&webhookID, "id", "i", "", "ID of the webhook. (required)")
+	removeWebhookCmd.MarkFlagRequired("id")
+
+	return removeWebhookCmd
+}
+
+func removeWebhook(cfg *model.Config) error {
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	err = core.NewWebhooks(cfg, blockchain).Remove(webhookID)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Println("Removed webhook", webhookID)
+
+	return nil
+}
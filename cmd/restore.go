@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRestoreCmd(cfg *model.Config) *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restores the blockchain and the wallet file from a backup",
+		Long:  "This command will check the checksums of a backup and the consistency of its database before swapping its files in place of the database and the wallet file. The node must be stopped first, the replaced files are kept with the .pre-restore suffix",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := restore(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	restoreCmd.Flags().StringVarP(&srcPath, "src", "s", "", "Directory of the backup being restored. (required)")
+	restoreCmd.MarkFlagRequired("src")
+
+	return restoreCmd
+}
+
+func restore(cfg *model.Config) error {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("restore needs a full node")
+		return utils.CatchErr(err)
+	}
+
+	manifest, err := core.RestoreBackup(cfg, srcPath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Restored the blockchain at height %d, tip %s, from %s\n", manifest.Height, manifest.TipHash, manifest.Dir)
+
+	if manifest.Wallet != nil {
+		fmt.Printf("Restored the wallet file %s\n", cfg.WalletConfig.WalletFile)
+	}
+
+	return nil
+}
@@ -12,6 +12,41 @@ var (
 	from    string
 	to      string
 	amount  int
+
+	fee          int
+	queue        bool
+	lockTime     int64
+	relativeLock int
+
+	listenAddress string
+	webhookURL    string
+	webhookID     string
+	secret        string
+	confirmations int
+	transactionID string
+
+	label       string
+	account     string
+	contactName string
+	pubKey      string
+	privKey     string
+	message     string
+	signature   string
+
+	allowPrivKeyFlag bool
+
+	secretHash  string
+	outputIndex int
+	swapTimeout int
+
+	filePath    string
+	trustedHash string
+
+	pruneDepth int
+	dryRun     bool
+	backupPath string
+	destPath   string
+	srcPath    string
 )
 
 var rootCmd = &cobra.Command{
@@ -31,6 +66,45 @@ func Execute() error {
 		NewGetBalanceCmd(config),
 		NewSendCmd(config),
 		NewCreateWalletCmd(config),
+		NewStartAPICmd(config),
+		NewAddWebhookCmd(config),
+		NewListWebhooksCmd(config),
+		NewRemoveWebhookCmd(config),
+		NewGetTxProofCmd(config),
+		NewSyncHeadersCmd(config),
+		NewSetLabelCmd(config),
+		NewSetAccountCmd(config),
+		NewListAddressesCmd(config),
+		NewListAccountsCmd(config),
+		NewAddContactCmd(config),
+		NewRemoveContactCmd(config),
+		NewListContactsCmd(config),
+		NewImportAddressCmd(config),
+		NewImportPubKeyCmd(config),
+		NewHistoryCmd(config),
+		NewDumpPrivKeyCmd(config),
+		NewImportPrivKeyCmd(config),
+		NewSignMessageCmd(config),
+		NewVerifyMessageCmd(config),
+		NewSwapInitiateCmd(config),
+		NewSwapParticipateCmd(config),
+		NewSwapRedeemCmd(config),
+		NewSwapRefundCmd(config),
+		NewSwapAuditCmd(config),
+		NewAnchorCmd(config),
+		NewVerifyAnchorCmd(config),
+		NewMineCmd(config),
+		NewMempoolCmd(config),
+		NewBumpFeeCmd(config),
+		NewCPFPCmd(config),
+		NewPruneCmd(config),
+		NewDumpUTXOSnapshotCmd(config),
+		NewLoadUTXOSnapshotCmd(config),
+		NewExportChainCmd(config),
+		NewImportChainCmd(config),
+		NewMigrateCmd(config),
+		NewBackupCmd(config),
+		NewRestoreCmd(config),
 	)
 
 	err = rootCmd.Execute()
@@ -12,8 +12,8 @@ import (
 func NewSendCmd(cfg *model.Config) *cobra.Command {
 	sendCmd := &cobra.Command{
 		Use:   "send",
-		Short: "Sends currency from one address to another.",
-		Long:  "This command will send currency from the address that is specified to another.",
+		Short: "Sends currency from one address or account to another address.",
+		Long:  "This command will send currency from the address or account that is specified to another address, which can also be given by the name of a contact. The transaction can be locked until a height or time and the payment until some blocks after it confirms. It is mined right away unless it is queued in the mempool, where the fee it pays decides how long it is kept when the mempool is full. A light client always queues it in the mempool of its full node.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := send(cfg)
 			if err != nil {
@@ -24,43 +24,79 @@ func NewSendCmd(cfg *model.Config) *cobra.Command {
 		},
 	}
 
-	sendCmd.Flags().StringVarP(&from, "from", "f", "", "Address of the wallet sending the currency. (required)")
-	sendCmd.MarkFlagRequired("from")
-	sendCmd.Flags().StringVarP(&to, "to", "t", "", "Address of the wallet receiving the currency. (required)")
+	sendCmd.Flags().StringVarP(&from, "from", "f", "", "Address of the wallet sending the currency.")
+	sendCmd.Flags().StringVarP(&account, "account", "c", "", "Account whose addresses pay for the transfer.")
+	sendCmd.MarkFlagsOneRequired("from", "account")
+	sendCmd.MarkFlagsMutuallyExclusive("from", "account")
+	sendCmd.Flags().StringVarP(&to, "to", "t", "", "Address or contact name of the wallet receiving the currency. (required)")
 	sendCmd.MarkFlagRequired("to")
 	sendCmd.Flags().IntVarP(&amount, "amount", "a", 0, "The amount being transferred. (required)")
 	sendCmd.MarkFlagRequired("amount")
+	sendCmd.Flags().IntVarP(&fee, "fee", "e", 0, "Fee left to the miner on top of the amount.")
+	sendCmd.Flags().BoolVarP(&queue, "queue", "q", false, "Add the transaction to the mempool instead of mining it right away.")
+	sendCmd.Flags().Int64VarP(&lockTime, "locktime", "l", 0, "Height, or Unix timestamp from 500000000 on, before which the transaction cannot be mined.")
+	sendCmd.Flags().IntVarP(&relativeLock, "relative-lock", "r", 0, "Number of blocks after it confirms before the payment can be spent.")
 
 	return sendCmd
 }
 
 func send(cfg *model.Config) error {
-	blockchain, err := core.InitalizeBlockchain(cfg)
+	wallets, err := core.NewWallets(cfg)
 	if err != nil {
 		return utils.CatchErr(err)
 	}
-	defer blockchain.Db.Close()
 
-	utxoSet := core.NewUTXOSet(cfg, blockchain)
+	to = wallets.ResolveAddress(to)
 
-	transaction, err := core.NewUTXOTransaction(*utxoSet, from, to, amount)
-	if err != nil {
-		return utils.CatchErr(err)
+	options := core.TransactionOptions{Fee: fee, LockTime: lockTime, RelativeLock: relativeLock}
+
+	if cfg.LightClientConfig.Enabled {
+		return lightSend(cfg, wallets, options)
 	}
 
-	coinbaseTransaction, err := core.NewCoinbaseTX(cfg, from, "")
+	blockchain, err := core.InitalizeBlockchain(cfg)
 	if err != nil {
 		return utils.CatchErr(err)
 	}
+	defer blockchain.Close()
+
+	utxoSet := core.NewUTXOSet(cfg, blockchain)
 
-	transactions := []*core.Transaction{coinbaseTransaction, transaction}
+	var transaction *core.Transaction
+	miner := from
 
-	newBlock, err := blockchain.MineBlock(transactions)
-	if err != nil {
-		return utils.CatchErr(err)
+	if account != "" {
+		addresses, err := wallets.GetAccountAddresses(account)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		// The mining reward goes to the first address of the account
+		miner = addresses[0]
+
+		transaction, err = core.NewAccountTransaction(*utxoSet, wallets, account, to, amount, options)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	} else {
+		transaction, err = core.NewUTXOTransaction(*utxoSet, from, to, amount, options)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
 	}
 
-	err = utxoSet.Update(newBlock)
+	if queue {
+		_, err = core.NewMempool(cfg, blockchain).Add(transaction)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		fmt.Printf("Transaction %x is waiting in the mempool\n", transaction.ID)
+
+		return nil
+	}
+
+	_, err = mineTransaction(cfg, blockchain, transaction, miner)
 	if err != nil {
 		return utils.CatchErr(err)
 	}
@@ -69,3 +105,23 @@ func send(cfg *model.Config) error {
 
 	return nil
 }
+
+// mineTransaction mines the transaction into a new block rewarding the miner with its fee
+func mineTransaction(cfg *model.Config, blockchain *core.Blockchain, transaction *core.Transaction, miner string) (*core.Block, error) {
+	fee, err := blockchain.TransactionFee(transaction)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	coinbaseTransaction, err := core.NewCoinbaseTXWithFees(cfg, miner, "", *fee)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	newBlock, err := blockchain.MineBlock([]*core.Transaction{coinbaseTransaction, transaction})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return newBlock, nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSetAccountCmd(cfg *model.Config) *cobra.Command {
+	setAccountCmd := &cobra.Command{
+		Use:   "set-account",
+		Short: "Moves one of your addresses into an account",
+		Long:  "This command will group an address of the wallet into a named account, whose balance and sends only use the addresses of that account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := setAccount(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	setAccountCmd.Flags().StringVarP(&address, "address", "a", "", "Address of the wallet being moved. (required)")
+	setAccountCmd.MarkFlagRequired("address")
+	setAccountCmd.Flags().StringVarP(&account, "account", "c", "", "Name of the account. (required)")
+	setAccountCmd.MarkFlagRequired("account")
+
+	return setAccountCmd
+}
+
+func setAccount(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SetAccount(address, account)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SaveToFile()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Moved address '%s' into account '%s'", address, wallets.GetAccount(address))
+
+	return nil
+}
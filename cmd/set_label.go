@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSetLabelCmd(cfg *model.Config) *cobra.Command {
+	setLabelCmd := &cobra.Command{
+		Use:   "set-label",
+		Short: "Labels one of your addresses",
+		Long:  "This command will attach a label to an address of the wallet, removing it when the label is empty",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := setLabel(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	setLabelCmd.Flags().StringVarP(&address, "address", "a", "", "Address of the wallet being labeled. (required)")
+	setLabelCmd.MarkFlagRequired("address")
+	setLabelCmd.Flags().StringVarP(&label, "label", "l", "", "Label of the address.")
+
+	return setLabelCmd
+}
+
+func setLabel(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SetLabel(address, label)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = wallets.SaveToFile()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Labeled address '%s': %s", address, label)
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSignMessageCmd(cfg *model.Config) *cobra.Command {
+	signMessageCmd := &cobra.Command{
+		Use:   "sign-message",
+		Short: "Signs a message with one of your addresses",
+		Long:  "This command will sign a message with the private key of an address of the wallet, proving ownership of the address without moving funds",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := signMessage(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	signMessageCmd.Flags().StringVarP(&address, "address", "a", "", "Address signing the message. (required)")
+	signMessageCmd.MarkFlagRequired("address")
+	signMessageCmd.Flags().StringVarP(&message, "message", "m", "", "Message being signed. (required)")
+	signMessageCmd.MarkFlagRequired("message")
+
+	return signMessageCmd
+}
+
+func signMessage(cfg *model.Config) error {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	wallet, err := wallets.GetSigningWallet(address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	messageSignature, err := wallet.SignMessage(message)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("%s", base64.StdEncoding.EncodeToString(messageSignature))
+
+	return nil
+}
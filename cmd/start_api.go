@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"go-burrokuchen/api"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewStartAPICmd(cfg *model.Config) *cobra.Command {
+	startAPICmd := &cobra.Command{
+		Use:   "start-api",
+		Short: "Starts the block explorer API",
+		Long:  "This command will start a REST API for exploring blocks, transactions and addresses of the blockchain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := startAPI(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	startAPICmd.Flags().StringVarP(&listenAddress, "listen", "l", cfg.APIConfig.Address, "Address the API listens on.")
+
+	return startAPICmd
+}
+
+func startAPI(cfg *model.Config) error {
+	server := api.NewServer(cfg)
+
+	err := server.ListenAndServe(listenAddress)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+// openSwapChain opens the blockchain for the swap commands, which build and mine contract transactions themselves
+func openSwapChain(cfg *model.Config) (*core.Blockchain, error) {
+	if cfg.LightClientConfig.Enabled {
+		err := fmt.Errorf("swap commands need a full node")
+		return nil, utils.CatchErr(err)
+	}
+
+	blockchain, err := core.InitalizeBlockchain(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return blockchain, nil
+}
+
+// findContract returns the transaction holding the contract output given by the txid and output flags
+func findContract(blockchain *core.Blockchain) (*core.Transaction, *core.HTLC, error) {
+	contractID, err := hex.DecodeString(transactionID)
+	if err != nil {
+		err := fmt.Errorf("transaction ID must be hex encoded")
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	contractTX, err := blockchain.FindTransaction(contractID)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	if outputIndex < 0 || outputIndex >= len(contractTX.OutputValue) || contractTX.OutputValue[outputIndex].HTLC == nil {
+		err := fmt.Errorf("output %s:%d is not a contract", transactionID, outputIndex)
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	return contractTX, contractTX.OutputValue[outputIndex].HTLC, nil
+}
+
+// createContract locks the amount in a contract from the from flag to the to flag expiring swapTimeout blocks after it is mined
+func createContract(cfg *model.Config, secretHash []byte) (*core.Transaction, *int64, error) {
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	to = wallets.ResolveAddress(to)
+
+	isValidate, err := core.ValidateAddress(cfg, to)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	if !(*isValidate) {
+		err := fmt.Errorf("address %s is not valid", to)
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	blockchain, err := openSwapChain(cfg)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	bestHeight, err := blockchain.GetBestHeight()
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	// The contract is mined into the next block and expires swapTimeout blocks later
+	timeout := int64(*bestHeight + 1 + swapTimeout)
+
+	utxoSet := core.NewUTXOSet(cfg, blockchain)
+
+	contractTX, err := core.NewContractTransaction(*utxoSet, from, to, secretHash, timeout, amount)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	_, err = mineTransaction(cfg, blockchain, contractTX, from)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	return contractTX, &timeout, nil
+}
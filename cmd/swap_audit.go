@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSwapAuditCmd(cfg *model.Config) *cobra.Command {
+	swapAuditCmd := &cobra.Command{
+		Use:   "swap-audit",
+		Short: "Audits an atomic swap contract",
+		Long:  "This command will print the terms of a contract and whether it was redeemed, printing the revealed secret, or refunded",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := swapAudit(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	swapAuditCmd.Flags().StringVarP(&transactionID, "txid", "i", "", "ID of the contract transaction. (required)")
+	swapAuditCmd.MarkFlagRequired("txid")
+	swapAuditCmd.Flags().IntVarP(&outputIndex, "output", "o", 0, "Index of the contract output.")
+
+	return swapAuditCmd
+}
+
+func swapAudit(cfg *model.Config) error {
+	blockchain, err := openSwapChain(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	contractTX, htlc, err := findContract(blockchain)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	contractHeight, err := blockchain.GetTransactionHeight(contractTX.ID)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	bestHeight, err := blockchain.GetBestHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Amount: %d\n", contractTX.OutputValue[outputIndex].Value)
+	fmt.Printf("Recipient: %s\n", core.EncodeAddress(cfg, htlc.RecipientPubKeyHash))
+	fmt.Printf("Refund: %s\n", core.EncodeAddress(cfg, htlc.RefundPubKeyHash))
+	fmt.Printf("Secret hash: %x\n", htlc.SecretHash)
+	fmt.Printf("Confirmations: %d\n", *bestHeight-*contractHeight+1)
+	fmt.Printf("Refundable from: %d\n", htlc.Timeout)
+
+	spendingTX, err := blockchain.FindSpendingTransaction(contractTX.ID, outputIndex)
+	if errors.Is(err, core.ErrTransactionNotFound) {
+		fmt.Printf("Status: unspent")
+
+		return nil
+	}
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	revealedSecret := core.ExtractSecret(spendingTX, contractTX.ID, outputIndex)
+	if revealedSecret == nil {
+		fmt.Printf("Status: refunded in transaction %x", spendingTX.ID)
+
+		return nil
+	}
+
+	fmt.Printf("Status: redeemed in transaction %x\n", spendingTX.ID)
+	fmt.Printf("Secret: %x", revealedSecret)
+
+	return nil
+}
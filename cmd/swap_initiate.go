@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSwapInitiateCmd(cfg *model.Config) *cobra.Command {
+	swapInitiateCmd := &cobra.Command{
+		Use:   "swap-initiate",
+		Short: "Initiates an atomic swap",
+		Long:  "This command will generate a secret and lock the amount in a contract that the participant can redeem with the secret, or that is refunded after the timeout. Keep the secret until you redeem the participant's contract.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := swapInitiate(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	swapInitiateCmd.Flags().StringVarP(&from, "from", "f", "", "Address of the wallet locking the currency. (required)")
+	swapInitiateCmd.MarkFlagRequired("from")
+	swapInitiateCmd.Flags().StringVarP(&to, "to", "t", "", "Address or contact name of the participant on this chain. (required)")
+	swapInitiateCmd.MarkFlagRequired("to")
+	swapInitiateCmd.Flags().IntVarP(&amount, "amount", "a", 0, "The amount being locked. (required)")
+	swapInitiateCmd.MarkFlagRequired("amount")
+	swapInitiateCmd.Flags().IntVarP(&swapTimeout, "timeout", "b", 48, "Number of blocks before the contract can be refunded, longer than the participant's.")
+
+	return swapInitiateCmd
+}
+
+func swapInitiate(cfg *model.Config) error {
+	swapSecret, swapSecretHash, err := core.NewSwapSecret()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	contractTX, timeout, err := createContract(cfg, swapSecretHash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Secret: %x\n", swapSecret)
+	fmt.Printf("Secret hash: %x\n", swapSecretHash)
+	fmt.Printf("Contract: %x output 0\n", contractTX.ID)
+	fmt.Printf("Refundable from height %d", *timeout)
+
+	return nil
+}
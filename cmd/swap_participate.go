@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSwapParticipateCmd(cfg *model.Config) *cobra.Command {
+	swapParticipateCmd := &cobra.Command{
+		Use:   "swap-participate",
+		Short: "Participates in an atomic swap",
+		Long:  "This command will lock the amount in a contract using the secret hash of the initiator's contract, so that redeeming it reveals the secret to the initiator's contract. Audit the initiator's contract first.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := swapParticipate(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	swapParticipateCmd.Flags().StringVarP(&from, "from", "f", "", "Address of the wallet locking the currency. (required)")
+	swapParticipateCmd.MarkFlagRequired("from")
+	swapParticipateCmd.Flags().StringVarP(&to, "to", "t", "", "Address or contact name of the initiator on this chain. (required)")
+	swapParticipateCmd.MarkFlagRequired("to")
+	swapParticipateCmd.Flags().IntVarP(&amount, "amount", "a", 0, "The amount being locked. (required)")
+	swapParticipateCmd.MarkFlagRequired("amount")
+	swapParticipateCmd.Flags().StringVarP(&secretHash, "secret-hash", "s", "", "Hex encoded secret hash of the initiator's contract. (required)")
+	swapParticipateCmd.MarkFlagRequired("secret-hash")
+	swapParticipateCmd.Flags().IntVarP(&swapTimeout, "timeout", "b", 24, "Number of blocks before the contract can be refunded, shorter than the initiator's.")
+
+	return swapParticipateCmd
+}
+
+func swapParticipate(cfg *model.Config) error {
+	decodedSecretHash, err := hex.DecodeString(secretHash)
+	if err != nil {
+		err := fmt.Errorf("secret hash must be hex encoded")
+		return utils.CatchErr(err)
+	}
+
+	contractTX, timeout, err := createContract(cfg, decodedSecretHash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Contract: %x output 0\n", contractTX.ID)
+	fmt.Printf("Refundable from height %d", *timeout)
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSwapRedeemCmd(cfg *model.Config) *cobra.Command {
+	swapRedeemCmd := &cobra.Command{
+		Use:   "swap-redeem",
+		Short: "Redeems an atomic swap contract",
+		Long:  "This command will pay a contract locked to one of your addresses to that address, revealing the secret on this chain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := swapRedeem(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	swapRedeemCmd.Flags().StringVarP(&transactionID, "txid", "i", "", "ID of the contract transaction. (required)")
+	swapRedeemCmd.MarkFlagRequired("txid")
+	swapRedeemCmd.Flags().IntVarP(&outputIndex, "output", "o", 0, "Index of the contract output.")
+	swapRedeemCmd.Flags().StringVarP(&secret, "secret", "s", "", "Hex encoded secret of the contract. (required)")
+	swapRedeemCmd.MarkFlagRequired("secret")
+
+	return swapRedeemCmd
+}
+
+func swapRedeem(cfg *model.Config) error {
+	decodedSecret, err := hex.DecodeString(secret)
+	if err != nil || len(decodedSecret) == 0 {
+		err := fmt.Errorf("secret must be hex encoded")
+		return utils.CatchErr(err)
+	}
+
+	redeemTX, err := spendContract(cfg, decodedSecret)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Redeemed contract in transaction %x", redeemTX.ID)
+
+	return nil
+}
+
+// spendContract redeems the contract given by the txid and output flags with the secret, or refunds it without one,
+// paying it to the wallet address it is locked to
+func spendContract(cfg *model.Config, contractSecret []byte) (*core.Transaction, error) {
+	blockchain, err := openSwapChain(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	contractTX, htlc, err := findContract(blockchain)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	isUnspent, err := core.NewUTXOSet(cfg, blockchain).IsUnspent(contractTX.ID, outputIndex)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !*isUnspent {
+		err := fmt.Errorf("contract %x:%d is already spent", contractTX.ID, outputIndex)
+		return nil, utils.CatchErr(err)
+	}
+
+	pubKeyHash := htlc.RecipientPubKeyHash
+	if contractSecret == nil {
+		pubKeyHash = htlc.RefundPubKeyHash
+	}
+
+	spender := string(core.EncodeAddress(cfg, pubKeyHash))
+
+	wallets, err := core.NewWallets(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	wallet, err := wallets.GetSigningWallet(spender)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	spendTX, err := core.NewContractSpendTransaction(cfg, contractTX, outputIndex, wallet, contractSecret)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	_, err = mineTransaction(cfg, blockchain, spendTX, spender)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return spendTX, nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSwapRefundCmd(cfg *model.Config) *cobra.Command {
+	swapRefundCmd := &cobra.Command{
+		Use:   "swap-refund",
+		Short: "Refunds an atomic swap contract",
+		Long:  "This command will pay an expired contract back to the address of the wallet that created it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := swapRefund(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	swapRefundCmd.Flags().StringVarP(&transactionID, "txid", "i", "", "ID of the contract transaction. (required)")
+	swapRefundCmd.MarkFlagRequired("txid")
+	swapRefundCmd.Flags().IntVarP(&outputIndex, "output", "o", 0, "Index of the contract output.")
+
+	return swapRefundCmd
+}
+
+func swapRefund(cfg *model.Config) error {
+	refundTX, err := spendContract(cfg, nil)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Refunded contract in transaction %x", refundTX.ID)
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSyncHeadersCmd(cfg *model.Config) *cobra.Command {
+	syncHeadersCmd := &cobra.Command{
+		Use:   "sync-headers",
+		Short: "Syncs block headers and filters from the full node",
+		Long:  "This command will download the block headers of the full node in light client mode, checking their linkage and proof of work, along with the compact block filters used to find wallet outputs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := syncHeaders(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	return syncHeadersCmd
+}
+
+func syncHeaders(cfg *model.Config) error {
+	client, err := openLightClient(cfg)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer client.Headers.Close()
+
+	height, err := client.Headers.Height()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	filterHeight, err := client.Headers.FilterHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Synced headers up to height %d, tip %x, filters up to height %d", *height, client.Headers.Tip, *filterHeight)
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewVerifyAnchorCmd(cfg *model.Config) *cobra.Command {
+	verifyAnchorCmd := &cobra.Command{
+		Use:   "verify-anchor",
+		Short: "Verifies that the hash of a file is anchored in the blockchain",
+		Long:  "This command will find the earliest block committing the SHA-256 hash of a file and print its timestamp, which proves when the file existed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := verifyAnchor(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	verifyAnchorCmd.Flags().StringVarP(&filePath, "file", "p", "", "Path of the file being verified. (required)")
+	verifyAnchorCmd.MarkFlagRequired("file")
+
+	return verifyAnchorCmd
+}
+
+func verifyAnchor(cfg *model.Config) error {
+	fileHash, err := hashFile(filePath)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	anchor, err := findAnchor(cfg, fileHash)
+	if errors.Is(err, core.ErrTransactionNotFound) {
+		fmt.Printf("%x is not anchored!", fileHash)
+
+		return nil
+	}
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("%x existed at %s\n", fileHash, time.Unix(anchor.Timestamp, 0).Format(time.RFC3339))
+	fmt.Printf("Anchored in transaction %s, block %s at height %d with %d confirmations", anchor.TransactionID, anchor.BlockHash, anchor.Height, anchor.BestHeight-anchor.Height+1)
+
+	return nil
+}
+
+// findAnchor finds the transaction anchoring the hash with its block, the height of the block and the best height,
+// asking the running node or matching block filters locally in light client mode
+func findAnchor(cfg *model.Config, fileHash []byte) (*api.AnchorResponse, error) {
+	if cfg.LightClientConfig.Enabled {
+		if !cfg.LightClientConfig.UseFilters {
+			err := fmt.Errorf("verify-anchor needs light_client.use_filters in light client mode")
+			return nil, utils.CatchErr(err)
+		}
+
+		client, err := openLightClient(cfg)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+		defer client.Headers.Close()
+
+		transaction, block, height, err := client.FindData(fileHash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		bestHeight, err := client.Headers.Height()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return newAnchor(transaction, block, *height, *bestHeight), nil
+	}
+
+	if node := api.DialNode(cfg); node != nil {
+		anchor, err := node.FindAnchor(fileHash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return anchor, nil
+	}
+
+	blockchain, err := core.OpenReadOnlyBlockchain(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer blockchain.Close()
+
+	transaction, block, err := blockchain.FindData(fileHash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	height, err := blockchain.GetBlockHeight(block.Hash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	bestHeight, err := blockchain.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return newAnchor(transaction, block, *height, *bestHeight), nil
+}
+
+// newAnchor describes the transaction anchoring a hash in the same way the node does
+func newAnchor(transaction *core.Transaction, block *core.Block, height int, bestHeight int) *api.AnchorResponse {
+	return &api.AnchorResponse{
+		TransactionID: hex.EncodeToString(transaction.ID),
+		BlockHash:     hex.EncodeToString(block.Hash),
+		Height:        height,
+		BestHeight:    bestHeight,
+		Timestamp:     block.Timestamp,
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func NewVerifyMessageCmd(cfg *model.Config) *cobra.Command {
+	verifyMessageCmd := &cobra.Command{
+		Use:   "verify-message",
+		Short: "Verifies a signed message",
+		Long:  "This command will check that a message was signed with the private key of the address that is specified",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := verifyMessage(cfg)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return nil
+		},
+	}
+
+	verifyMessageCmd.Flags().StringVarP(&address, "address", "a", "", "Address that signed the message. (required)")
+	verifyMessageCmd.MarkFlagRequired("address")
+	verifyMessageCmd.Flags().StringVarP(&signature, "signature", "s", "", "Base64 encoded signature. (required)")
+	verifyMessageCmd.MarkFlagRequired("signature")
+	verifyMessageCmd.Flags().StringVarP(&message, "message", "m", "", "Message that was signed. (required)")
+	verifyMessageCmd.MarkFlagRequired("message")
+
+	return verifyMessageCmd
+}
+
+func verifyMessage(cfg *model.Config) error {
+	decodedSignature, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		err := fmt.Errorf("signature must be base64 encoded")
+		return utils.CatchErr(err)
+	}
+
+	isVerified, err := core.VerifyMessage(cfg, address, decodedSignature, message)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !(*isVerified) {
+		err := fmt.Errorf("signature is not valid for address %s", address)
+		return utils.CatchErr(err)
+	}
+
+	fmt.Printf("Signature is valid, the message was signed by %s\n", address)
+
+	return nil
+}
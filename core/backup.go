@@ -0,0 +1,434 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrBackupCorrupted = errors.New("backup is corrupted")
+	ErrDatabaseInUse   = errors.New("database is in use, stop the node first")
+)
+
+const (
+	// backupManifestName is the name of the manifest in a backup directory, which is written last so that only
+	// complete backups have one
+	backupManifestName = "manifest.json"
+	// backupTimeLayout names the backup directories after the time they were taken, so that they sort by age
+	backupTimeLayout = "20060102T150405Z"
+	// backupOpenTimeout is how long opening a database file waits for the lock held by a running node
+	backupOpenTimeout = time.Second
+	// preRestoreSuffix is appended to the files a restore replaces, which are kept until they are removed by hand
+	preRestoreSuffix = ".pre-restore"
+)
+
+// BackupFile is a file of a backup and the SHA-256 of its content
+type BackupFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest describes a backup directory, the chain its database holds and the files it contains
+type BackupManifest struct {
+	Dir           string      `json:"-"`
+	CreatedAt     int64       `json:"created_at"`
+	TipHash       string      `json:"tip_hash"`
+	Height        int         `json:"height"`
+	SchemaVersion int         `json:"schema_version"`
+	Database      BackupFile  `json:"database"`
+	Wallet        *BackupFile `json:"wallet,omitempty"`
+}
+
+// Backup takes a consistent copy of the database and of the wallet file into a new directory of dest while the
+// chain keeps being used, and writes the manifest describing them
+func (bc *Blockchain) Backup(dest string) (*BackupManifest, error) {
+	createdAt := time.Now().UTC()
+	dir := filepath.Join(dest, createdAt.Format(backupTimeLayout))
+
+	err := os.MkdirAll(dest, 0700)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = os.Mkdir(dir, 0700)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	manifest, err := bc.writeBackup(dir, createdAt)
+	if err != nil {
+		os.RemoveAll(dir)
+
+		return nil, utils.CatchErr(err)
+	}
+
+	return manifest, nil
+}
+
+// writeBackup copies the database and the wallet file into dir and writes their manifest
+func (bc *Blockchain) writeBackup(dir string, createdAt time.Time) (*BackupManifest, error) {
+	manifest := BackupManifest{
+		Dir:       dir,
+		CreatedAt: createdAt.Unix(),
+		Database:  BackupFile{Name: filepath.Base(bc.cfg.DatabaseConfig.DbName)},
+	}
+
+	databasePath := filepath.Join(dir, manifest.Database.Name)
+
+	err := bc.Store.Backup(databasePath)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// The chain is read from the copy, as blocks may have been mined since it was taken
+	err = readBackupChain(bc.cfg, databasePath, &manifest)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	manifest.Database.SHA256, err = fileSHA256(databasePath)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	walletContent, err := os.ReadFile(bc.cfg.WalletConfig.WalletFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, utils.CatchErr(err)
+	}
+
+	if err == nil {
+		walletSum := sha256.Sum256(walletContent)
+		manifest.Wallet = &BackupFile{Name: filepath.Base(bc.cfg.WalletConfig.WalletFile), SHA256: hex.EncodeToString(walletSum[:])}
+
+		err = os.WriteFile(filepath.Join(dir, manifest.Wallet.Name), walletContent, 0600)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, backupManifestName), content, 0600)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &manifest, nil
+}
+
+// readBackupChain reads the tip, its height and the schema version from a copy of the database into the manifest
+func readBackupChain(cfg *model.Config, path string, manifest *BackupManifest) error {
+	store, err := openBoltChainStore(cfg, path, &bolt.Options{ReadOnly: true, Timeout: backupOpenTimeout})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer store.Close()
+
+	return store.View(func(tx ChainTx) error {
+		tip := tx.Tip()
+		if tip == nil {
+			return fmt.Errorf("the database holds no block")
+		}
+
+		height, err := tx.BlockHeight(tip)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		manifest.TipHash = hex.EncodeToString(tip)
+		manifest.Height = *height
+		manifest.SchemaVersion = readSchemaVersion(cfg, tx)
+
+		return nil
+	})
+}
+
+// ListBackups returns the manifests of the complete backups in dest, oldest first
+func ListBackups(dest string) ([]BackupManifest, error) {
+	entries, err := os.ReadDir(dest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var manifests []BackupManifest
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		// Directories without a readable manifest are not complete backups
+		manifest, err := ReadBackupManifest(filepath.Join(dest, entry.Name()))
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrBackupCorrupted) {
+			continue
+		}
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		manifests = append(manifests, *manifest)
+	}
+
+	slices.SortFunc(manifests, func(a, b BackupManifest) int {
+		return cmp.Compare(a.CreatedAt, b.CreatedAt)
+	})
+
+	return manifests, nil
+}
+
+// ReadBackupManifest reads the manifest of the backup in dir
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var manifest BackupManifest
+
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading its manifest: %v", ErrBackupCorrupted, err)
+	}
+
+	manifest.Dir = dir
+
+	return &manifest, nil
+}
+
+// PruneBackups removes the oldest complete backups in dest until keep are left and returns the directories removed.
+// Directories without a manifest are left alone, they may be a backup still being written
+func PruneBackups(dest string, keep int) ([]string, error) {
+	manifests, err := ListBackups(dest)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var removed []string
+
+	for len(manifests) > keep {
+		err = os.RemoveAll(manifests[0].Dir)
+		if err != nil {
+			return removed, utils.CatchErr(err)
+		}
+
+		removed = append(removed, manifests[0].Dir)
+		manifests = manifests[1:]
+	}
+
+	return removed, nil
+}
+
+// VerifyBackup checks that the files of the backup in dir match their checksums, that the database file is consistent
+// and holds the chain described by the manifest, and that the wallet file can be read
+func VerifyBackup(cfg *model.Config, dir string) (*BackupManifest, error) {
+	manifest, err := ReadBackupManifest(dir)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	files := []BackupFile{manifest.Database}
+	if manifest.Wallet != nil {
+		files = append(files, *manifest.Wallet)
+	}
+
+	for _, file := range files {
+		// The names come from the manifest, which must not point outside of the backup
+		if file.Name != filepath.Base(file.Name) {
+			return nil, fmt.Errorf("%w: invalid file name %q", ErrBackupCorrupted, file.Name)
+		}
+
+		sum, err := fileSHA256(filepath.Join(dir, file.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupCorrupted, err)
+		}
+
+		if sum != file.SHA256 {
+			return nil, fmt.Errorf("%w: checksum of %s does not match the manifest", ErrBackupCorrupted, file.Name)
+		}
+	}
+
+	err = verifyBackupDatabase(cfg, filepath.Join(dir, manifest.Database.Name), manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupCorrupted, err)
+	}
+
+	if manifest.Wallet != nil {
+		walletCfg := *cfg
+		walletCfg.WalletConfig.WalletFile = filepath.Join(dir, manifest.Wallet.Name)
+
+		_, err = NewWallets(&walletCfg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading the wallet file: %v", ErrBackupCorrupted, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// verifyBackupDatabase checks the pages of a database copy and that it holds the chain described by the manifest
+func verifyBackupDatabase(cfg *model.Config, path string, manifest *BackupManifest) error {
+	store, err := openBoltChainStore(cfg, path, &bolt.Options{ReadOnly: true, Timeout: backupOpenTimeout})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer store.Close()
+
+	err = store.check()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if manifest.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("%w: version %d, up to %d is supported", ErrSchemaTooNew, manifest.SchemaVersion, SchemaVersion)
+	}
+
+	return store.View(func(tx ChainTx) error {
+		tip := tx.Tip()
+		if hex.EncodeToString(tip) != manifest.TipHash {
+			return fmt.Errorf("tip is %x instead of %s", tip, manifest.TipHash)
+		}
+
+		if readSchemaVersion(cfg, tx) != manifest.SchemaVersion {
+			return fmt.Errorf("schema version is %d instead of %d", readSchemaVersion(cfg, tx), manifest.SchemaVersion)
+		}
+
+		_, err := tx.Block(tip)
+		if err != nil {
+			return fmt.Errorf("reading the tip: %w", err)
+		}
+
+		hash, err := tx.BlockHash(manifest.Height)
+		if err != nil || !bytes.Equal(hash, tip) {
+			return fmt.Errorf("the tip is not indexed at height %d", manifest.Height)
+		}
+
+		// The UTXO set is recorded to be at the tip from the schema version that records it
+		if manifest.SchemaVersion >= 3 && !bytes.Equal(utxoTip(cfg, tx), tip) {
+			return fmt.Errorf("the UTXO set is not at the tip")
+		}
+
+		return nil
+	})
+}
+
+// RestoreBackup verifies the backup in dir and replaces the database and the wallet file with its copies. The node
+// must be stopped, the files replaced are kept next to them with the .pre-restore suffix
+func RestoreBackup(cfg *model.Config, dir string) (*BackupManifest, error) {
+	manifest, err := VerifyBackup(cfg, dir)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = checkDatabaseUnused(cfg.DatabaseConfig.DbName)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = restoreFile(filepath.Join(dir, manifest.Database.Name), cfg.DatabaseConfig.DbName)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if manifest.Wallet != nil {
+		err = restoreFile(filepath.Join(dir, manifest.Wallet.Name), cfg.WalletConfig.WalletFile)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// checkDatabaseUnused returns ErrDatabaseInUse when a running node holds the lock of the database file
+func checkDatabaseUnused(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: backupOpenTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return fmt.Errorf("%w: %s", ErrDatabaseInUse, describeLockHolder(path))
+	}
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return db.Close()
+}
+
+// restoreFile copies src next to dst and swaps it in once it is synced, moving the file it replaces aside
+func restoreFile(src string, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer source.Close()
+
+	file, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp*")
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	_, err = io.Copy(file, source)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = file.Sync()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = os.Rename(dst, dst+preRestoreSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return utils.CatchErr(err)
+	}
+
+	return os.Rename(file.Name(), dst)
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of the content of a file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", utils.CatchErr(err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", utils.CatchErr(err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"go-burrokuchen/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestBoltChain creates a database file and a wallet file in a temporary directory and returns the open chain
+func newTestBoltChain(t *testing.T) (*model.Config, *Blockchain, string) {
+	t.Helper()
+
+	cfg := testConfig()
+	dir := t.TempDir()
+	cfg.DatabaseConfig.DbName = filepath.Join(dir, "blockchain.db")
+	cfg.WalletConfig.WalletFile = filepath.Join(dir, "wallet.dat")
+
+	wallets, err := NewWallets(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := wallets.CreateWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wallets.SaveToFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc, err := NewBlockchain(cfg, *address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg, bc, *address
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	cfg, bc, address := newTestBoltChain(t)
+
+	manifest, err := bc.Backup(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The chain moves on after the backup, the restore brings it back to the backed up tip
+	_, err = bc.MineBlock([]*Transaction{testCoinbase(t, cfg, address)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := VerifyBackup(cfg, manifest.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if verified.TipHash != manifest.TipHash || verified.Height != 0 {
+		t.Errorf("verified tip %s at height %d, expected %s at height 0", verified.TipHash, verified.Height, manifest.TipHash)
+	}
+
+	_, err = RestoreBackup(cfg, manifest.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{cfg.DatabaseConfig.DbName, cfg.WalletConfig.WalletFile} {
+		_, err = os.Stat(path + preRestoreSuffix)
+		if err != nil {
+			t.Errorf("replaced file was not kept: %v", err)
+		}
+	}
+
+	restored, err := InitalizeBlockchain(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	height, err := restored.GetBestHeight()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *height != 0 {
+		t.Errorf("restored chain is at height %d, expected 0", *height)
+	}
+
+	wallets, err := NewWallets(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !wallets.HasAddress(address) {
+		t.Errorf("restored wallet file does not hold %s", address)
+	}
+}
+
+func TestVerifyBackupRefusesCorruptedChecksum(t *testing.T) {
+	cfg, bc, _ := newTestBoltChain(t)
+
+	manifest, err := bc.Backup(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest.Database.SHA256 = manifest.Wallet.SHA256
+
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(manifest.Dir, backupManifestName), content, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = VerifyBackup(cfg, manifest.Dir)
+	if !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("verified with %v, expected %v", err, ErrBackupCorrupted)
+	}
+
+	_, err = RestoreBackup(cfg, manifest.Dir)
+	if !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("restored with %v, expected %v", err, ErrBackupCorrupted)
+	}
+
+	_, err = os.Stat(cfg.DatabaseConfig.DbName + preRestoreSuffix)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("the database was replaced by a corrupted backup")
+	}
+}
+
+func TestRestoreBackupRefusesLockedDatabase(t *testing.T) {
+	cfg, bc, _ := newTestBoltChain(t)
+	defer bc.Close()
+
+	manifest, err := bc.Backup(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = RestoreBackup(cfg, manifest.Dir)
+	if !errors.Is(err, ErrDatabaseInUse) {
+		t.Errorf("restored with %v, expected %v", err, ErrDatabaseInUse)
+	}
+
+	_, err = os.Stat(cfg.DatabaseConfig.DbName + preRestoreSuffix)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("the database of the running node was replaced")
+	}
+}
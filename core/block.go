@@ -3,23 +3,81 @@ package core
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
 	"time"
 )
 
+// BlockVersion is the version of the blocks mined by this node. Version 0 blocks were mined before versions were
+// recorded, their Merkle root only covers the first transaction and their proof of work does not cover the version.
+// Version 1 blocks have a Merkle root covering all of their transactions, with tagged leaf and inner node hashes
+const BlockVersion = 1
+
+var ErrInvalidBlockVersion = errors.New("invalid block version")
+
 // Block represents a block in the blockchain
 type Block struct {
+	Version       int
 	Timestamp     int64
 	Transactions  []*Transaction
 	PrevBlockHash []byte
 	Hash          []byte
 	Nonce         int
+	// MerkleRoot is kept in place of the transactions once the block is pruned
+	MerkleRoot []byte
+}
+
+// BlockHeader represents the fields of a block that are covered by its proof of work
+type BlockHeader struct {
+	Version       int
+	Timestamp     int64
+	PrevBlockHash []byte
+	MerkleRoot    []byte
+	Hash          []byte
+	Nonce         int
+}
+
+// TransactionProof proves that a transaction is included in the block of the header
+type TransactionProof struct {
+	Header         BlockHeader
+	TransactionID  []byte
+	RawTransaction []byte
+	LeafHash       []byte
+	Index          int
+	Proof          []MerkleProofStep
+}
+
+// Verify checks that the raw transaction has the ID of the proof and hashes to its leaf, and the proof against the
+// Merkle root of the header, so that a valid proof cannot be passed off as the proof of another transaction
+func (p *TransactionProof) Verify() bool {
+	transaction, err := DeserializeTransaction(p.RawTransaction)
+	if err != nil || !bytes.Equal(transaction.ID, p.TransactionID) || transaction.CheckID() != nil {
+		return false
+	}
+
+	if !bytes.Equal(p.LeafHash, p.Header.LeafHash(p.RawTransaction)) {
+		return false
+	}
+
+	return VerifyMerkleProof(p.Header.MerkleRoot, p.LeafHash, p.Proof)
+}
+
+// LeafHash returns the hash of a serialized transaction as a leaf of the Merkle tree of the block, which is not
+// tagged in version 0 blocks
+func (h *BlockHeader) LeafHash(rawTransaction []byte) []byte {
+	if h.Version < 1 {
+		return legacyMerkleLeafHash(rawTransaction)
+	}
+
+	return MerkleLeafHash(rawTransaction)
 }
 
-// NewBlock generates and returns a new block
-func NewBlock(cfg *model.Config, transactions []*Transaction, prevBlockHash []byte) (*Block, error) {
+// NewBlock generates and returns a new block mined with the proof of work algorithm of the blockchain
+func NewBlock(cfg *model.Config, algorithm string, transactions []*Transaction, prevBlockHash []byte) (*Block, error) {
 	block := &Block{
+		Version:       BlockVersion,
 		Timestamp:     time.Now().Unix(),
 		Transactions:  transactions,
 		PrevBlockHash: prevBlockHash,
@@ -27,7 +85,7 @@ func NewBlock(cfg *model.Config, transactions []*Transaction, prevBlockHash []by
 		Nonce:         0,
 	}
 
-	pow, err := NewProofOfWork(cfg, block)
+	pow, err := NewProofOfWork(cfg, algorithm, block)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
@@ -44,10 +102,10 @@ func NewBlock(cfg *model.Config, transactions []*Transaction, prevBlockHash []by
 }
 
 // NewGenesisBlock generates and returns a genesis block
-func NewGenesisBlock(cfg *model.Config, coinbase *Transaction) (*Block, error) {
+func NewGenesisBlock(cfg *model.Config, algorithm string, coinbase *Transaction) (*Block, error) {
 	transactions := []*Transaction{coinbase}
 
-	block, err := NewBlock(cfg, transactions, []byte{})
+	block, err := NewBlock(cfg, algorithm, transactions, []byte{})
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
@@ -69,6 +127,34 @@ func (b *Block) SerializeBlock() ([]byte, error) {
 
 // HashTransactions returns a hash of the transactions in the block
 func (b *Block) HashTransactions() ([]byte, error) {
+	transactions, err := b.serializeTransactions()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if b.Version < 1 {
+		return LegacyMerkleRoot(transactions), nil
+	}
+
+	return NewMerkleTree(transactions).RootNode.Data, nil
+}
+
+// MerkleTree builds the Merkle tree of the transactions in the block
+func (b *Block) MerkleTree() (*MerkleTree, error) {
+	if b.Version < 1 {
+		return nil, fmt.Errorf("version %d block %x has no Merkle tree over its transactions", b.Version, b.Hash)
+	}
+
+	transactions, err := b.serializeTransactions()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return NewMerkleTree(transactions), nil
+}
+
+// serializeTransactions returns the serialized transactions of the block, the leaves of its Merkle tree
+func (b *Block) serializeTransactions() ([][]byte, error) {
 	var transactions [][]byte
 
 	for _, tx := range b.Transactions {
@@ -79,9 +165,92 @@ func (b *Block) HashTransactions() ([]byte, error) {
 		transactions = append(transactions, serializedTransaction)
 	}
 
-	mTree := NewMerkleTree(transactions)
+	return transactions, nil
+}
+
+// checkBlockVersion checks that a block does not have a version this node does not know, nor one lower than its
+// parent, so that blocks whose Merkle root only covers their first transaction cannot follow newer ones
+func checkBlockVersion(version int, parentVersion int) error {
+	if version > BlockVersion {
+		return fmt.Errorf("%w: version %d is newer than %d", ErrInvalidBlockVersion, version, BlockVersion)
+	}
+
+	if version < parentVersion {
+		return fmt.Errorf("%w: version %d is lower than the version %d of its parent", ErrInvalidBlockVersion, version, parentVersion)
+	}
+
+	return nil
+}
+
+// Header returns the header of the block
+func (b *Block) Header() (*BlockHeader, error) {
+	merkleRoot := b.MerkleRoot
+
+	if !b.IsPruned() {
+		var err error
+		merkleRoot, err = b.HashTransactions()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	header := BlockHeader{
+		Version:       b.Version,
+		Timestamp:     b.Timestamp,
+		PrevBlockHash: b.PrevBlockHash,
+		MerkleRoot:    merkleRoot,
+		Hash:          b.Hash,
+		Nonce:         b.Nonce,
+	}
+
+	return &header, nil
+}
+
+// ProveTransaction returns a Merkle proof that the transaction with the given ID is included in the block
+func (b *Block) ProveTransaction(ID []byte) (*TransactionProof, error) {
+	for index, tx := range b.Transactions {
+		if !bytes.Equal(tx.ID, ID) {
+			continue
+		}
+
+		var proof []MerkleProofStep
+
+		// The Merkle root of a version 0 block is the hash of its first transaction, which needs no sibling hashes
+		if b.Version >= 1 || index != 0 {
+			mTree, err := b.MerkleTree()
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			proof, err = mTree.Proof(index)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+		}
+
+		header, err := b.Header()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		serializedTransaction, err := tx.Serialize()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		transactionProof := TransactionProof{
+			Header:         *header,
+			TransactionID:  tx.ID,
+			RawTransaction: serializedTransaction,
+			LeafHash:       header.LeafHash(serializedTransaction),
+			Index:          index,
+			Proof:          proof,
+		}
+
+		return &transactionProof, nil
+	}
 
-	return mTree.RootNode.Data, nil
+	return nil, fmt.Errorf("transaction %x is not in block %x", ID, b.Hash)
 }
 
 // DeserializeBlock deserializes a block
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"go-burrokuchen/utils"
+)
+
+// BlockFilter is the compact filter of a block and its position in the filter header chain
+type BlockFilter struct {
+	Filter []byte
+	Header []byte
+}
+
+// NewBlockFilter builds the filter of a block over the public key hashes or data of its outputs and the outpoints it spends
+func NewBlockFilter(block *Block) *GCSFilter {
+	var items [][]byte
+
+	for _, tx := range block.Transactions {
+		for _, out := range tx.OutputValue {
+			if out.IsData() {
+				items = append(items, out.Data)
+				continue
+			}
+
+			items = append(items, out.PubKeyHash)
+		}
+
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		for _, in := range tx.InputValue {
+			items = append(items, FilterOutpoint(in.TransactionID, in.OutputIndex))
+		}
+	}
+
+	return NewGCSFilter(block.Hash, items)
+}
+
+// FilterOutpoint returns the filter item of a spent output
+func FilterOutpoint(transactionID []byte, outputIndex int) []byte {
+	outpoint := make([]byte, len(transactionID)+4)
+	copy(outpoint, transactionID)
+	binary.BigEndian.PutUint32(outpoint[len(transactionID):], uint32(outputIndex))
+
+	return outpoint
+}
+
+// FilterHeader chains the hash of a filter to the filter header of the previous block
+func FilterHeader(filter []byte, prevHeader []byte) []byte {
+	if prevHeader == nil {
+		prevHeader = make([]byte, sha256.Size)
+	}
+
+	filterHash := sha256.Sum256(filter)
+	header := sha256.Sum256(append(filterHash[:], prevHeader...))
+
+	return header[:]
+}
+
+// GetBlockFilter returns the filter of the block with the given hash
+func (bc *Blockchain) GetBlockFilter(hash []byte) (*BlockFilter, error) {
+	var blockFilter *BlockFilter
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		decodedFilter, err := tx.Filter(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		blockFilter = decodedFilter
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return blockFilter, nil
+}
+
+// putBlockFilter stores the filter of a block, chaining it to the filter header of its parent
+func putBlockFilter(tx ChainTx, block *Block) error {
+	var prevHeader []byte
+
+	if len(block.PrevBlockHash) != 0 {
+		prevFilter, err := tx.Filter(block.PrevBlockHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		prevHeader = prevFilter.Header
+	}
+
+	filter := NewBlockFilter(block).Serialize()
+
+	return tx.PutFilter(block.Hash, &BlockFilter{Filter: filter, Header: FilterHeader(filter, prevHeader)})
+}
+
+// ensureFilters builds the filters of every block when the filter bucket is missing or does not contain the tip
+func (bc *Blockchain) ensureFilters() error {
+	var filtered bool
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		_, err := tx.Filter(bc.Tip)
+		filtered = err == nil
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if filtered {
+		return nil
+	}
+
+	var blocks []*Block
+
+	bci := bc.InitializeIterator()
+
+	for {
+		block, err := bci.Prev()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		blocks = append(blocks, block)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	err = bc.Store.Update(func(tx ChainTx) error {
+		for i := len(blocks) - 1; i >= 0; i-- {
+			// Pruned blocks were filtered before their transactions were deleted
+			if blocks[i].IsPruned() {
+				continue
+			}
+
+			_, err := tx.Filter(blocks[i].Hash)
+			if err == nil {
+				continue
+			}
+
+			err = putBlockFilter(tx, blocks[i])
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// VerifyBlockFilter checks that a filter was built from the block
+func VerifyBlockFilter(block *Block, filter []byte) bool {
+	return bytes.Equal(NewBlockFilter(block).Serialize(), filter)
+}
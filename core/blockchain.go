@@ -3,68 +3,116 @@ package core
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
 
-	"slices"
+	"time"
+)
 
-	bolt "go.etcd.io/bbolt"
+var (
+	ErrBlockNotFound       = errors.New("block not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidTransaction  = errors.New("invalid transaction")
+	ErrTransactionLocked   = errors.New("transaction is time-locked")
+	ErrBlockPruned         = errors.New("block was pruned")
 )
 
 // Blockchain represents a blockchain
 type Blockchain struct {
-	cfg *model.Config
-	Tip []byte
-	Db  *bolt.DB
+	cfg   *model.Config
+	Tip   []byte
+	Store ChainStore
+	// PowAlgorithm is the proof of work algorithm recorded when the blockchain was created
+	PowAlgorithm string
 }
 
 // NewBlockchain genearates and returns a new blockchain
 func NewBlockchain(cfg *model.Config, address string) (*Blockchain, error) {
-	databaseName := cfg.DatabaseConfig.DbName
-	blocksBucket := []byte(cfg.DatabaseConfig.BlocksBucket)
-	genesisData := cfg.TransactionConfig.GenesisCoinbaseData
-
-	if utils.DbExists(databaseName) {
+	if utils.DbExists(cfg.DatabaseConfig.DbName) {
 		return nil, fmt.Errorf("blockchain already exists")
 	}
 
-	var tip []byte
+	store, err := OpenBoltChainStore(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	blockchain, err := NewBlockchainWithStore(cfg, store, address)
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	return blockchain, nil
+}
+
+// NewBlockchainWithStore generates and returns a new blockchain kept in the given empty store
+func NewBlockchainWithStore(cfg *model.Config, store ChainStore, address string) (*Blockchain, error) {
+	genesisData := cfg.TransactionConfig.GenesisCoinbaseData
 
-	db, err := bolt.Open(databaseName, 0600, nil)
+	algorithm, err := NewChainPowAlgorithm(cfg)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
+	var tip []byte
+
+	err = store.Update(func(tx ChainTx) error {
+		if tx.Tip() != nil {
+			return fmt.Errorf("blockchain already exists")
+		}
+
 		fmt.Println("No existing blockchain found. Generating a new one...")
 		coinbaseTX, err := NewCoinbaseTX(cfg, address, genesisData)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		genesis, err := NewGenesisBlock(cfg, coinbaseTX)
+		genesis, err := NewGenesisBlock(cfg, algorithm, coinbaseTX)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.PutBlock(genesis)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.SetTip(genesis.Hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.PutBlockIndex(genesis.Hash, 0)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = putBlockFilter(tx, genesis)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		bucket, err := tx.CreateBucketIfNotExists(blocksBucket)
+		err = applyBlockUTXOs(tx, genesis)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		serializedBlock, err := genesis.SerializeBlock()
+		err = putUTXOTip(cfg, tx, genesis.Hash)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		err = bucket.Put(genesis.Hash, serializedBlock)
+		err = putSchemaVersion(cfg, tx, SchemaVersion)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		err = bucket.Put([]byte("l"), genesis.Hash)
+		err = putPowAlgorithm(cfg, tx, algorithm)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
@@ -78,63 +126,160 @@ func NewBlockchain(cfg *model.Config, address string) (*Blockchain, error) {
 		return nil, utils.CatchErr(err)
 	}
 
-	blockChain := Blockchain{cfg: cfg, Tip: tip, Db: db}
+	blockChain := Blockchain{cfg: cfg, Tip: tip, Store: store, PowAlgorithm: algorithm}
 
 	return &blockChain, nil
 }
 
 // InitalizeBlockchain initializes and returns a blockchain object
 func InitalizeBlockchain(cfg *model.Config) (*Blockchain, error) {
-	databaseName := cfg.DatabaseConfig.DbName
-	blocksBucket := []byte(cfg.DatabaseConfig.BlocksBucket)
+	if !utils.DbExists(cfg.DatabaseConfig.DbName) {
+		return nil, fmt.Errorf("no existing blockchain found, generate one first")
+	}
+
+	store, err := OpenBoltChainStore(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	blockchain, err := InitializeBlockchainWithStore(cfg, store)
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
 
-	if !utils.DbExists(databaseName) {
+	return blockchain, nil
+}
+
+// OpenReadOnlyBlockchain opens the existing blockchain without write access, so that it can be read while other
+// processes read it too. As it cannot be migrated or repaired, a database that would need it is refused
+func OpenReadOnlyBlockchain(cfg *model.Config) (*Blockchain, error) {
+	if !utils.DbExists(cfg.DatabaseConfig.DbName) {
 		return nil, fmt.Errorf("no existing blockchain found, generate one first")
 	}
 
+	store, err := OpenReadOnlyBoltChainStore(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
 	var tip []byte
-	db, err := bolt.Open(databaseName, 0600, nil)
+	var version int
+	var upToDate bool
+	var algorithm string
+
+	err = store.View(func(tx ChainTx) error {
+		tip = tx.Tip()
+		version = readSchemaVersion(cfg, tx)
+		upToDate = bytes.Equal(utxoTip(cfg, tx), tip)
+		algorithm = readPowAlgorithm(cfg, tx)
+
+		return nil
+	})
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	switch {
+	case tip == nil:
+		err = fmt.Errorf("no existing blockchain found, generate one first")
+	case version > SchemaVersion:
+		err = fmt.Errorf("%w: version %d, up to %d is supported", ErrSchemaTooNew, version, SchemaVersion)
+	case version < SchemaVersion:
+		err = fmt.Errorf("%w: version %d is older than %d, run migrate first", ErrSchemaOutdated, version, SchemaVersion)
+	case !upToDate:
+		err = fmt.Errorf("the UTXO set is behind the chain, run a command that writes to the database to repair it")
+	}
 	if err != nil {
+		store.Close()
 		return nil, utils.CatchErr(err)
 	}
 
-	err = db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(blocksBucket)
+	return &Blockchain{cfg: cfg, Tip: tip, Store: store, PowAlgorithm: algorithm}, nil
+}
+
+// InitializeBlockchainWithStore initializes and returns the blockchain kept in the given store
+func InitializeBlockchainWithStore(cfg *model.Config, store ChainStore) (*Blockchain, error) {
+	var tip []byte
+	var algorithm string
 
-		tip = bucket.Get([]byte("l"))
+	err := store.View(func(tx ChainTx) error {
+		tip = tx.Tip()
+		algorithm = readPowAlgorithm(cfg, tx)
 
 		return nil
 	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if tip == nil {
+		return nil, fmt.Errorf("no existing blockchain found, generate one first")
+	}
+
+	blockchain := Blockchain{cfg: cfg, Tip: tip, Store: store, PowAlgorithm: algorithm}
 
+	err = blockchain.ensureSchema()
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	blockchain := Blockchain{cfg: cfg, Tip: tip, Db: db}
+	err = blockchain.ensureUTXOSet()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
 
 	return &blockchain, nil
 }
 
-// MineBlock mines a new block with the provided transactions
-func (bc *Blockchain) MineBlock(transactions []*Transaction) (*Block, error) {
-	blocksBucket := []byte(bc.cfg.DatabaseConfig.BlocksBucket)
+// Close closes the store of the blockchain
+func (bc *Blockchain) Close() error {
+	return bc.Store.Close()
+}
 
+// MineBlock mines a new block with the provided transactions and connects it on top of the tip
+func (bc *Blockchain) MineBlock(transactions []*Transaction) (*Block, error) {
 	var lastHash []byte
+	var lastHeight int
 
-	for _, tx := range transactions {
-		verified, err := bc.VerifyTransaction(tx)
+	err := bc.Store.View(func(tx ChainTx) error {
+		lastHash = tx.Tip()
+
+		height, err := tx.BlockHeight(lastHash)
 		if err != nil {
-			return nil, utils.CatchErr(err)
+			return utils.CatchErr(err)
 		}
+		lastHeight = *height
 
-		if !*verified {
-			return nil, fmt.Errorf("invalid transaction")
-		}
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// The block is stamped after these checks, so its timestamp is at least now
+	err = bc.checkBlockTransactions(transactions, lastHeight+1, time.Now().Unix())
+	if err != nil {
+		return nil, utils.CatchErr(err)
 	}
 
-	err := bc.Db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(blocksBucket)
-		lastHash = bucket.Get([]byte("l"))
+	newBlock, err := NewBlock(bc.cfg, bc.PowAlgorithm, transactions, lastHash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = bc.Store.Update(func(tx ChainTx) error {
+		err := bc.connectBlock(tx, newBlock, lastHeight+1)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		// The mempool is cleared of the mined transactions in the same transaction as the block is stored
+		err = removeMinedTransactions(tx, bc.cfg.DatabaseConfig.MempoolBucket, newBlock)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
 
 		return nil
 	})
@@ -142,51 +287,178 @@ func (bc *Blockchain) MineBlock(transactions []*Transaction) (*Block, error) {
 		return nil, utils.CatchErr(err)
 	}
 
-	newBlock, err := NewBlock(bc.cfg, transactions, lastHash)
+	bc.Tip = newBlock.Hash
+
+	_, err = bc.Prune()
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	err = bc.Db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(blocksBucket)
-		serializedBlock, err := newBlock.SerializeBlock()
+	return newBlock, nil
+}
+
+// checkBlockTransactions checks the IDs, signatures, outputs, locks and fees of the transactions of a block with the given
+// height and timestamp, and that its coinbase pays no more than the subsidy and fees
+func (bc *Blockchain) checkBlockTransactions(transactions []*Transaction, height int, timestamp int64) error {
+	// Transactions may spend outputs of the transactions before them in the block
+	pending := make(map[string]Transaction)
+	fees := 0
+	coinbaseValue := 0
+
+	for _, tx := range transactions {
+		err := tx.CheckID()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		verified, err := bc.verifyTransaction(tx, pending)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		err = bucket.Put(newBlock.Hash, serializedBlock)
+		if !*verified {
+			return ErrInvalidTransaction
+		}
+
+		err = tx.CheckDataOutputs(bc.cfg.TransactionConfig.MaxDataSize)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		err = bucket.Put([]byte("l"), newBlock.Hash)
+		err = tx.CheckOutputValues()
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		bc.Tip = newBlock.Hash
+		err = bc.checkLocks(tx, height, timestamp, pending)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
 
-		return nil
+		if tx.IsCoinbase() {
+			for _, out := range tx.OutputValue {
+				coinbaseValue += out.Value
+			}
+		} else {
+			prevTXs, err := bc.prevTransactions(tx, pending)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			fee := tx.Fee(prevTXs)
+			if fee < 0 {
+				return fmt.Errorf("%w: outputs of %x spend more than its inputs", ErrInvalidTransaction, tx.ID)
+			}
+
+			fees += fee
+		}
+
+		pending[hex.EncodeToString(tx.ID)] = *tx
+	}
+
+	if coinbaseValue > bc.cfg.TransactionConfig.Subsidy+fees {
+		return fmt.Errorf("%w: coinbase pays more than the subsidy and fees", ErrInvalidTransaction)
+	}
+
+	return nil
+}
+
+// ConnectBlock stores a block extending the tip with its index entries and filter, applies its transactions to the UTXO set
+// and moves the tip to it in a single transaction, so that the UTXO set never falls out of step with the chain
+func (bc *Blockchain) ConnectBlock(block *Block) error {
+	err := bc.Store.Update(func(tx ChainTx) error {
+		tip := tx.Tip()
+		if !bytes.Equal(block.PrevBlockHash, tip) {
+			return fmt.Errorf("block %x does not extend the tip %x", block.Hash, tip)
+		}
+
+		height := 0
+
+		if tip != nil {
+			tipHeight, err := tx.BlockHeight(tip)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			height = *tipHeight + 1
+		}
+
+		return bc.connectBlock(tx, block, height)
 	})
 	if err != nil {
-		return nil, utils.CatchErr(err)
+		return utils.CatchErr(err)
 	}
 
-	return newBlock, nil
+	bc.Tip = block.Hash
+
+	return nil
+}
+
+// connectBlock stores the block as the new tip at the given height and applies it to the UTXO set within the transaction
+func (bc *Blockchain) connectBlock(tx ChainTx, block *Block, height int) error {
+	err := bc.putBlock(tx, block, height)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = applyBlockUTXOs(tx, block)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return putUTXOTip(bc.cfg, tx, block.Hash)
+}
+
+// putBlock stores the block as the new tip at the given height along with its index entries and filter
+func (bc *Blockchain) putBlock(tx ChainTx, block *Block, height int) error {
+	err := tx.PutBlock(block)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = tx.SetTip(block.Hash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = tx.PutBlockIndex(block.Hash, height)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = putBlockFilter(tx, block)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
 }
 
 // InitializeIterator initializes the blockchain iterator object
 func (bc *Blockchain) InitializeIterator() *BlockchainIterator {
-	bci := &BlockchainIterator{cfg: bc.cfg, currentHash: bc.Tip, db: bc.Db}
+	bci := &BlockchainIterator{cfg: bc.cfg, currentHash: bc.Tip, store: bc.Store}
 
 	return bci
 }
 
-// FindUTXO finds all unspent transaction outputs and returns transactions with spent outputs removed
-func (bc *Blockchain) FindUTXO() (map[string]TXOutputs, error) {
-	UTXO := make(map[string]TXOutputs)
-	spentTXOs := make(map[string][]int)
+// FindTransaction finds a transaction by its ID
+func (bc *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
+	block, err := bc.FindTransactionBlock(ID)
+	if err != nil {
+		return &Transaction{}, utils.CatchErr(err)
+	}
+
+	for _, tx := range block.Transactions {
+		if bytes.Equal(tx.ID, ID) {
+			return tx, nil
+		}
+	}
+
+	return &Transaction{}, ErrTransactionNotFound
+}
 
+// FindSpendingTransaction finds the transaction of the main chain that spends an output
+func (bc *Blockchain) FindSpendingTransaction(ID []byte, outIndex int) (*Transaction, error) {
 	bci := bc.InitializeIterator()
 
 	for {
@@ -195,34 +467,16 @@ func (bc *Blockchain) FindUTXO() (map[string]TXOutputs, error) {
 			return nil, utils.CatchErr(err)
 		}
 
-		for _, transaction := range block.Transactions {
-			transactionID := hex.EncodeToString(transaction.ID)
-
-			err := func() error {
-				for outIndex, out := range transaction.OutputValue {
-					if spentTXOs[transactionID] != nil {
-						if slices.Contains(spentTXOs[transactionID], outIndex) {
-							return nil
-						}
-					}
-
-					outs := UTXO[transactionID]
-					outs.Outputs = append(outs.Outputs, out)
-					UTXO[transactionID] = outs
-				}
+		// Blocks below a pruned block are pruned as well
+		if block.IsPruned() {
+			return nil, fmt.Errorf("%w: output %x:%d may be spent in block %x", ErrBlockPruned, ID, outIndex, block.Hash)
+		}
 
-				if !transaction.IsCoinbase() {
-					for _, in := range transaction.InputValue {
-						inTransactionID := hex.EncodeToString(in.TransactionID)
-						spentTXOs[inTransactionID] = append(spentTXOs[inTransactionID], in.OutputIndex)
-					}
+		for _, tx := range block.Transactions {
+			for _, in := range tx.InputValue {
+				if bytes.Equal(in.TransactionID, ID) && in.OutputIndex == outIndex {
+					return tx, nil
 				}
-
-				return nil
-			}()
-
-			if err != nil {
-				return nil, utils.CatchErr(err)
 			}
 		}
 
@@ -231,11 +485,11 @@ func (bc *Blockchain) FindUTXO() (map[string]TXOutputs, error) {
 		}
 	}
 
-	return UTXO, nil
+	return nil, ErrTransactionNotFound
 }
 
-// FindTransaction finds a transaction by its ID
-func (bc *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
+// FindTransactionBlock finds the block that includes a transaction
+func (bc *Blockchain) FindTransactionBlock(ID []byte) (*Block, error) {
 	bci := bc.InitializeIterator()
 
 	for {
@@ -244,9 +498,14 @@ func (bc *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
 			return nil, utils.CatchErr(err)
 		}
 
+		// Blocks below a pruned block are pruned as well
+		if block.IsPruned() {
+			return nil, fmt.Errorf("%w: transaction %x may be in block %x", ErrBlockPruned, ID, block.Hash)
+		}
+
 		for _, tx := range block.Transactions {
 			if bytes.Equal(tx.ID, ID) {
-				return tx, nil
+				return block, nil
 			}
 		}
 
@@ -255,50 +514,232 @@ func (bc *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
 		}
 	}
 
-	return &Transaction{}, fmt.Errorf("transaction not found")
+	return nil, ErrTransactionNotFound
 }
 
 // SignTransaction signs inputs of a Transaction
 func (bc *Blockchain) SignTransaction(tx *Transaction, privKey ecdsa.PrivateKey) error {
-	prevTXs := make(map[string]Transaction)
+	privKeys := make([]ecdsa.PrivateKey, len(tx.InputValue))
+	for i := range privKeys {
+		privKeys[i] = privKey
+	}
 
-	for _, vin := range tx.InputValue {
-		prevTX, err := bc.FindTransaction(vin.TransactionID)
-		if err != nil {
-			return utils.CatchErr(err)
-		}
+	return bc.SignTransactionInputs(tx, privKeys)
+}
 
-		prevTXs[hex.EncodeToString(prevTX.ID)] = *prevTX
+// SignTransactionInputs signs every input of a Transaction with its own key
+func (bc *Blockchain) SignTransactionInputs(tx *Transaction, privKeys []ecdsa.PrivateKey) error {
+	prevTXs, err := bc.prevTransactions(tx, nil)
+	if err != nil {
+		return utils.CatchErr(err)
 	}
 
-	tx.Sign(privKey, prevTXs)
-
-	return nil
+	return tx.SignInputs(privKeys, prevTXs)
 }
 
 // VerifyTransaction verifies transaction input signatures
 func (bc *Blockchain) VerifyTransaction(tx *Transaction) (*bool, error) {
+	return bc.verifyTransaction(tx, nil)
+}
+
+// verifyTransaction verifies transaction input signatures, looking up the spent outputs in the pending transactions before the chain
+func (bc *Blockchain) verifyTransaction(tx *Transaction, pending map[string]Transaction) (*bool, error) {
 	if tx.IsCoinbase() {
 		verified := true
 
 		return &verified, nil
 	}
 
+	prevTXs, err := bc.prevTransactions(tx, pending)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	verified, err := tx.Verify(prevTXs)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return verified, nil
+}
+
+// TransactionFee returns the fee of a transaction spending outputs of the chain
+func (bc *Blockchain) TransactionFee(tx *Transaction) (*int, error) {
+	prevTXs, err := bc.prevTransactions(tx, nil)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	fee := tx.Fee(prevTXs)
+
+	return &fee, nil
+}
+
+// prevTransactions returns the transactions whose outputs the inputs of the transaction spend, by their hex encoded ID,
+// looking them up in the pending transactions before the chain, and in the UTXO set when their block was pruned
+func (bc *Blockchain) prevTransactions(tx *Transaction, pending map[string]Transaction) (map[string]Transaction, error) {
 	prevTXs := make(map[string]Transaction)
 
 	for _, vin := range tx.InputValue {
+		prevTXID := hex.EncodeToString(vin.TransactionID)
+
+		if prevTX, ok := pending[prevTXID]; ok {
+			prevTXs[prevTXID] = prevTX
+			continue
+		}
+
 		prevTX, err := bc.FindTransaction(vin.TransactionID)
+		if errors.Is(err, ErrBlockPruned) {
+			prevTX, err = bc.unspentOutputsTransaction(vin.TransactionID, err)
+		}
 		if err != nil {
 			return nil, utils.CatchErr(err)
 		}
 
-		prevTXs[hex.EncodeToString(prevTX.ID)] = *prevTX
+		prevTXs[prevTXID] = *prevTX
 	}
 
-	verified, err := tx.Verify(prevTXs)
+	return prevTXs, nil
+}
+
+// GetBlock returns the block with the given hash
+func (bc *Blockchain) GetBlock(hash []byte) (*Block, error) {
+	var block *Block
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		decodedBlock, err := tx.Block(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if decodedBlock.IsPruned() {
+			return fmt.Errorf("%w: %x", ErrBlockPruned, hash)
+		}
+
+		block = decodedBlock
+
+		return nil
+	})
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	return verified, nil
+	return block, nil
+}
+
+// GetBlockHeight returns the height of the block with the given hash
+func (bc *Blockchain) GetBlockHeight(hash []byte) (*int, error) {
+	var height *int
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		blockHeight, err := tx.BlockHeight(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height = blockHeight
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return height, nil
+}
+
+// GetBlockHash returns the hash of the block at the given height
+func (bc *Blockchain) GetBlockHash(height int) ([]byte, error) {
+	var hash []byte
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		blockHash, err := tx.BlockHash(height)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		hash = blockHash
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return hash, nil
+}
+
+// GetBestHeight returns the height of the tip of the blockchain
+func (bc *Blockchain) GetBestHeight() (*int, error) {
+	height, err := bc.GetBlockHeight(bc.Tip)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return height, nil
+}
+
+// ensureIndex rebuilds the height index when it is missing or does not contain the tip
+func (bc *Blockchain) ensureIndex() error {
+	var indexed bool
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		_, err := tx.BlockHeight(bc.Tip)
+		indexed = err == nil
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if indexed {
+		return nil
+	}
+
+	var hashes [][]byte
+
+	bci := bc.InitializeIterator()
+
+	for {
+		block, err := bci.Prev()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		hashes = append(hashes, block.Hash)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	err = bc.Store.Update(func(tx ChainTx) error {
+		err := tx.ClearBlockIndex()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for i, hash := range hashes {
+			err = tx.PutBlockIndex(hash, len(hashes)-1-i)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// heightKey returns the index key of a block height
+func heightKey(height int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(height))
+
+	return key
 }
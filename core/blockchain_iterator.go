@@ -3,30 +3,26 @@ package core
 import (
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
-
-	bolt "go.etcd.io/bbolt"
 )
 
 // BlockchainIterator is used to iterate over blockchain blocks
 type BlockchainIterator struct {
 	cfg         *model.Config
 	currentHash []byte
-	db          *bolt.DB
+	store       ChainStore
 }
 
 // NewBlockchainIterator generates and returns a blockchain iterator
-func NewBlockchainIterator(cfg *model.Config, tip []byte, db *bolt.DB) *BlockchainIterator {
-	return &BlockchainIterator{cfg: cfg, currentHash: tip, db: db}
+func NewBlockchainIterator(cfg *model.Config, tip []byte, store ChainStore) *BlockchainIterator {
+	return &BlockchainIterator{cfg: cfg, currentHash: tip, store: store}
 }
 
 // Prev returns the previous block instance in the blockchain
 func (bci *BlockchainIterator) Prev() (*Block, error) {
 	var prevBlock *Block
 
-	err := bci.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bci.cfg.DatabaseConfig.BlocksBucket))
-		encodedBlock := bucket.Get(bci.currentHash)
-		block, err := DeserializeBlock(encodedBlock)
+	err := bci.store.View(func(tx ChainTx) error {
+		block, err := tx.Block(bci.currentHash)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
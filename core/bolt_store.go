@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"os"
+	"path/filepath"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltChainStore stores the blockchain in a bbolt database file
+type BoltChainStore struct {
+	cfg    *model.Config
+	db     *bolt.DB
+	path   string
+	holder bool
+}
+
+// OpenBoltChainStore opens the configured database file, creating it when it does not exist. It waits up to the lock
+// timeout for other processes to release the file and records the current process as holding it
+func OpenBoltChainStore(cfg *model.Config) (*BoltChainStore, error) {
+	store, err := openBoltChainStore(cfg, cfg.DatabaseConfig.DbName, &bolt.Options{Timeout: cfg.DatabaseConfig.LockTimeout})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = writeLockHolder(store.path)
+	if err != nil {
+		store.db.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	store.holder = true
+
+	return store, nil
+}
+
+// OpenReadOnlyBoltChainStore opens the configured database file without write access. The file can be shared with
+// other readers, it waits up to the lock timeout for a process writing it
+func OpenReadOnlyBoltChainStore(cfg *model.Config) (*BoltChainStore, error) {
+	return openBoltChainStore(cfg, cfg.DatabaseConfig.DbName, &bolt.Options{ReadOnly: true, Timeout: cfg.DatabaseConfig.LockTimeout})
+}
+
+// openBoltChainStore opens the database file at the given path with the given bbolt options
+func openBoltChainStore(cfg *model.Config, path string, options *bolt.Options) (*BoltChainStore, error) {
+	db, err := bolt.Open(path, 0600, options)
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", ErrDatabaseLocked, describeLockHolder(path))
+	}
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &BoltChainStore{cfg: cfg, db: db, path: path}, nil
+}
+
+// View runs fn in a read-only transaction
+func (s *BoltChainStore) View(fn func(tx ChainTx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(&bucketTx{cfg: s.cfg, buckets: boltBuckets{tx: tx}})
+	})
+}
+
+// Update runs fn in a read-write transaction, which is committed when fn returns nil and rolled back otherwise
+func (s *BoltChainStore) Update(fn func(tx ChainTx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&bucketTx{cfg: s.cfg, buckets: boltBuckets{tx: tx}})
+	})
+}
+
+// Backup writes a copy of the database file from a read-only transaction, so the chain can be used while it is copied.
+// The copy is written next to the path and renamed into place once it is synced, so the path never holds a partial copy
+func (s *BoltChainStore) Backup(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(file)
+
+		return err
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = file.Sync()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// check walks the pages of the database file and returns the first inconsistency found
+func (s *BoltChainStore) check() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		var checkErr error
+
+		// Every error is read so that the check is done before the transaction is closed
+		for err := range tx.Check() {
+			if checkErr == nil {
+				checkErr = err
+			}
+		}
+
+		return checkErr
+	})
+}
+
+// Close closes the database file
+func (s *BoltChainStore) Close() error {
+	if s.holder {
+		removeLockHolder(s.path)
+	}
+
+	return s.db.Close()
+}
+
+// boltBuckets gives access to the buckets of a bbolt transaction
+type boltBuckets struct {
+	tx *bolt.Tx
+}
+
+func (b boltBuckets) get(bucket string, key []byte) []byte {
+	boltBucket := b.tx.Bucket([]byte(bucket))
+	if boltBucket == nil {
+		return nil
+	}
+
+	// bbolt values are only valid until the transaction ends
+	return bytes.Clone(boltBucket.Get(key))
+}
+
+func (b boltBuckets) put(bucket string, key []byte, value []byte) error {
+	boltBucket, err := b.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return boltBucket.Put(key, value)
+}
+
+func (b boltBuckets) delete(bucket string, key []byte) error {
+	boltBucket := b.tx.Bucket([]byte(bucket))
+	if boltBucket == nil {
+		return nil
+	}
+
+	return boltBucket.Delete(key)
+}
+
+func (b boltBuckets) forEach(bucket string, fn func(key []byte, value []byte) error) error {
+	boltBucket := b.tx.Bucket([]byte(bucket))
+	if boltBucket == nil {
+		return nil
+	}
+
+	// The records are copied first, as bbolt does not allow changing a bucket while iterating over it
+	var keys, values [][]byte
+
+	err := boltBucket.ForEach(func(k, v []byte) error {
+		keys = append(keys, bytes.Clone(k))
+		values = append(values, bytes.Clone(v))
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for i := range keys {
+		err = fn(keys[i], values[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b boltBuckets) clear(bucket string) error {
+	err := b.tx.DeleteBucket([]byte(bucket))
+	if err != nil && err != bolt.ErrBucketNotFound {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
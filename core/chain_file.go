@@ -0,0 +1,380 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"io"
+)
+
+// maxChainFileBlockSize bounds the length prefix of a block in a chain file, so that a corrupt file is not read into memory whole
+const maxChainFileBlockSize = 32 << 20
+
+// maxChainFileFieldSize bounds the length prefix of a field of the chain file header
+const maxChainFileFieldSize = 256
+
+// chainFileMagic starts the header of a chain file. Read as a block length it is above maxChainFileBlockSize, so files
+// written before the header was added, which start with the length of their first block, are told apart
+var chainFileMagic = []byte("BKCF")
+
+// ChainFileHeader describes the blockchain the blocks of a chain file belong to
+type ChainFileHeader struct {
+	PowAlgorithm string
+}
+
+// ExportChain writes a header with the proof of work algorithm of the blockchain to w, followed by the blocks from the
+// genesis block up to the tip, each serialized block preceded by its length as a big-endian uint32, calling progress
+// with the height of every written block. A pruned blockchain is refused before anything is written, as the chain file
+// needs the transactions of every block
+func (bc *Blockchain) ExportChain(w io.Writer, progress func(height int)) error {
+	pruneHeight, err := bc.PruneHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if *pruneHeight >= 0 {
+		return fmt.Errorf("%w: blocks up to height %d were pruned, only a full node can export its chain", ErrBlockPruned, *pruneHeight)
+	}
+
+	bestHeight, err := bc.GetBestHeight()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = writeChainFileHeader(w, ChainFileHeader{PowAlgorithm: bc.PowAlgorithm})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for height := 0; height <= *bestHeight; height++ {
+		hash, err := bc.GetBlockHash(height)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		block, err := bc.GetBlock(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		serializedBlock, err := block.SerializeBlock()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = binary.Write(w, binary.BigEndian, uint32(len(serializedBlock)))
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		_, err = w.Write(serializedBlock)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		progress(height)
+	}
+
+	return nil
+}
+
+// writeChainFileHeader writes the magic of chain files followed by the fields of the header, each preceded by its
+// length as a big-endian uint32
+func writeChainFileHeader(w io.Writer, header ChainFileHeader) error {
+	_, err := w.Write(chainFileMagic)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = binary.Write(w, binary.BigEndian, uint32(len(header.PowAlgorithm)))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	_, err = io.WriteString(w, header.PowAlgorithm)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// ReadChainFileHeader reads the header of a chain file written by ExportChain. Files written before the header was
+// added have none and belong to a blockchain mined with SHA-256
+func ReadChainFileHeader(r *bufio.Reader) (*ChainFileHeader, error) {
+	magic, err := r.Peek(len(chainFileMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !bytes.Equal(magic, chainFileMagic) {
+		return &ChainFileHeader{PowAlgorithm: PowSHA256}, nil
+	}
+
+	_, err = r.Discard(len(chainFileMagic))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var length uint32
+
+	err = binary.Read(r, binary.BigEndian, &length)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if length > maxChainFileFieldSize {
+		return nil, fmt.Errorf("chain file header field of %d bytes is larger than the limit of %d bytes", length, maxChainFileFieldSize)
+	}
+
+	algorithm := make([]byte, length)
+
+	_, err = io.ReadFull(r, algorithm)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	_, err = NewPowHasher(string(algorithm))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &ChainFileHeader{PowAlgorithm: string(algorithm)}, nil
+}
+
+// ReadChainFileBlock reads the next block written by ExportChain, returning io.EOF at the end of the file
+func ReadChainFileBlock(r io.Reader) (*Block, error) {
+	var length uint32
+
+	err := binary.Read(r, binary.BigEndian, &length)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if length > maxChainFileBlockSize {
+		return nil, fmt.Errorf("block of %d bytes is larger than the limit of %d bytes", length, maxChainFileBlockSize)
+	}
+
+	serializedBlock := make([]byte, length)
+
+	_, err = io.ReadFull(r, serializedBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	block, err := DeserializeBlock(serializedBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return block, nil
+}
+
+// OpenImportBlockchain opens the blockchain a chain file whose header is given is imported into, creating an empty one
+// with the proof of work algorithm of the file when it does not exist
+func OpenImportBlockchain(cfg *model.Config, header *ChainFileHeader) (*Blockchain, error) {
+	store, err := OpenBoltChainStore(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var tip []byte
+
+	err = store.Update(func(tx ChainTx) error {
+		tip = tx.Tip()
+
+		// A blockchain without blocks is written with the current schema and the proof of work of the file from the start
+		if tip == nil {
+			err := putSchemaVersion(cfg, tx, SchemaVersion)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			return putPowAlgorithm(cfg, tx, header.PowAlgorithm)
+		}
+
+		algorithm := readPowAlgorithm(cfg, tx)
+		if algorithm != header.PowAlgorithm {
+			return fmt.Errorf("%w: the chain file was written with %s, the blockchain was created with %s", ErrPowAlgorithmMismatch, header.PowAlgorithm, algorithm)
+		}
+
+		return nil
+	})
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	blockchain := Blockchain{cfg: cfg, Tip: tip, Store: store, PowAlgorithm: header.PowAlgorithm}
+
+	err = blockchain.ensureSchema()
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	err = blockchain.ensureUTXOSet()
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	return &blockchain, nil
+}
+
+// ImportChain reads the blocks of a chain file into the blockchain, checking their linkage, proof of work and transactions,
+// and returns how many blocks were imported. Every block is connected with its UTXO changes, and blocks the blockchain
+// already has are compared with the stored ones and skipped, so an interrupted import is resumed by importing the same file again
+func (bc *Blockchain) ImportChain(r io.Reader, progress func(height int)) (*int, error) {
+	bestHeight := -1
+	imported := 0
+
+	if bc.Tip != nil {
+		height, err := bc.GetBestHeight()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+		bestHeight = *height
+	}
+
+	for height := 0; ; height++ {
+		block, err := ReadChainFileBlock(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading block at height %d: %w", height, err)
+		}
+
+		if height <= bestHeight {
+			hash, err := bc.GetBlockHash(height)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			if !bytes.Equal(hash, block.Hash) {
+				return nil, fmt.Errorf("block %x at height %d is not the stored block %x", block.Hash, height, hash)
+			}
+
+			continue
+		}
+
+		err = bc.importBlock(block, height)
+		if err != nil {
+			return nil, fmt.Errorf("importing block %x at height %d: %w", block.Hash, height, err)
+		}
+
+		imported++
+		progress(height)
+	}
+
+	if bc.Tip == nil {
+		return nil, fmt.Errorf("chain file contains no blocks")
+	}
+
+	_, err := bc.Prune()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &imported, nil
+}
+
+// importBlock checks that the block extends the tip with a valid proof of work and valid transactions that only spend
+// unspent outputs, and connects it as the new tip
+func (bc *Blockchain) importBlock(block *Block, height int) error {
+	if !bytes.Equal(block.PrevBlockHash, bc.Tip) {
+		return fmt.Errorf("block does not extend the tip %x", bc.Tip)
+	}
+
+	if block.IsPruned() {
+		return ErrBlockPruned
+	}
+
+	parentVersion := 0
+
+	if bc.Tip != nil {
+		parent, err := bc.GetBlockHeader(bc.Tip)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		parentVersion = parent.Version
+	}
+
+	err := checkBlockVersion(block.Version, parentVersion)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	pow, err := NewProofOfWork(bc.cfg, bc.PowAlgorithm, block)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	isValid, err := pow.Validate()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		return fmt.Errorf("invalid proof of work")
+	}
+
+	err = bc.checkBlockTransactions(block.Transactions, height, block.Timestamp)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = bc.checkUnspentInputs(block)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return bc.ConnectBlock(block)
+}
+
+// checkUnspentInputs checks that the transactions of the block spend outputs of the UTXO set, or of the transactions
+// before them in the block, and none twice. The UTXO set still tells which outputs the pruned blocks spent
+func (bc *Blockchain) checkUnspentInputs(block *Block) error {
+	utxoSet := NewUTXOSet(bc.cfg, bc)
+	created := make(map[string]bool)
+	spent := make(map[string]bool)
+
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinbase() {
+			for _, vin := range tx.InputValue {
+				if spent[outpointKey(vin)] {
+					return fmt.Errorf("%w: %x spends the output %x:%d twice", ErrInvalidTransaction, tx.ID, vin.TransactionID, vin.OutputIndex)
+				}
+
+				spent[outpointKey(vin)] = true
+
+				if created[hex.EncodeToString(vin.TransactionID)] {
+					continue
+				}
+
+				isUnspent, err := utxoSet.IsUnspent(vin.TransactionID, vin.OutputIndex)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+
+				if !*isUnspent {
+					return fmt.Errorf("%w: %x spends the output %x:%d, which was already spent", ErrInvalidTransaction, tx.ID, vin.TransactionID, vin.OutputIndex)
+				}
+			}
+		}
+
+		created[hex.EncodeToString(tx.ID)] = true
+	}
+
+	return nil
+}
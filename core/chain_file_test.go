@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestChainFileHeader(t *testing.T) {
+	cfg := testConfig()
+	_, address := testWallet(t, cfg)
+	block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{testCoinbase(t, cfg, address)}, []byte{})
+
+	serializedBlock, err := block.SerializeBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var blocks bytes.Buffer
+	binary.Write(&blocks, binary.BigEndian, uint32(len(serializedBlock)))
+	blocks.Write(serializedBlock)
+
+	var withHeader bytes.Buffer
+
+	err = writeChainFileHeader(&withHeader, ChainFileHeader{PowAlgorithm: PowScrypt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withHeader.Write(blocks.Bytes())
+
+	tests := []struct {
+		name      string
+		file      []byte
+		algorithm string
+	}{
+		{name: "file with a header", file: withHeader.Bytes(), algorithm: PowScrypt},
+		{name: "file written before the header", file: blocks.Bytes(), algorithm: PowSHA256},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(test.file))
+
+			header, err := ReadChainFileHeader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if header.PowAlgorithm != test.algorithm {
+				t.Errorf("read algorithm %s, expected %s", header.PowAlgorithm, test.algorithm)
+			}
+
+			// The blocks follow the header
+			readBlock, err := ReadChainFileBlock(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(readBlock.Hash, block.Hash) {
+				t.Errorf("read block %x, expected %x", readBlock.Hash, block.Hash)
+			}
+		})
+	}
+
+	var unknownAlgorithm bytes.Buffer
+	writeChainFileHeader(&unknownAlgorithm, ChainFileHeader{PowAlgorithm: "md5"})
+
+	_, err = ReadChainFileHeader(bufio.NewReader(&unknownAlgorithm))
+	if !errors.Is(err, ErrUnknownPowAlgorithm) {
+		t.Errorf("read a header with an unknown algorithm with %v, expected %v", err, ErrUnknownPowAlgorithm)
+	}
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"encoding/binary"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+// ChainStore stores a blockchain. Everything is read and written through transactions, so that a block and
+// the state that depends on it are stored together or not at all
+type ChainStore interface {
+	// View runs fn in a read-only transaction
+	View(fn func(tx ChainTx) error) error
+	// Update runs fn in a read-write transaction, which is committed when fn returns nil and rolled back otherwise
+	Update(fn func(tx ChainTx) error) error
+	// Backup writes a consistent copy of the store to a file
+	Backup(path string) error
+	Close() error
+}
+
+// ChainTx is a transaction on a chain store, covering the blocks, the tip, the height index, the block filters and the
+// UTXO set, along with the records the mempool, webhooks and snapshots keep next to them. Values it returns stay valid
+// after the transaction ends
+type ChainTx interface {
+	// Tip returns the hash of the last block, or nil when the store holds no block
+	Tip() []byte
+	SetTip(hash []byte) error
+	// Block returns the block with the given hash, or ErrBlockNotFound
+	Block(hash []byte) (*Block, error)
+	PutBlock(block *Block) error
+
+	// BlockHeight returns the height of the block with the given hash, or ErrBlockNotFound
+	BlockHeight(hash []byte) (*int, error)
+	// BlockHash returns the hash of the block at the given height, or ErrBlockNotFound
+	BlockHash(height int) ([]byte, error)
+	PutBlockIndex(hash []byte, height int) error
+	ClearBlockIndex() error
+
+	// Filter returns the filter of the block with the given hash, or ErrBlockNotFound
+	Filter(hash []byte) (*BlockFilter, error)
+	PutFilter(hash []byte, filter *BlockFilter) error
+
+	// UTXOs returns the unspent outputs of a transaction, or nil when none are left
+	UTXOs(transactionID []byte) (*TXOutputs, error)
+	PutUTXOs(transactionID []byte, outs TXOutputs) error
+	DeleteUTXOs(transactionID []byte) error
+	// ForEachUTXOs calls fn with the unspent outputs of every transaction, in transaction ID order
+	ForEachUTXOs(fn func(transactionID []byte, outs *TXOutputs) error) error
+	ClearUTXOs() error
+
+	// Record returns the value of a key in a named bucket of records, or nil
+	Record(bucket string, key []byte) []byte
+	PutRecord(bucket string, key []byte, value []byte) error
+	DeleteRecord(bucket string, key []byte) error
+	// ForEachRecord calls fn with every record of a bucket in key order, fn may delete records
+	ForEachRecord(bucket string, fn func(key []byte, value []byte) error) error
+}
+
+// tipKey is the key of the tip in the blocks bucket
+var tipKey = []byte("l")
+
+// buckets is the access to named buckets of keys and values a chain store backend gives its transactions.
+// Returned values are copies, missing buckets read as empty and are created by the first write
+type buckets interface {
+	get(bucket string, key []byte) []byte
+	put(bucket string, key []byte, value []byte) error
+	delete(bucket string, key []byte) error
+	forEach(bucket string, fn func(key []byte, value []byte) error) error
+	clear(bucket string) error
+}
+
+// bucketTx implements ChainTx over the buckets of a backend, using the configured bucket names
+type bucketTx struct {
+	cfg     *model.Config
+	buckets buckets
+}
+
+// Tip returns the hash of the last block, or nil when the store holds no block
+func (tx *bucketTx) Tip() []byte {
+	return tx.buckets.get(tx.cfg.DatabaseConfig.BlocksBucket, tipKey)
+}
+
+// SetTip makes the block with the given hash the last block
+func (tx *bucketTx) SetTip(hash []byte) error {
+	return tx.buckets.put(tx.cfg.DatabaseConfig.BlocksBucket, tipKey, hash)
+}
+
+// Block returns the block with the given hash, or ErrBlockNotFound
+func (tx *bucketTx) Block(hash []byte) (*Block, error) {
+	encodedBlock := tx.buckets.get(tx.cfg.DatabaseConfig.BlocksBucket, hash)
+	if encodedBlock == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	block, err := DeserializeBlock(encodedBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return block, nil
+}
+
+// PutBlock stores the block under its hash
+func (tx *bucketTx) PutBlock(block *Block) error {
+	serializedBlock, err := block.SerializeBlock()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return tx.buckets.put(tx.cfg.DatabaseConfig.BlocksBucket, block.Hash, serializedBlock)
+}
+
+// BlockHeight returns the height of the block with the given hash, or ErrBlockNotFound
+func (tx *bucketTx) BlockHeight(hash []byte) (*int, error) {
+	encodedHeight := tx.buckets.get(tx.cfg.DatabaseConfig.IndexBucket, hash)
+	if encodedHeight == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	height := int(binary.BigEndian.Uint64(encodedHeight))
+
+	return &height, nil
+}
+
+// BlockHash returns the hash of the block at the given height, or ErrBlockNotFound
+func (tx *bucketTx) BlockHash(height int) ([]byte, error) {
+	hash := tx.buckets.get(tx.cfg.DatabaseConfig.IndexBucket, heightKey(height))
+	if hash == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	return hash, nil
+}
+
+// PutBlockIndex stores the height of a block in both directions (height -> hash and hash -> height)
+func (tx *bucketTx) PutBlockIndex(hash []byte, height int) error {
+	err := tx.buckets.put(tx.cfg.DatabaseConfig.IndexBucket, heightKey(height), hash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return tx.buckets.put(tx.cfg.DatabaseConfig.IndexBucket, hash, heightKey(height))
+}
+
+// ClearBlockIndex deletes the whole height index
+func (tx *bucketTx) ClearBlockIndex() error {
+	return tx.buckets.clear(tx.cfg.DatabaseConfig.IndexBucket)
+}
+
+// Filter returns the filter of the block with the given hash, or ErrBlockNotFound
+func (tx *bucketTx) Filter(hash []byte) (*BlockFilter, error) {
+	encodedFilter := tx.buckets.get(tx.cfg.DatabaseConfig.FiltersBucket, hash)
+	if encodedFilter == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	var blockFilter BlockFilter
+
+	err := gobDecode(encodedFilter, &blockFilter)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &blockFilter, nil
+}
+
+// PutFilter stores the filter of the block with the given hash
+func (tx *bucketTx) PutFilter(hash []byte, filter *BlockFilter) error {
+	encodedFilter, err := gobEncode(filter)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return tx.buckets.put(tx.cfg.DatabaseConfig.FiltersBucket, hash, encodedFilter)
+}
+
+// UTXOs returns the unspent outputs of a transaction, or nil when none are left
+func (tx *bucketTx) UTXOs(transactionID []byte) (*TXOutputs, error) {
+	outsBytes := tx.buckets.get(tx.cfg.DatabaseConfig.UTXOSetBucket, transactionID)
+	if outsBytes == nil {
+		return nil, nil
+	}
+
+	outs, err := DeserializeOutputs(outsBytes)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return outs, nil
+}
+
+// PutUTXOs stores the unspent outputs of a transaction
+func (tx *bucketTx) PutUTXOs(transactionID []byte, outs TXOutputs) error {
+	serializedOutputs, err := outs.Serialize()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return tx.buckets.put(tx.cfg.DatabaseConfig.UTXOSetBucket, transactionID, serializedOutputs)
+}
+
+// DeleteUTXOs deletes the outputs of a transaction whose outputs are all spent
+func (tx *bucketTx) DeleteUTXOs(transactionID []byte) error {
+	return tx.buckets.delete(tx.cfg.DatabaseConfig.UTXOSetBucket, transactionID)
+}
+
+// ForEachUTXOs calls fn with the unspent outputs of every transaction, in transaction ID order
+func (tx *bucketTx) ForEachUTXOs(fn func(transactionID []byte, outs *TXOutputs) error) error {
+	return tx.buckets.forEach(tx.cfg.DatabaseConfig.UTXOSetBucket, func(key []byte, value []byte) error {
+		outs, err := DeserializeOutputs(value)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		return fn(key, outs)
+	})
+}
+
+// ClearUTXOs deletes the whole UTXO set
+func (tx *bucketTx) ClearUTXOs() error {
+	return tx.buckets.clear(tx.cfg.DatabaseConfig.UTXOSetBucket)
+}
+
+// Record returns the value of a key in a named bucket of records, or nil
+func (tx *bucketTx) Record(bucket string, key []byte) []byte {
+	return tx.buckets.get(bucket, key)
+}
+
+// PutRecord stores a record in a named bucket
+func (tx *bucketTx) PutRecord(bucket string, key []byte, value []byte) error {
+	return tx.buckets.put(bucket, key, value)
+}
+
+// DeleteRecord deletes a record from a named bucket
+func (tx *bucketTx) DeleteRecord(bucket string, key []byte) error {
+	return tx.buckets.delete(bucket, key)
+}
+
+// ForEachRecord calls fn with every record of a bucket in key order, fn may delete records
+func (tx *bucketTx) ForEachRecord(bucket string, fn func(key []byte, value []byte) error) error {
+	return tx.buckets.forEach(bucket, fn)
+}
@@ -0,0 +1,390 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"go-burrokuchen/model"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testBlocks mines a chain of count blocks paying their coinbase to a new address
+func testBlocks(t *testing.T, cfg *model.Config, count int) []*Block {
+	t.Helper()
+
+	_, address := testWallet(t, cfg)
+
+	var blocks []*Block
+	prevBlockHash := []byte{}
+
+	for len(blocks) < count {
+		block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{testCoinbase(t, cfg, address)}, prevBlockHash)
+		blocks = append(blocks, block)
+		prevBlockHash = block.Hash
+	}
+
+	return blocks
+}
+
+// testStores runs the test against every chain store backend
+func testStores(t *testing.T, test func(t *testing.T, store ChainStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryChainStore(testConfig()))
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.DatabaseConfig.DbName = filepath.Join(t.TempDir(), "chain.db")
+
+		store, err := OpenBoltChainStore(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		test(t, store)
+	})
+}
+
+func TestChainStoreBlocks(t *testing.T) {
+	cfg := testConfig()
+	blocks := testBlocks(t, cfg, 2)
+
+	testStores(t, func(t *testing.T, store ChainStore) {
+		err := store.Update(func(tx ChainTx) error {
+			if tx.Tip() != nil {
+				t.Errorf("empty store has tip %x", tx.Tip())
+			}
+
+			for height, block := range blocks {
+				err := tx.PutBlock(block)
+				if err != nil {
+					return err
+				}
+
+				err = tx.PutBlockIndex(block.Hash, height)
+				if err != nil {
+					return err
+				}
+
+				err = tx.PutFilter(block.Hash, &BlockFilter{Filter: []byte{byte(height)}, Header: block.Hash})
+				if err != nil {
+					return err
+				}
+			}
+
+			return tx.SetTip(blocks[1].Hash)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.View(func(tx ChainTx) error {
+			if !bytes.Equal(tx.Tip(), blocks[1].Hash) {
+				t.Errorf("tip is %x, expected %x", tx.Tip(), blocks[1].Hash)
+			}
+
+			for height, block := range blocks {
+				storedBlock, err := tx.Block(block.Hash)
+				if err != nil {
+					return err
+				}
+
+				if !bytes.Equal(storedBlock.Hash, block.Hash) || len(storedBlock.Transactions) != 1 {
+					t.Errorf("stored block %x differs from %x", storedBlock.Hash, block.Hash)
+				}
+
+				storedHeight, err := tx.BlockHeight(block.Hash)
+				if err != nil {
+					return err
+				}
+
+				hash, err := tx.BlockHash(height)
+				if err != nil {
+					return err
+				}
+
+				if *storedHeight != height || !bytes.Equal(hash, block.Hash) {
+					t.Errorf("block %x is indexed at height %d with hash %x, expected %d", block.Hash, *storedHeight, hash, height)
+				}
+
+				filter, err := tx.Filter(block.Hash)
+				if err != nil {
+					return err
+				}
+
+				if !bytes.Equal(filter.Filter, []byte{byte(height)}) {
+					t.Errorf("filter of block %x is %x", block.Hash, filter.Filter)
+				}
+			}
+
+			missing := bytes.Repeat([]byte{0xFF}, 32)
+
+			_, err := tx.Block(missing)
+			if !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("read a missing block with %v, expected %v", err, ErrBlockNotFound)
+			}
+
+			_, err = tx.BlockHeight(missing)
+			if !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("read the height of a missing block with %v, expected %v", err, ErrBlockNotFound)
+			}
+
+			_, err = tx.BlockHash(2)
+			if !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("read a missing height with %v, expected %v", err, ErrBlockNotFound)
+			}
+
+			_, err = tx.Filter(missing)
+			if !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("read a missing filter with %v, expected %v", err, ErrBlockNotFound)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.Update(func(tx ChainTx) error {
+			return tx.ClearBlockIndex()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.View(func(tx ChainTx) error {
+			_, err := tx.BlockHash(0)
+			if !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("read a cleared height with %v, expected %v", err, ErrBlockNotFound)
+			}
+
+			// Clearing the index keeps the blocks
+			_, err = tx.Block(blocks[0].Hash)
+
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestChainStoreUTXOs(t *testing.T) {
+	testStores(t, func(t *testing.T, store ChainStore) {
+		outputs := TXOutputs{Outputs: []TXOutput{{Value: 5, PubKeyHash: []byte{1}}}, Indexes: []int{2}}
+
+		err := store.Update(func(tx ChainTx) error {
+			for _, transactionID := range [][]byte{{3}, {1}, {2}} {
+				err := tx.PutUTXOs(transactionID, outputs)
+				if err != nil {
+					return err
+				}
+			}
+
+			return tx.DeleteUTXOs([]byte{2})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.View(func(tx ChainTx) error {
+			outs, err := tx.UTXOs([]byte{1})
+			if err != nil {
+				return err
+			}
+
+			if outs == nil || outs.Index(0) != 2 || outs.Outputs[0].Value != 5 {
+				t.Errorf("read outputs %+v, expected %+v", outs, outputs)
+			}
+
+			outs, err = tx.UTXOs([]byte{2})
+			if err != nil {
+				return err
+			}
+
+			if outs != nil {
+				t.Errorf("read deleted outputs %+v", outs)
+			}
+
+			var transactionIDs [][]byte
+
+			err = tx.ForEachUTXOs(func(transactionID []byte, outs *TXOutputs) error {
+				transactionIDs = append(transactionIDs, transactionID)
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if !slices.EqualFunc(transactionIDs, [][]byte{{1}, {3}}, bytes.Equal) {
+				t.Errorf("iterated over %x, expected 01 and 03 in order", transactionIDs)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.Update(func(tx ChainTx) error {
+			return tx.ClearUTXOs()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.View(func(tx ChainTx) error {
+			return tx.ForEachUTXOs(func(transactionID []byte, outs *TXOutputs) error {
+				t.Errorf("cleared UTXO set holds %x", transactionID)
+
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestChainStoreRecords(t *testing.T) {
+	testStores(t, func(t *testing.T, store ChainStore) {
+		err := store.Update(func(tx ChainTx) error {
+			for _, key := range []string{"c", "a", "b"} {
+				err := tx.PutRecord("records", []byte(key), []byte("value "+key))
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Records are deleted while iterating over them
+		var keys []string
+
+		err = store.Update(func(tx ChainTx) error {
+			return tx.ForEachRecord("records", func(key []byte, value []byte) error {
+				keys = append(keys, string(key))
+
+				if !bytes.Equal(value, []byte("value "+string(key))) {
+					t.Errorf("record %s has value %s", key, value)
+				}
+
+				if string(key) == "b" {
+					return tx.DeleteRecord("records", key)
+				}
+
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(keys, []string{"a", "b", "c"}) {
+			t.Errorf("iterated over %v, expected a, b and c in order", keys)
+		}
+
+		err = store.View(func(tx ChainTx) error {
+			if tx.Record("records", []byte("b")) != nil {
+				t.Error("deleted record is still stored")
+			}
+
+			if !bytes.Equal(tx.Record("records", []byte("c")), []byte("value c")) {
+				t.Errorf("record c has value %s", tx.Record("records", []byte("c")))
+			}
+
+			if tx.Record("missing", []byte("a")) != nil {
+				t.Error("a missing bucket holds a record")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestChainStoreRollsBackFailedUpdates(t *testing.T) {
+	cfg := testConfig()
+	blocks := testBlocks(t, cfg, 2)
+	errFailed := errors.New("failed")
+
+	testStores(t, func(t *testing.T, store ChainStore) {
+		err := store.Update(func(tx ChainTx) error {
+			err := tx.PutBlock(blocks[0])
+			if err != nil {
+				return err
+			}
+
+			err = tx.PutRecord("records", []byte("a"), []byte("kept"))
+			if err != nil {
+				return err
+			}
+
+			return tx.SetTip(blocks[0].Hash)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.Update(func(tx ChainTx) error {
+			err := tx.PutBlock(blocks[1])
+			if err != nil {
+				return err
+			}
+
+			err = tx.SetTip(blocks[1].Hash)
+			if err != nil {
+				return err
+			}
+
+			err = tx.PutRecord("records", []byte("a"), []byte("overwritten"))
+			if err != nil {
+				return err
+			}
+
+			err = tx.DeleteRecord("records", []byte("a"))
+			if err != nil {
+				return err
+			}
+
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Fatalf("update failed with %v, expected %v", err, errFailed)
+		}
+
+		err = store.View(func(tx ChainTx) error {
+			if !bytes.Equal(tx.Tip(), blocks[0].Hash) {
+				t.Errorf("tip is %x after the rollback, expected %x", tx.Tip(), blocks[0].Hash)
+			}
+
+			_, err := tx.Block(blocks[1].Hash)
+			if !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("read the rolled back block with %v, expected %v", err, ErrBlockNotFound)
+			}
+
+			if !bytes.Equal(tx.Record("records", []byte("a")), []byte("kept")) {
+				t.Errorf("record is %s after the rollback, expected kept", tx.Record("records", []byte("a")))
+			}
+
+			// Read-only transactions refuse writes
+			if tx.PutRecord("records", []byte("b"), []byte("value")) == nil {
+				t.Error("wrote a record in a read-only transaction")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+var ErrCPFPFeeTooLow = errors.New("fee does not raise the fee rate of the pending transaction")
+
+// NewCPFPTransaction returns a transaction spending an output of a pending transaction back to the wallet that owns it,
+// paying a fee high enough for miners to take the pending transaction along with it
+func NewCPFPTransaction(cfg *model.Config, parent *Transaction, outIndex int, wallet *Wallet, fee int) (*Transaction, error) {
+	if outIndex < 0 || outIndex >= len(parent.OutputValue) {
+		return nil, fmt.Errorf("transaction %x has no output %d", parent.ID, outIndex)
+	}
+
+	out := parent.OutputValue[outIndex]
+
+	pubKeyHash, err := HashPubKey(wallet.PublicKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if out.HTLC != nil || !out.IsLockedWithKey(pubKeyHash) {
+		return nil, fmt.Errorf("output %d of %x is not locked to the wallet", outIndex, parent.ID)
+	}
+
+	if fee <= 0 || fee >= out.Value {
+		return nil, fmt.Errorf("fee must be positive and below the output value of %d", out.Value)
+	}
+
+	address, err := wallet.GetAddress()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	output, err := NewTXOutput(cfg, out.Value-fee, string(address))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	input := TXInput{TransactionID: parent.ID, OutputIndex: outIndex, PubKey: wallet.PublicKey}
+
+	tx := Transaction{ID: nil, InputValue: []TXInput{input}, OutputValue: []TXOutput{*output}}
+	hash, err := tx.Hash()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tx.ID = hash
+
+	// The parent is not in the chain yet, so the transaction is signed against it directly
+	err = tx.Sign(wallet.PrivateKey, map[string]Transaction{hex.EncodeToString(parent.ID): *parent})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &tx, nil
+}
+
+// CheckCPFPFeeRate checks that a child paying the given fee raises the fee rate of the pending parent, which is what
+// miners rank the two by, and returns the combined fee rate
+func CheckCPFPFeeRate(parent *MempoolEntry, child *Transaction, fee int) (*float64, error) {
+	serializedChild, err := child.Serialize()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	childSize := len(serializedChild)
+	packageRate := float64(parent.Fee+fee) / float64(parent.Size+childSize)
+
+	// The package rate only rises above the parent rate when the child pays more per byte than the parent
+	if fee*parent.Size <= parent.Fee*childSize {
+		minFee := parent.Fee*childSize/parent.Size + 1
+
+		return nil, fmt.Errorf("%w: combined fee rate %.4f does not exceed the fee rate %.4f of %x, the fee must be at least %d", ErrCPFPFeeTooLow, packageRate, parent.FeeRate(), parent.Transaction.ID, minFee)
+	}
+
+	return &packageRate, nil
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckCPFPFeeRate(t *testing.T) {
+	cfg := testConfig()
+	wallet, _ := testWallet(t, cfg)
+
+	pubKeyHash, err := HashPubKey(wallet.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parentTX := &Transaction{ID: []byte{1}, OutputValue: []TXOutput{{Value: 100, PubKeyHash: pubKeyHash}}}
+
+	// The parent has the size of the child, so their fee rates compare like their fees
+	tests := []struct {
+		name      string
+		fee       int
+		parentFee int
+		expected  error
+	}{
+		{name: "child raising the rate", fee: 20, parentFee: 10},
+		{name: "child matching the rate", fee: 10, parentFee: 10, expected: ErrCPFPFeeTooLow},
+		{name: "child lowering the rate", fee: 5, parentFee: 10, expected: ErrCPFPFeeTooLow},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			child, err := NewCPFPTransaction(cfg, parentTX, 0, wallet, test.fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			serializedChild, err := child.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parent := MempoolEntry{Transaction: *parentTX, Fee: test.parentFee, Size: len(serializedChild)}
+
+			packageRate, err := CheckCPFPFeeRate(&parent, child, test.fee)
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("checked with %v, expected %v", err, test.expected)
+			}
+
+			if test.expected == nil && *packageRate <= parent.FeeRate() {
+				t.Errorf("combined fee rate %.4f does not exceed the parent rate %.4f", *packageRate, parent.FeeRate())
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+// NewDataOutput creates a zero-value output carrying data, which can never be spent
+func NewDataOutput(cfg *model.Config, data []byte) (*TXOutput, error) {
+	maxDataSize := cfg.TransactionConfig.MaxDataSize
+
+	if len(data) == 0 || len(data) > maxDataSize {
+		return nil, fmt.Errorf("data outputs carry 1 to %d bytes", maxDataSize)
+	}
+
+	return &TXOutput{cfg: cfg, Value: 0, Data: data}, nil
+}
+
+// IsData checks whether the output is a data output
+func (out *TXOutput) IsData() bool {
+	return len(out.Data) != 0
+}
+
+// CheckDataOutputs checks that the data outputs of the transaction hold no value, no key and at most maxDataSize bytes
+func (tx *Transaction) CheckDataOutputs(maxDataSize int) error {
+	for outIndex, out := range tx.OutputValue {
+		if !out.IsData() {
+			continue
+		}
+
+		if out.Value != 0 || out.PubKeyHash != nil || out.HTLC != nil || out.RelativeLock != 0 {
+			return fmt.Errorf("%w: data output %d is spendable", ErrInvalidTransaction, outIndex)
+		}
+
+		if len(out.Data) > maxDataSize {
+			return fmt.Errorf("%w: data output %d carries more than %d bytes", ErrInvalidTransaction, outIndex, maxDataSize)
+		}
+	}
+
+	return nil
+}
+
+// NewDataTransaction generates and returns a transaction committing the data, paid for by an output of the sender sent back as change
+func NewDataTransaction(utxoSet UTXOSet, from string, data []byte) (*Transaction, error) {
+	wallets, err := NewWallets(utxoSet.cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	wallet, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	pubKeyHash, err := HashPubKey(wallet.PublicKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// A transaction needs an input, so the smallest amount is spent and returned as change
+	balance, validOutputs, err := utxoSet.FindSpendableOutputs(pubKeyHash, 1)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if *balance == 0 {
+		err := fmt.Errorf("%s has no output to pay for the data", from)
+
+		return nil, utils.CatchErr(err)
+	}
+
+	inputs, err := newInputs(wallet.PublicKey, validOutputs)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	output, err := NewDataOutput(utxoSet.cfg, data)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tx, err := newTransactionWithOutput(utxoSet.cfg, inputs, *output, from, *balance, 0)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = utxoSet.Blockchain.SignTransaction(tx, wallet.PrivateKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return tx, nil
+}
+
+// FindData finds the earliest transaction of the main chain with a data output carrying the data, and its block.
+// On a pruned chain only the blocks that were not pruned are searched.
+func (bc *Blockchain) FindData(data []byte) (*Transaction, *Block, error) {
+	var foundTX *Transaction
+	var foundBlock *Block
+
+	bci := bc.InitializeIterator()
+
+	for {
+		block, err := bci.Prev()
+		if err != nil {
+			return nil, nil, utils.CatchErr(err)
+		}
+
+		if block.IsPruned() {
+			if foundTX == nil {
+				return nil, nil, fmt.Errorf("%w: data may be in block %x", ErrBlockPruned, block.Hash)
+			}
+
+			break
+		}
+
+		for _, tx := range block.Transactions {
+			if tx.CarriesData(data) {
+				foundTX, foundBlock = tx, block
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	if foundTX == nil {
+		return nil, nil, ErrTransactionNotFound
+	}
+
+	return foundTX, foundBlock, nil
+}
+
+// CarriesData checks whether one of the data outputs of the transaction carries the data
+func (tx *Transaction) CarriesData(data []byte) bool {
+	for _, out := range tx.OutputValue {
+		if out.IsData() && bytes.Equal(out.Data, data) {
+			return true
+		}
+	}
+
+	return false
+}
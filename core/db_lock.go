@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-burrokuchen/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrDatabaseLocked is returned when another process keeps the database file locked for longer than the lock timeout
+var ErrDatabaseLocked = errors.New("database is locked by another process")
+
+// LockHolder is the process that opened a database file for writing, recorded next to it so that a process waiting
+// for the lock can tell which one holds it
+type LockHolder struct {
+	PID     int    `json:"pid"`
+	Command string `json:"command"`
+	Since   int64  `json:"since"`
+}
+
+// lockHolderPath returns the path of the file recording the process holding the lock of a database file
+func lockHolderPath(path string) string {
+	return path + ".holder"
+}
+
+// writeLockHolder records the current process as the holder of the lock of a database file
+func writeLockHolder(path string) error {
+	command := filepath.Base(os.Args[0])
+	// Only the subcommand is recorded, the flags may hold secrets
+	if len(os.Args) > 1 {
+		command += " " + os.Args[1]
+	}
+
+	content, err := json.Marshal(LockHolder{PID: os.Getpid(), Command: command, Since: time.Now().Unix()})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return os.WriteFile(lockHolderPath(path), content, 0600)
+}
+
+// removeLockHolder removes the record of the current process holding the lock of a database file
+func removeLockHolder(path string) {
+	holder, err := ReadLockHolder(path)
+	if err == nil && holder != nil && holder.PID == os.Getpid() {
+		os.Remove(lockHolderPath(path))
+	}
+}
+
+// ReadLockHolder returns the process recorded as holding the lock of a database file, or nil when none is
+func ReadLockHolder(path string) (*LockHolder, error) {
+	content, err := os.ReadFile(lockHolderPath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var holder LockHolder
+
+	err = json.Unmarshal(content, &holder)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &holder, nil
+}
+
+// describeLockHolder describes the process holding the lock of a database file. Processes only reading the file
+// share its lock without recording themselves
+func describeLockHolder(path string) string {
+	holder, err := ReadLockHolder(path)
+	if err != nil || holder == nil {
+		return fmt.Sprintf("%s is held by a process reading it", path)
+	}
+
+	return fmt.Sprintf("%s is held by process %d (%s) since %s", path, holder.PID, holder.Command, time.Unix(holder.Since, 0).Format(time.RFC3339))
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// gcsP is the number of bits of the remainder of every Golomb-Rice coded value
+	gcsP = 19
+	// gcsM is the inverse of the false positive rate of a filter
+	gcsM = 784931
+	// gcsKeyLength is how many bytes of the block hash key the item hashes
+	gcsKeyLength = 16
+)
+
+// GCSFilter is a Golomb-coded set of items, answering membership queries with a small false positive rate
+type GCSFilter struct {
+	N    uint32
+	Data []byte
+}
+
+// NewGCSFilter builds a filter over the items, hashing them with the given key
+func NewGCSFilter(key []byte, items [][]byte) *GCSFilter {
+	seen := make(map[string]bool)
+
+	var unique [][]byte
+	for _, item := range items {
+		if !seen[string(item)] {
+			seen[string(item)] = true
+			unique = append(unique, item)
+		}
+	}
+
+	values := hashGCSItems(key, uint64(len(unique)), unique)
+	slices.Sort(values)
+
+	var writer bitWriter
+	var last uint64
+
+	for _, value := range values {
+		delta := value - last
+		last = value
+
+		for quotient := delta >> gcsP; quotient > 0; quotient-- {
+			writer.writeBit(true)
+		}
+		writer.writeBit(false)
+		writer.writeBits(delta, gcsP)
+	}
+
+	filter := GCSFilter{N: uint32(len(values)), Data: writer.bytes}
+
+	return &filter
+}
+
+// Match reports whether the item may be in the set
+func (f *GCSFilter) Match(key []byte, item []byte) (bool, error) {
+	return f.MatchAny(key, [][]byte{item})
+}
+
+// MatchAny reports whether any of the items may be in the set
+func (f *GCSFilter) MatchAny(key []byte, items [][]byte) (bool, error) {
+	if f.N == 0 || len(items) == 0 {
+		return false, nil
+	}
+
+	queries := hashGCSItems(key, uint64(f.N), items)
+	slices.Sort(queries)
+
+	reader := bitReader{bytes: f.Data}
+	var value uint64
+
+	for i := uint32(0); i < f.N; i++ {
+		delta, err := reader.readGolombRice()
+		if err != nil {
+			return false, err
+		}
+
+		value += delta
+
+		for len(queries) > 0 && queries[0] < value {
+			queries = queries[1:]
+		}
+
+		if len(queries) == 0 {
+			return false, nil
+		}
+
+		if queries[0] == value {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Serialize serializes the filter as its item count followed by the Golomb-Rice coded deltas
+func (f *GCSFilter) Serialize() []byte {
+	serializedFilter := make([]byte, 4, 4+len(f.Data))
+	binary.BigEndian.PutUint32(serializedFilter, f.N)
+
+	return append(serializedFilter, f.Data...)
+}
+
+// DeserializeGCSFilter deserializes a filter
+func DeserializeGCSFilter(d []byte) (*GCSFilter, error) {
+	if len(d) < 4 {
+		return nil, fmt.Errorf("filter is too short")
+	}
+
+	filter := GCSFilter{N: binary.BigEndian.Uint32(d), Data: d[4:]}
+
+	return &filter, nil
+}
+
+// hashGCSItems maps the items uniformly onto the range [0, n * M) of a filter holding n items
+func hashGCSItems(key []byte, n uint64, items [][]byte) []uint64 {
+	key = key[:min(len(key), gcsKeyLength)]
+	modulus := n * gcsM
+
+	var values []uint64
+
+	for _, item := range items {
+		hash := sha256.Sum256(append(append([]byte{}, key...), item...))
+		value, _ := bits.Mul64(binary.BigEndian.Uint64(hash[:8]), modulus)
+
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// bitWriter appends bits to a byte slice, most significant bit first
+type bitWriter struct {
+	bytes []byte
+	count uint
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.count%8 == 0 {
+		w.bytes = append(w.bytes, 0)
+	}
+
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 0x80 >> (w.count % 8)
+	}
+
+	w.count++
+}
+
+func (w *bitWriter) writeBits(value uint64, n uint) {
+	for i := n; i > 0; i-- {
+		w.writeBit(value&(1<<(i-1)) != 0)
+	}
+}
+
+// bitReader reads bits written by a bitWriter
+type bitReader struct {
+	bytes []byte
+	count uint
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.count/8 >= uint(len(r.bytes)) {
+		return false, fmt.Errorf("filter ended unexpectedly")
+	}
+
+	bit := r.bytes[r.count/8]&(0x80>>(r.count%8)) != 0
+	r.count++
+
+	return bit, nil
+}
+
+func (r *bitReader) readGolombRice() (uint64, error) {
+	var quotient uint64
+
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		if !bit {
+			break
+		}
+
+		quotient++
+	}
+
+	remainder := uint64(0)
+
+	for i := 0; i < gcsP; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		remainder <<= 1
+		if bit {
+			remainder |= 1
+		}
+	}
+
+	return quotient<<gcsP | remainder, nil
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+// testGCSItems returns count distinct items derived from the seed
+func testGCSItems(seed string, count int) [][]byte {
+	var items [][]byte
+
+	for i := 0; i < count; i++ {
+		var index [8]byte
+		binary.BigEndian.PutUint64(index[:], uint64(i))
+
+		item := sha256.Sum256(append([]byte(seed), index[:]...))
+		items = append(items, item[:20])
+	}
+
+	return items
+}
+
+func TestGCSFilterMatchesItems(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	items := testGCSItems("member", 500)
+
+	// Duplicate items are only stored once
+	filter := NewGCSFilter(key, append(items, items[:10]...))
+
+	if filter.N != uint32(len(items)) {
+		t.Errorf("filter holds %d items, expected %d", filter.N, len(items))
+	}
+
+	filter, err := DeserializeGCSFilter(filter.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, item := range items {
+		matched, err := filter.Match(key, item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !matched {
+			t.Errorf("item %d does not match", i)
+		}
+	}
+
+	matched, err := filter.MatchAny(key, append(testGCSItems("other", 20), items[250]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !matched {
+		t.Error("items including a member do not match")
+	}
+}
+
+func TestGCSFilterFalsePositives(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	filter := NewGCSFilter(key, testGCSItems("member", 1000))
+
+	queries := testGCSItems("other", 100000)
+	falsePositives := 0
+
+	// Queries are matched in batches, a batch matches when any of its queries is a false positive
+	for batch := 0; batch < len(queries); batch += 100 {
+		matched, err := filter.MatchAny(key, queries[batch:batch+100])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matched {
+			falsePositives++
+		}
+	}
+
+	// The expected rate is 1 in gcsM, so a handful of the queries may match
+	if falsePositives > 5 {
+		t.Errorf("%d batches of non members match, expected a rate of 1/%d", falsePositives, gcsM)
+	}
+
+	empty := NewGCSFilter(key, nil)
+
+	matched, err := empty.MatchAny(key, queries[:10])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched {
+		t.Error("an empty filter matches")
+	}
+}
+
+func TestBlockFilterMatchesBlockItems(t *testing.T) {
+	cfg := testConfig()
+	wallet, address := testWallet(t, cfg)
+	_, otherAddress := testWallet(t, cfg)
+	_, unrelatedAddress := testWallet(t, cfg)
+
+	genesisCoinbase := testCoinbase(t, cfg, address)
+	genesis := mineTestBlock(t, cfg, BlockVersion, []*Transaction{genesisCoinbase}, []byte{})
+
+	payment := testPayment(t, cfg, wallet, genesisCoinbase, otherAddress, 10)
+	payment.OutputValue = append(payment.OutputValue, TXOutput{Data: []byte("anchored data")})
+	block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{testCoinbase(t, cfg, address), payment}, genesis.Hash)
+
+	filter := NewBlockFilter(block)
+
+	tests := []struct {
+		name     string
+		item     []byte
+		expected bool
+	}{
+		{name: "paid address", item: DecodeAddress(cfg, otherAddress), expected: true},
+		{name: "coinbase address", item: DecodeAddress(cfg, address), expected: true},
+		{name: "spent outpoint", item: FilterOutpoint(genesisCoinbase.ID, 0), expected: true},
+		{name: "data", item: []byte("anchored data"), expected: true},
+		{name: "unrelated address", item: DecodeAddress(cfg, unrelatedAddress), expected: false},
+		{name: "unspent outpoint", item: FilterOutpoint(genesisCoinbase.ID, 1), expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, err := filter.Match(block.Hash, test.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if matched != test.expected {
+				t.Errorf("matched %t, expected %t", matched, test.expected)
+			}
+		})
+	}
+
+	if !VerifyBlockFilter(block, filter.Serialize()) {
+		t.Error("filter of the block does not verify")
+	}
+
+	if VerifyBlockFilter(genesis, filter.Serialize()) {
+		t.Error("filter of the block verifies against another block")
+	}
+}
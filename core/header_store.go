@@ -0,0 +1,531 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"math/big"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HeaderStore stores the block headers of a light client, with the filters of their blocks
+type HeaderStore struct {
+	cfg   *model.Config
+	Tip   []byte
+	Store ChainStore
+	// PowAlgorithm is the proof of work algorithm the headers are checked with, recorded when the store was created
+	PowAlgorithm string
+}
+
+// OpenHeaderStore opens the header store file, creating it when it does not exist yet
+func OpenHeaderStore(cfg *model.Config) (*HeaderStore, error) {
+	store, err := openBoltChainStore(cfg, cfg.LightClientConfig.DbName, &bolt.Options{Timeout: cfg.DatabaseConfig.LockTimeout})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	headerStore, err := NewHeaderStore(cfg, store)
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	return headerStore, nil
+}
+
+// NewHeaderStore returns the header store kept in the given chain store. A new store records the proof of work
+// algorithm of the network, which its headers are checked with from then on
+func NewHeaderStore(cfg *model.Config, store ChainStore) (*HeaderStore, error) {
+	var tip []byte
+	var algorithm []byte
+
+	err := store.View(func(tx ChainTx) error {
+		tip = tx.Record(cfg.DatabaseConfig.HeadersBucket, tipKey)
+		algorithm = tx.Record(cfg.DatabaseConfig.MetaBucket, powAlgorithmKey)
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if algorithm == nil {
+		newAlgorithm, err := NewChainPowAlgorithm(cfg)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		err = store.Update(func(tx ChainTx) error {
+			return putPowAlgorithm(cfg, tx, newAlgorithm)
+		})
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		algorithm = []byte(newAlgorithm)
+	}
+
+	headerStore := HeaderStore{cfg: cfg, Tip: tip, Store: store, PowAlgorithm: string(algorithm)}
+
+	return &headerStore, nil
+}
+
+// Close closes the store of the headers
+func (hs *HeaderStore) Close() error {
+	return hs.Store.Close()
+}
+
+// Height returns the height of the tip, or -1 when no header is stored
+func (hs *HeaderStore) Height() (*int, error) {
+	height := -1
+
+	if hs.Tip == nil {
+		return &height, nil
+	}
+
+	tipHeight, err := hs.GetHeight(hs.Tip)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return tipHeight, nil
+}
+
+// AddHeader checks that the header extends the tip with a valid proof of work and stores it as the new tip
+func (hs *HeaderStore) AddHeader(header *BlockHeader) error {
+	err := hs.Store.Update(func(tx ChainTx) error {
+		return hs.addHeader(tx, header)
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	hs.Tip = header.Hash
+
+	return nil
+}
+
+// SwitchBranch replaces the headers above the ancestor with the headers of another branch when that branch has more
+// work, and reports whether it did. The headers must extend the ancestor, which is nil for a branch starting at a
+// different genesis block. The stored headers are left as they are when the branch is invalid or has no more work
+func (hs *HeaderStore) SwitchBranch(ancestor []byte, headers []*BlockHeader) (bool, error) {
+	switched := false
+
+	err := hs.Store.Update(func(tx ChainTx) error {
+		ancestorHeight := -1
+		ancestorVersion := 0
+
+		if ancestor != nil {
+			header, err := hs.header(tx, ancestor)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			height, err := tx.BlockHeight(ancestor)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			ancestorHeight = *height
+			ancestorVersion = header.Version
+		}
+
+		branchWork, err := hs.checkBranch(ancestor, ancestorVersion, headers)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		storedWork, err := hs.workAbove(tx, ancestorHeight)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if branchWork.Cmp(storedWork) <= 0 {
+			return nil
+		}
+
+		err = hs.removeAbove(tx, ancestor)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, header := range headers {
+			err = hs.addHeader(tx, header)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		switched = true
+
+		return nil
+	})
+	if err != nil {
+		return false, utils.CatchErr(err)
+	}
+
+	if switched {
+		hs.Tip = headers[len(headers)-1].Hash
+	}
+
+	return switched, nil
+}
+
+// checkBranch checks that the headers link up from the ancestor with valid versions and proofs of work, and returns their work
+func (hs *HeaderStore) checkBranch(ancestor []byte, ancestorVersion int, headers []*BlockHeader) (*big.Int, error) {
+	work := big.NewInt(0)
+	prevHash := ancestor
+	parentVersion := ancestorVersion
+
+	for _, header := range headers {
+		if !bytes.Equal(header.PrevBlockHash, prevHash) {
+			return nil, fmt.Errorf("header %x of the branch does not extend %x", header.Hash, prevHash)
+		}
+
+		err := hs.checkHeader(header, parentVersion)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		work.Add(work, NewHeaderProofOfWork(hs.cfg, hs.PowAlgorithm, header).Work())
+		prevHash = header.Hash
+		parentVersion = header.Version
+	}
+
+	return work, nil
+}
+
+// workAbove returns the work of the stored headers above the given height
+func (hs *HeaderStore) workAbove(tx ChainTx, height int) (*big.Int, error) {
+	work := big.NewInt(0)
+	hash := tx.Record(hs.cfg.DatabaseConfig.HeadersBucket, tipKey)
+
+	for len(hash) != 0 {
+		headerHeight, err := tx.BlockHeight(hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if *headerHeight <= height {
+			break
+		}
+
+		header, err := hs.header(tx, hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		work.Add(work, NewHeaderProofOfWork(hs.cfg, hs.PowAlgorithm, header).Work())
+		hash = header.PrevBlockHash
+	}
+
+	return work, nil
+}
+
+// checkHeader checks the version and proof of work of a header
+func (hs *HeaderStore) checkHeader(header *BlockHeader, parentVersion int) error {
+	err := checkBlockVersion(header.Version, parentVersion)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	isValid, err := NewHeaderProofOfWork(hs.cfg, hs.PowAlgorithm, header).Validate()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		return fmt.Errorf("header %x has an invalid proof of work", header.Hash)
+	}
+
+	return nil
+}
+
+// addHeader checks that the header extends the stored tip and stores it as the new tip within the transaction
+func (hs *HeaderStore) addHeader(tx ChainTx, header *BlockHeader) error {
+	headersBucket := hs.cfg.DatabaseConfig.HeadersBucket
+
+	tip := tx.Record(headersBucket, tipKey)
+	if !bytes.Equal(header.PrevBlockHash, tip) {
+		return fmt.Errorf("header %x does not extend the tip %x", header.Hash, tip)
+	}
+
+	height := 0
+	parentVersion := 0
+
+	if tip != nil {
+		parent, err := hs.header(tx, tip)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		tipHeight, err := tx.BlockHeight(tip)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height = *tipHeight + 1
+		parentVersion = parent.Version
+	}
+
+	err := hs.checkHeader(header, parentVersion)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	serializedHeader, err := header.Serialize()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = tx.PutRecord(headersBucket, header.Hash, serializedHeader)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = tx.PutRecord(headersBucket, tipKey, header.Hash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return tx.PutBlockIndex(header.Hash, height)
+}
+
+// removeAbove disconnects the headers above the ancestor with their filters and index entries, making the ancestor the
+// new tip. A nil ancestor removes every header
+func (hs *HeaderStore) removeAbove(tx ChainTx, ancestor []byte) error {
+	headersBucket := hs.cfg.DatabaseConfig.HeadersBucket
+	filtersBucket := hs.cfg.DatabaseConfig.FiltersBucket
+	indexBucket := hs.cfg.DatabaseConfig.IndexBucket
+
+	hash := tx.Record(headersBucket, tipKey)
+
+	for len(hash) != 0 && !bytes.Equal(hash, ancestor) {
+		header, err := hs.header(tx, hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		height, err := tx.BlockHeight(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		records := []struct {
+			bucket string
+			key    []byte
+		}{
+			{bucket: headersBucket, key: hash},
+			{bucket: filtersBucket, key: hash},
+			{bucket: indexBucket, key: hash},
+			{bucket: indexBucket, key: heightKey(*height)},
+		}
+
+		for _, record := range records {
+			err = tx.DeleteRecord(record.bucket, record.key)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		hash = header.PrevBlockHash
+	}
+
+	// The filter tip moves back to the ancestor when its header was removed
+	filterTip := tx.Record(filtersBucket, tipKey)
+	if filterTip != nil {
+		_, err := tx.BlockHeight(filterTip)
+		if errors.Is(err, ErrBlockNotFound) {
+			err = putTipRecord(tx, filtersBucket, ancestor)
+		}
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	return putTipRecord(tx, headersBucket, ancestor)
+}
+
+// putTipRecord records the tip of a bucket, deleting the record when there is no tip
+func putTipRecord(tx ChainTx, bucket string, tip []byte) error {
+	if tip == nil {
+		return tx.DeleteRecord(bucket, tipKey)
+	}
+
+	return tx.PutRecord(bucket, tipKey, tip)
+}
+
+// header returns the stored header with the given hash within the transaction
+func (hs *HeaderStore) header(tx ChainTx, hash []byte) (*BlockHeader, error) {
+	encodedHeader := tx.Record(hs.cfg.DatabaseConfig.HeadersBucket, hash)
+	if encodedHeader == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	header, err := DeserializeBlockHeader(encodedHeader)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return header, nil
+}
+
+// GetHeader returns the header with the given hash
+func (hs *HeaderStore) GetHeader(hash []byte) (*BlockHeader, error) {
+	var header *BlockHeader
+
+	err := hs.Store.View(func(tx ChainTx) error {
+		storedHeader, err := hs.header(tx, hash)
+		header = storedHeader
+
+		return err
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return header, nil
+}
+
+// GetHeight returns the height of the header with the given hash
+func (hs *HeaderStore) GetHeight(hash []byte) (*int, error) {
+	var height *int
+
+	err := hs.Store.View(func(tx ChainTx) error {
+		headerHeight, err := tx.BlockHeight(hash)
+		height = headerHeight
+
+		return err
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return height, nil
+}
+
+// GetHash returns the hash of the header at the given height
+func (hs *HeaderStore) GetHash(height int) ([]byte, error) {
+	var hash []byte
+
+	err := hs.Store.View(func(tx ChainTx) error {
+		headerHash, err := tx.BlockHash(height)
+		hash = headerHash
+
+		return err
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return hash, nil
+}
+
+// FilterHeight returns the height of the last header whose filter is stored, or -1 when no filter is stored
+func (hs *HeaderStore) FilterHeight() (*int, error) {
+	var filterTip []byte
+
+	err := hs.Store.View(func(tx ChainTx) error {
+		filterTip = tx.Record(hs.cfg.DatabaseConfig.FiltersBucket, tipKey)
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if filterTip == nil {
+		height := -1
+		return &height, nil
+	}
+
+	height, err := hs.GetHeight(filterTip)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return height, nil
+}
+
+// AddFilter checks that the filter extends the filter header chain and stores it for the header with the given hash
+func (hs *HeaderStore) AddFilter(hash []byte, blockFilter *BlockFilter) error {
+	filtersBucket := hs.cfg.DatabaseConfig.FiltersBucket
+
+	return hs.Store.Update(func(tx ChainTx) error {
+		header, err := hs.header(tx, hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if !bytes.Equal(tx.Record(filtersBucket, tipKey), header.PrevBlockHash) {
+			return fmt.Errorf("filter of %x does not extend the filter tip", hash)
+		}
+
+		var prevHeader []byte
+
+		if len(header.PrevBlockHash) != 0 {
+			prevFilter, err := tx.Filter(header.PrevBlockHash)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			prevHeader = prevFilter.Header
+		}
+
+		if !bytes.Equal(FilterHeader(blockFilter.Filter, prevHeader), blockFilter.Header) {
+			return fmt.Errorf("filter of %x does not match its filter header", hash)
+		}
+
+		err = tx.PutFilter(hash, blockFilter)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		return tx.PutRecord(filtersBucket, tipKey, hash)
+	})
+}
+
+// GetFilter returns the stored filter of the header with the given hash
+func (hs *HeaderStore) GetFilter(hash []byte) (*BlockFilter, error) {
+	var blockFilter *BlockFilter
+
+	err := hs.Store.View(func(tx ChainTx) error {
+		storedFilter, err := tx.Filter(hash)
+		blockFilter = storedFilter
+
+		return err
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return blockFilter, nil
+}
+
+// Serialize serializes the block header
+func (h *BlockHeader) Serialize() ([]byte, error) {
+	var result bytes.Buffer
+
+	err := gob.NewEncoder(&result).Encode(h)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return result.Bytes(), nil
+}
+
+// DeserializeBlockHeader deserializes a block header
+func DeserializeBlockHeader(d []byte) (*BlockHeader, error) {
+	var header BlockHeader
+
+	err := gob.NewDecoder(bytes.NewReader(d)).Decode(&header)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &header, nil
+}
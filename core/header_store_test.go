@@ -0,0 +1,252 @@
+package core
+
+import (
+	"bytes"
+	"go-burrokuchen/model"
+	"testing"
+)
+
+// testBranch mines count blocks on top of prevBlockHash, each with a coinbase paying a new address, and returns them
+func testBranch(t *testing.T, cfg *model.Config, prevBlockHash []byte, count int) []*Block {
+	t.Helper()
+
+	var blocks []*Block
+
+	for i := 0; i < count; i++ {
+		_, address := testWallet(t, cfg)
+
+		block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{testCoinbase(t, cfg, address)}, prevBlockHash)
+		blocks = append(blocks, block)
+		prevBlockHash = block.Hash
+	}
+
+	return blocks
+}
+
+// testHeaders returns the headers of the blocks
+func testHeaders(t *testing.T, blocks []*Block) []*BlockHeader {
+	t.Helper()
+
+	var headers []*BlockHeader
+
+	for _, block := range blocks {
+		header, err := block.Header()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers
+}
+
+// newTestHeaderStore returns a header store in memory holding the headers and filters of the blocks
+func newTestHeaderStore(t *testing.T, cfg *model.Config, blocks []*Block) *HeaderStore {
+	t.Helper()
+
+	hs, err := NewHeaderStore(cfg, NewMemoryChainStore(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prevHeader []byte
+
+	for i, header := range testHeaders(t, blocks) {
+		err = hs.AddHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		filter := NewBlockFilter(blocks[i]).Serialize()
+		blockFilter := &BlockFilter{Filter: filter, Header: FilterHeader(filter, prevHeader)}
+		prevHeader = blockFilter.Header
+
+		err = hs.AddFilter(header.Hash, blockFilter)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return hs
+}
+
+// checkHeaderChain checks that the store indexes exactly the blocks, in order, with the last one as its tip
+func checkHeaderChain(t *testing.T, hs *HeaderStore, blocks []*Block) {
+	t.Helper()
+
+	height, err := hs.Height()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *height != len(blocks)-1 {
+		t.Fatalf("header height is %d, expected %d", *height, len(blocks)-1)
+	}
+
+	if !bytes.Equal(hs.Tip, blocks[len(blocks)-1].Hash) {
+		t.Errorf("header tip is %x, expected %x", hs.Tip, blocks[len(blocks)-1].Hash)
+	}
+
+	for i, block := range blocks {
+		hash, err := hs.GetHash(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(hash, block.Hash) {
+			t.Errorf("height %d is indexed as %x, expected %x", i, hash, block.Hash)
+		}
+
+		blockHeight, err := hs.GetHeight(block.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *blockHeight != i {
+			t.Errorf("block %x is at height %d, expected %d", block.Hash, *blockHeight, i)
+		}
+	}
+}
+
+func TestHeaderStoreAddHeader(t *testing.T) {
+	cfg := testConfig()
+	blocks := testBranch(t, cfg, []byte{}, 3)
+	hs := newTestHeaderStore(t, cfg, blocks)
+
+	checkHeaderChain(t, hs, blocks)
+
+	filterHeight, err := hs.FilterHeight()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *filterHeight != 2 {
+		t.Errorf("filter height is %d, expected 2", *filterHeight)
+	}
+
+	// Headers which do not extend the tip are refused
+	fork := testBranch(t, cfg, blocks[0].Hash, 1)
+
+	err = hs.AddHeader(testHeaders(t, fork)[0])
+	if err == nil {
+		t.Error("stored a header which does not extend the tip")
+	}
+
+	checkHeaderChain(t, hs, blocks)
+}
+
+func TestHeaderStoreSwitchBranch(t *testing.T) {
+	cfg := testConfig()
+	blocks := testBranch(t, cfg, []byte{}, 3)
+
+	tests := []struct {
+		name     string
+		length   int
+		switched bool
+	}{
+		{name: "shorter branch", length: 1, switched: false},
+		{name: "branch with the same work", length: 2, switched: false},
+		{name: "branch with more work", length: 3, switched: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hs := newTestHeaderStore(t, cfg, blocks)
+			fork := testBranch(t, cfg, blocks[0].Hash, test.length)
+
+			switched, err := hs.SwitchBranch(blocks[0].Hash, testHeaders(t, fork))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if switched != test.switched {
+				t.Fatalf("switched is %t, expected %t", switched, test.switched)
+			}
+
+			if !test.switched {
+				checkHeaderChain(t, hs, blocks)
+				return
+			}
+
+			checkHeaderChain(t, hs, append([]*Block{blocks[0]}, fork...))
+
+			// The filters of the disconnected headers are dropped along with them
+			filterHeight, err := hs.FilterHeight()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *filterHeight != 0 {
+				t.Errorf("filter height is %d, expected 0", *filterHeight)
+			}
+
+			for _, block := range blocks[1:] {
+				_, err = hs.GetHeader(block.Hash)
+				if err == nil {
+					t.Errorf("disconnected header %x is still stored", block.Hash)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderStoreSwitchBranchFromGenesis(t *testing.T) {
+	cfg := testConfig()
+	blocks := testBranch(t, cfg, []byte{}, 2)
+	hs := newTestHeaderStore(t, cfg, blocks)
+
+	fork := testBranch(t, cfg, []byte{}, 3)
+
+	switched, err := hs.SwitchBranch(nil, testHeaders(t, fork))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !switched {
+		t.Fatal("kept a chain with less work than one from another genesis block")
+	}
+
+	checkHeaderChain(t, hs, fork)
+
+	filterHeight, err := hs.FilterHeight()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *filterHeight != -1 {
+		t.Errorf("filter height is %d, expected -1", *filterHeight)
+	}
+}
+
+func TestHeaderStoreSwitchBranchRefusesInvalidBranch(t *testing.T) {
+	cfg := testConfig()
+	blocks := testBranch(t, cfg, []byte{}, 2)
+
+	unlinked := testBranch(t, cfg, blocks[0].Hash, 3)
+	unlinked[1] = testBranch(t, cfg, blocks[0].Hash, 1)[0]
+
+	invalidWork := testBranch(t, cfg, blocks[0].Hash, 3)
+	invalidWork[2].Nonce++
+
+	tests := []struct {
+		name   string
+		blocks []*Block
+	}{
+		{name: "headers which do not link up", blocks: unlinked},
+		{name: "header with an invalid proof of work", blocks: invalidWork},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hs := newTestHeaderStore(t, cfg, blocks)
+
+			switched, err := hs.SwitchBranch(blocks[0].Hash, testHeaders(t, test.blocks))
+			if err == nil {
+				t.Fatalf("switched is %t without an error", switched)
+			}
+
+			checkHeaderChain(t, hs, blocks)
+		})
+	}
+}
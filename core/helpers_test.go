@@ -0,0 +1,111 @@
+package core
+
+import (
+	"encoding/hex"
+	"go-burrokuchen/model"
+	"testing"
+	"time"
+)
+
+// testConfig returns a configuration with an easy proof of work, so that tests mine blocks quickly
+func testConfig() *model.Config {
+	return &model.Config{
+		DatabaseConfig: model.DatabaseConfig{
+			BlocksBucket:          "blocks",
+			UTXOSetBucket:         "utxo_set",
+			IndexBucket:           "index",
+			WebhookBucket:         "webhooks",
+			WebhookDeliveryBucket: "webhook_deliveries",
+			HeadersBucket:         "headers",
+			FiltersBucket:         "filters",
+			MempoolBucket:         "mempool",
+			SnapshotBucket:        "snapshot",
+			MetaBucket:            "meta",
+			AutoMigrate:           true,
+		},
+		ProofOfWorkConfig: model.ProofOfWorkConfig{TargetBits: 4, Algorithm: PowSHA256},
+		TransactionConfig: model.TransactionConfig{Subsidy: 10, GenesisCoinbaseData: "genesis", MaxDataSize: 80},
+		WalletConfig:      model.WalletConfig{CheckSumLength: 4},
+		MempoolConfig:     model.MempoolConfig{MaxAge: time.Hour, MaxSize: 1000000, MaxBlockSize: 100000},
+	}
+}
+
+// testWallet returns a new wallet and its address
+func testWallet(t *testing.T, cfg *model.Config) (*Wallet, string) {
+	t.Helper()
+
+	wallet, err := NewWallet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := wallet.GetAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return wallet, string(address)
+}
+
+// mineTestBlock mines a block of the given version on top of prevBlockHash
+func mineTestBlock(t *testing.T, cfg *model.Config, version int, transactions []*Transaction, prevBlockHash []byte) *Block {
+	t.Helper()
+
+	block := &Block{
+		Version:       version,
+		Timestamp:     time.Now().Unix(),
+		Transactions:  transactions,
+		PrevBlockHash: prevBlockHash,
+	}
+
+	pow, err := NewProofOfWork(cfg, cfg.ProofOfWorkConfig.Algorithm, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, hash, err := pow.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block.Hash = hash
+	block.Nonce = *nonce
+
+	return block
+}
+
+// testCoinbase returns a coinbase transaction paying the subsidy to the address
+func testCoinbase(t *testing.T, cfg *model.Config, address string) *Transaction {
+	t.Helper()
+
+	coinbase, err := NewCoinbaseTX(cfg, address, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coinbase
+}
+
+// testPayment returns a transaction spending the first output of prevTX, owned by the wallet, to the address. Like the
+// transactions of version 0, its ID is the hash of the transaction before it was signed
+func testPayment(t *testing.T, cfg *model.Config, wallet *Wallet, prevTX *Transaction, address string, value int) *Transaction {
+	t.Helper()
+
+	tx := Transaction{
+		InputValue:  []TXInput{{TransactionID: prevTX.ID, OutputIndex: 0, PubKey: wallet.PublicKey}},
+		OutputValue: []TXOutput{{Value: value, PubKeyHash: DecodeAddress(cfg, address)}},
+	}
+
+	hash, err := tx.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.ID = hash
+
+	err = tx.Sign(wallet.PrivateKey, map[string]Transaction{hex.EncodeToString(prevTX.ID): *prevTX})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tx
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"go-burrokuchen/utils"
+)
+
+// HistoryEntry is the effect of a transaction on the tracked addresses
+type HistoryEntry struct {
+	TransactionID []byte
+	BlockHash     []byte
+	Height        int
+	Timestamp     int64
+	Received      int
+	Sent          int
+}
+
+// AddressTracker follows the outputs of a set of public key hashes through blocks given in ascending height
+type AddressTracker struct {
+	pubKeyHashes [][]byte
+	transactions map[string]*Transaction
+	heights      map[string]int
+	UTXOs        []UTXO
+	History      []HistoryEntry
+	// PrunedHeight is the height of the last pruned block whose transactions are missing from the history, or -1
+	PrunedHeight int
+}
+
+// NewAddressTracker generates and returns a tracker of the given public key hashes
+func NewAddressTracker(pubKeyHashes [][]byte) *AddressTracker {
+	return &AddressTracker{pubKeyHashes: pubKeyHashes, transactions: make(map[string]*Transaction), heights: make(map[string]int), PrunedHeight: -1}
+}
+
+// FilterItems returns the block filter items that match blocks touching the tracked addresses
+func (t *AddressTracker) FilterItems() [][]byte {
+	items := append([][]byte{}, t.pubKeyHashes...)
+
+	for _, utxo := range t.UTXOs {
+		items = append(items, FilterOutpoint(utxo.TransactionID, utxo.OutputIndex))
+	}
+
+	return items
+}
+
+// Transaction returns the transaction that created a tracked unspent output
+func (t *AddressTracker) Transaction(transactionID []byte) *Transaction {
+	return t.transactions[hex.EncodeToString(transactionID)]
+}
+
+// Height returns the height of the block that confirmed a tracked unspent output
+func (t *AddressTracker) Height(transactionID []byte) int {
+	return t.heights[hex.EncodeToString(transactionID)]
+}
+
+// Apply spends the tracked outputs consumed by the block, tracks the outputs it pays to the addresses and records the history
+func (t *AddressTracker) Apply(block *Block, height int) {
+	for _, tx := range block.Transactions {
+		entry := HistoryEntry{TransactionID: tx.ID, BlockHash: block.Hash, Height: height, Timestamp: block.Timestamp}
+
+		if !tx.IsCoinbase() {
+			for _, in := range tx.InputValue {
+				for i, utxo := range t.UTXOs {
+					if bytes.Equal(utxo.TransactionID, in.TransactionID) && utxo.OutputIndex == in.OutputIndex {
+						entry.Sent += utxo.Output.Value
+						t.UTXOs = append(t.UTXOs[:i], t.UTXOs[i+1:]...)
+
+						break
+					}
+				}
+			}
+		}
+
+		for outIndex, out := range tx.OutputValue {
+			for _, pubKeyHash := range t.pubKeyHashes {
+				if out.IsLockedWithKey(pubKeyHash) {
+					entry.Received += out.Value
+					t.UTXOs = append(t.UTXOs, UTXO{TransactionID: tx.ID, OutputIndex: outIndex, Output: out})
+					t.transactions[hex.EncodeToString(tx.ID)] = tx
+					t.heights[hex.EncodeToString(tx.ID)] = height
+
+					break
+				}
+			}
+		}
+
+		if entry.Received != 0 || entry.Sent != 0 {
+			t.History = append(t.History, entry)
+		}
+	}
+}
+
+// TrackAddresses applies every block of the main chain to a tracker of the given public key hashes. The pruned blocks
+// are skipped, the outputs they paid to the addresses that are still unspent are taken from the UTXO set instead
+func (bc *Blockchain) TrackAddresses(pubKeyHashes [][]byte) (*AddressTracker, error) {
+	tracker := NewAddressTracker(pubKeyHashes)
+
+	bestHeight, err := bc.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	pruneHeight, err := bc.PruneHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tracker.PrunedHeight = *pruneHeight
+
+	for height := *pruneHeight + 1; height <= *bestHeight; height++ {
+		hash, err := bc.GetBlockHash(height)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		block, err := bc.GetBlock(hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		tracker.Apply(block, height)
+	}
+
+	if *pruneHeight < 0 {
+		return tracker, nil
+	}
+
+	// Every unspent output paid by the applied blocks is tracked, the others of the UTXO set were paid by pruned blocks
+	var prunedUTXOs []UTXO
+
+	utxoSet := NewUTXOSet(bc.cfg, bc)
+
+	for _, pubKeyHash := range pubKeyHashes {
+		UTXOs, err := utxoSet.FindUTXOsByPubKeyHash(pubKeyHash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		for _, utxo := range UTXOs {
+			if !containsUTXO(tracker.UTXOs, utxo) && !containsUTXO(prunedUTXOs, utxo) {
+				prunedUTXOs = append(prunedUTXOs, utxo)
+			}
+		}
+	}
+
+	tracker.UTXOs = append(prunedUTXOs, tracker.UTXOs...)
+
+	return tracker, nil
+}
+
+// containsUTXO checks whether the list holds the unspent output
+func containsUTXO(UTXOs []UTXO, utxo UTXO) bool {
+	for _, listed := range UTXOs {
+		if bytes.Equal(listed.TransactionID, utxo.TransactionID) && listed.OutputIndex == utxo.OutputIndex {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+// SwapSecretSize is the only accepted size of contract secrets. Both chains of a swap enforce it, so a secret
+// redeemable on one chain is always redeemable on the other.
+const SwapSecretSize = 32
+
+// HTLC is a hash time-locked contract. Its output can be spent by the recipient with the secret hashing to
+// SecretHash, or by the refund key once the transaction lock time reaches Timeout.
+type HTLC struct {
+	SecretHash          []byte
+	RecipientPubKeyHash []byte
+	RefundPubKeyHash    []byte
+	Timeout             int64
+}
+
+// NewSwapSecret generates and returns a random contract secret and its hash
+func NewSwapSecret() ([]byte, []byte, error) {
+	secret := make([]byte, SwapSecretSize)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	secretHash := sha256.Sum256(secret)
+
+	return secret, secretHash[:], nil
+}
+
+// NewHTLCOutput creates an output locked by a contract between the recipient and refund addresses
+func NewHTLCOutput(cfg *model.Config, value int, secretHash []byte, recipient string, refund string, timeout int64) (*TXOutput, error) {
+	if len(secretHash) != sha256.Size {
+		return nil, fmt.Errorf("secret hash must be %d bytes", sha256.Size)
+	}
+
+	if timeout <= 0 {
+		return nil, fmt.Errorf("contract timeout must be positive")
+	}
+
+	htlc := HTLC{
+		SecretHash:          secretHash,
+		RecipientPubKeyHash: DecodeAddress(cfg, recipient),
+		RefundPubKeyHash:    DecodeAddress(cfg, refund),
+		Timeout:             timeout,
+	}
+
+	return &TXOutput{cfg: cfg, Value: value, HTLC: &htlc}, nil
+}
+
+// IsUnlockedBy checks whether the input redeems the contract with the secret and the recipient key,
+// or refunds it with the refund key in a transaction locked until the timeout
+func (h *HTLC) IsUnlockedBy(in TXInput, lockTime int64) (*bool, error) {
+	result := false
+
+	if len(in.Secret) != 0 {
+		secretHash := sha256.Sum256(in.Secret)
+		if len(in.Secret) != SwapSecretSize || !bytes.Equal(secretHash[:], h.SecretHash) {
+			return &result, nil
+		}
+
+		return in.UsesKey(h.RecipientPubKeyHash)
+	}
+
+	// Heights and timestamps cannot be compared, so the refund lock time has to be of the same kind as the timeout
+	if (lockTime < LockTimeThreshold) != (h.Timeout < LockTimeThreshold) || lockTime < h.Timeout {
+		return &result, nil
+	}
+
+	return in.UsesKey(h.RefundPubKeyHash)
+}
+
+// NewContractTransaction generates and returns a transaction locking the amount in a contract refundable to the sender
+func NewContractTransaction(utxoSet UTXOSet, from string, to string, secretHash []byte, timeout int64, amount int) (*Transaction, error) {
+	wallets, err := NewWallets(utxoSet.cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	wallet, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	pubKeyHash, err := HashPubKey(wallet.PublicKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	balance, validOutputs, err := utxoSet.FindSpendableOutputs(pubKeyHash, amount)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if *balance < amount {
+		err := fmt.Errorf("%s doesn't have enough funds", from)
+
+		return nil, utils.CatchErr(err)
+	}
+
+	inputs, err := newInputs(wallet.PublicKey, validOutputs)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	output, err := NewHTLCOutput(utxoSet.cfg, amount, secretHash, to, from, timeout)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tx, err := newTransactionWithOutput(utxoSet.cfg, inputs, *output, from, *balance, 0)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = utxoSet.Blockchain.SignTransaction(tx, wallet.PrivateKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return tx, nil
+}
+
+// NewContractSpendTransaction generates and returns a transaction paying a contract output to the address of the wallet.
+// With a secret the recipient redeems the contract, without one the sender refunds it once the timeout is reached.
+func NewContractSpendTransaction(cfg *model.Config, contractTX *Transaction, outIndex int, wallet *Wallet, secret []byte) (*Transaction, error) {
+	if outIndex < 0 || outIndex >= len(contractTX.OutputValue) || contractTX.OutputValue[outIndex].HTLC == nil {
+		return nil, fmt.Errorf("output %x:%d is not a contract", contractTX.ID, outIndex)
+	}
+
+	contract := contractTX.OutputValue[outIndex]
+
+	address, err := wallet.GetAddress()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	output, err := NewTXOutput(cfg, contract.Value, string(address))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var lockTime int64
+	if secret == nil {
+		lockTime = contract.HTLC.Timeout
+	}
+
+	input := TXInput{TransactionID: contractTX.ID, OutputIndex: outIndex, PubKey: wallet.PublicKey, Secret: secret}
+
+	tx, err := newTransactionWithOutput(cfg, []TXInput{input}, *output, "", contract.Value, lockTime)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = tx.Sign(wallet.PrivateKey, map[string]Transaction{hex.EncodeToString(contractTX.ID): *contractTX})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	isUnlocked, err := contract.IsUnlockedBy(tx.InputValue[0], tx.LockTime)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !*isUnlocked {
+		return nil, fmt.Errorf("wallet %s cannot spend contract %x:%d with the given secret", address, contractTX.ID, outIndex)
+	}
+
+	return tx, nil
+}
+
+// ExtractSecret returns the secret revealed by a transaction redeeming the contract output, or nil if it does not redeem it
+func ExtractSecret(tx *Transaction, contractID []byte, outIndex int) []byte {
+	for _, in := range tx.InputValue {
+		if bytes.Equal(in.TransactionID, contractID) && in.OutputIndex == outIndex {
+			return in.Secret
+		}
+	}
+
+	return nil
+}
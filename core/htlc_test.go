@@ -0,0 +1,257 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"go-burrokuchen/model"
+	"testing"
+)
+
+// newSwapChain returns a blockchain in memory whose genesis block pays the address, together with the genesis coinbase
+func newSwapChain(t *testing.T, address string) (*Blockchain, *Transaction) {
+	t.Helper()
+
+	cfg := testConfig()
+
+	bc, err := NewBlockchainWithStore(cfg, NewMemoryChainStore(cfg), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis, err := bc.GetBlock(bc.Tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bc, genesis.Transactions[0]
+}
+
+// testContract returns a transaction locking the first output of prevTX, owned by the wallet, in a contract
+func testContract(t *testing.T, cfg *model.Config, wallet *Wallet, prevTX *Transaction, secretHash []byte, recipient string, refund string, timeout int64) *Transaction {
+	t.Helper()
+
+	output, err := NewHTLCOutput(cfg, prevTX.OutputValue[0].Value, secretHash, recipient, refund, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := TXInput{TransactionID: prevTX.ID, OutputIndex: 0, PubKey: wallet.PublicKey}
+
+	tx, err := newTransactionWithOutput(cfg, []TXInput{input}, *output, "", output.Value, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Sign(wallet.PrivateKey, map[string]Transaction{hex.EncodeToString(prevTX.ID): *prevTX})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+// mineSwapBlock mines the transactions into a new block after a coinbase paying the address
+func mineSwapBlock(t *testing.T, bc *Blockchain, address string, transactions ...*Transaction) (*Block, error) {
+	t.Helper()
+
+	return bc.MineBlock(append([]*Transaction{testCoinbase(t, bc.cfg, address)}, transactions...))
+}
+
+// testBalance returns the value of the unspent outputs of the address
+func testBalance(t *testing.T, bc *Blockchain, address string) int {
+	t.Helper()
+
+	utxoSet := NewUTXOSet(bc.cfg, bc)
+
+	err := utxoSet.Reindex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxos, err := utxoSet.FindUTXOsByPubKeyHash(DecodeAddress(bc.cfg, address))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := 0
+	for _, utxo := range utxos {
+		balance += utxo.Output.Value
+	}
+
+	return balance
+}
+
+func TestAtomicSwap(t *testing.T) {
+	cfg := testConfig()
+	alice, aliceAddress := testWallet(t, cfg)
+	bob, bobAddress := testWallet(t, cfg)
+
+	// Alice holds coins on the test chain and Bob on the partner chain
+	testChain, aliceCoins := newSwapChain(t, aliceAddress)
+	partnerChain, bobCoins := newSwapChain(t, bobAddress)
+
+	secret, secretHash, err := NewSwapSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice initiates with the longer timeout, so that Bob has time to redeem after she reveals the secret
+	initiation := testContract(t, cfg, alice, aliceCoins, secretHash, bobAddress, aliceAddress, 10)
+
+	_, err = mineSwapBlock(t, testChain, aliceAddress, initiation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	participation := testContract(t, cfg, bob, bobCoins, secretHash, aliceAddress, bobAddress, 5)
+
+	_, err = mineSwapBlock(t, partnerChain, bobAddress, participation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob cannot redeem before Alice reveals the secret
+	_, err = NewContractSpendTransaction(cfg, initiation, 0, bob, bytes.Repeat([]byte{1}, SwapSecretSize))
+	if err == nil {
+		t.Fatal("redeemed the contract with a wrong secret")
+	}
+
+	aliceRedeem, err := NewContractSpendTransaction(cfg, participation, 0, alice, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mineSwapBlock(t, partnerChain, bobAddress, aliceRedeem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob learns the secret from the redeem transaction on the partner chain
+	revealed := ExtractSecret(aliceRedeem, participation.ID, 0)
+	if !bytes.Equal(revealed, secret) {
+		t.Fatalf("extracted secret %x, expected %x", revealed, secret)
+	}
+
+	bobRedeem, err := NewContractSpendTransaction(cfg, initiation, 0, bob, revealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mineSwapBlock(t, testChain, aliceAddress, bobRedeem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance := testBalance(t, testChain, bobAddress); balance != 10 {
+		t.Errorf("Bob holds %d on the test chain, expected 10", balance)
+	}
+
+	if balance := testBalance(t, partnerChain, aliceAddress); balance != 10 {
+		t.Errorf("Alice holds %d on the partner chain, expected 10", balance)
+	}
+}
+
+func TestContractRefund(t *testing.T) {
+	cfg := testConfig()
+	alice, aliceAddress := testWallet(t, cfg)
+	bob, bobAddress := testWallet(t, cfg)
+
+	bc, aliceCoins := newSwapChain(t, aliceAddress)
+
+	_, secretHash, err := NewSwapSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract := testContract(t, cfg, alice, aliceCoins, secretHash, bobAddress, aliceAddress, 3)
+
+	_, err = mineSwapBlock(t, bc, aliceAddress, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the refund key can spend the contract without the secret
+	_, err = NewContractSpendTransaction(cfg, contract, 0, bob, nil)
+	if err == nil {
+		t.Fatal("refunded the contract with the recipient key")
+	}
+
+	refund, err := NewContractSpendTransaction(cfg, contract, 0, alice, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refund.LockTime != 3 {
+		t.Errorf("refund is locked until %d, expected the timeout 3", refund.LockTime)
+	}
+
+	// The refund cannot be mined before the timeout height
+	_, err = mineSwapBlock(t, bc, bobAddress, refund)
+	if !errors.Is(err, ErrTransactionLocked) {
+		t.Fatalf("mined the refund at height 2 with %v, expected %v", err, ErrTransactionLocked)
+	}
+
+	_, err = mineSwapBlock(t, bc, bobAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mineSwapBlock(t, bc, bobAddress, refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice got her coins back, next to the reward of block 1
+	if balance := testBalance(t, bc, aliceAddress); balance != 20 {
+		t.Errorf("Alice holds %d, expected 20", balance)
+	}
+}
+
+func TestHTLCIsUnlockedBy(t *testing.T) {
+	cfg := testConfig()
+	recipient, recipientAddress := testWallet(t, cfg)
+	refund, refundAddress := testWallet(t, cfg)
+
+	secret, secretHash, err := NewSwapSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := NewHTLCOutput(cfg, 10, secretHash, recipientAddress, refundAddress, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		pubKey   []byte
+		secret   []byte
+		lockTime int64
+		expected bool
+	}{
+		{name: "recipient with the secret", pubKey: recipient.PublicKey, secret: secret, expected: true},
+		{name: "recipient with a wrong secret", pubKey: recipient.PublicKey, secret: bytes.Repeat([]byte{1}, SwapSecretSize)},
+		{name: "recipient with a short secret", pubKey: recipient.PublicKey, secret: secret[:16]},
+		{name: "refund key with the secret", pubKey: refund.PublicKey, secret: secret},
+		{name: "refund at the timeout", pubKey: refund.PublicKey, lockTime: 100, expected: true},
+		{name: "refund before the timeout", pubKey: refund.PublicKey, lockTime: 99},
+		{name: "refund locked until a timestamp", pubKey: refund.PublicKey, lockTime: LockTimeThreshold + 100},
+		{name: "recipient at the timeout", pubKey: recipient.PublicKey, lockTime: 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := TXInput{TransactionID: []byte{1}, OutputIndex: 0, PubKey: test.pubKey, Secret: test.secret}
+
+			isUnlocked, err := output.IsUnlockedBy(input, test.lockTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *isUnlocked != test.expected {
+				t.Errorf("unlocked %t, expected %t", *isUnlocked, test.expected)
+			}
+		})
+	}
+}
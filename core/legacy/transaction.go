@@ -0,0 +1,51 @@
+// Package core holds the transaction types as they were before lock times, contracts and data outputs were added.
+//
+// gob writes type names and the numbers it gives types into its output, and transaction IDs, signatures and Merkle
+// roots are hashes of that output. These types keep the names, the package name and the fields of the original
+// ones, and are the first types the process encodes, so a transaction that uses none of the newer fields serializes
+// to the same bytes as it did when it was first hashed.
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+)
+
+// init encodes the transaction type before any other type, giving it and the types it contains the numbers they had
+func init() {
+	gob.NewEncoder(io.Discard).Encode(Transaction{})
+}
+
+// Transaction represents a transaction
+type Transaction struct {
+	ID          []byte
+	InputValue  []TXInput
+	OutputValue []TXOutput
+}
+
+// TXInput represents a transaction input
+type TXInput struct {
+	TransactionID []byte
+	OutputIndex   int
+	Signature     []byte
+	PubKey        []byte
+}
+
+// TXOutput represents a transaction output
+type TXOutput struct {
+	Value      int
+	PubKeyHash []byte
+}
+
+// Serialize returns a serialized Transaction
+func (tx Transaction) Serialize() ([]byte, error) {
+	var encoded bytes.Buffer
+
+	err := gob.NewEncoder(&encoded).Encode(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoded.Bytes(), nil
+}
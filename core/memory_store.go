@@ -0,0 +1,173 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"go-burrokuchen/model"
+	"slices"
+	"sync"
+)
+
+var (
+	errReadOnlyTransaction = errors.New("transaction is read-only")
+	errMemoryBackup        = errors.New("an in-memory chain store cannot be backed up")
+)
+
+// MemoryChainStore keeps the blockchain in memory, for tests and ephemeral nodes whose chain is dropped when they stop
+type MemoryChainStore struct {
+	cfg     *model.Config
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryChainStore generates and returns an empty in-memory chain store
+func NewMemoryChainStore(cfg *model.Config) *MemoryChainStore {
+	return &MemoryChainStore{cfg: cfg, buckets: make(map[string]map[string][]byte)}
+}
+
+// View runs fn in a read-only transaction
+func (s *MemoryChainStore) View(fn func(tx ChainTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(&bucketTx{cfg: s.cfg, buckets: &memoryBuckets{store: s}})
+}
+
+// Update runs fn in a read-write transaction, which is committed when fn returns nil and rolled back otherwise
+func (s *MemoryChainStore) Update(fn func(tx ChainTx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryBuckets{store: s, writable: true}
+
+	err := fn(&bucketTx{cfg: s.cfg, buckets: tx})
+	if err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return nil
+}
+
+// Backup fails, as an in-memory chain is dropped when the node stops and has no file to copy
+func (s *MemoryChainStore) Backup(path string) error {
+	return errMemoryBackup
+}
+
+// Close drops the stored chain
+func (s *MemoryChainStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buckets = make(map[string]map[string][]byte)
+
+	return nil
+}
+
+// memoryBuckets gives a transaction access to the buckets of the store, writing in place and keeping
+// the changes needed to undo its writes when it is rolled back
+type memoryBuckets struct {
+	store    *MemoryChainStore
+	writable bool
+	undo     []func()
+}
+
+func (b *memoryBuckets) get(bucket string, key []byte) []byte {
+	return bytes.Clone(b.store.buckets[bucket][string(key)])
+}
+
+func (b *memoryBuckets) put(bucket string, key []byte, value []byte) error {
+	if !b.writable {
+		return errReadOnlyTransaction
+	}
+
+	records, ok := b.store.buckets[bucket]
+	if !ok {
+		records = make(map[string][]byte)
+		b.store.buckets[bucket] = records
+		b.undo = append(b.undo, func() { delete(b.store.buckets, bucket) })
+	}
+
+	b.keepUndo(records, string(key))
+	records[string(key)] = bytes.Clone(value)
+
+	return nil
+}
+
+func (b *memoryBuckets) delete(bucket string, key []byte) error {
+	if !b.writable {
+		return errReadOnlyTransaction
+	}
+
+	records, ok := b.store.buckets[bucket]
+	if !ok {
+		return nil
+	}
+
+	b.keepUndo(records, string(key))
+	delete(records, string(key))
+
+	return nil
+}
+
+func (b *memoryBuckets) forEach(bucket string, fn func(key []byte, value []byte) error) error {
+	records := b.store.buckets[bucket]
+
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		// Records deleted by fn are skipped
+		value, ok := records[key]
+		if !ok {
+			continue
+		}
+
+		err := fn([]byte(key), bytes.Clone(value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *memoryBuckets) clear(bucket string) error {
+	if !b.writable {
+		return errReadOnlyTransaction
+	}
+
+	records, ok := b.store.buckets[bucket]
+	if !ok {
+		return nil
+	}
+
+	delete(b.store.buckets, bucket)
+	b.undo = append(b.undo, func() { b.store.buckets[bucket] = records })
+
+	return nil
+}
+
+// keepUndo remembers the value of a key before it is changed
+func (b *memoryBuckets) keepUndo(records map[string][]byte, key string) {
+	value, existed := records[key]
+
+	b.undo = append(b.undo, func() {
+		if existed {
+			records[key] = value
+		} else {
+			delete(records, key)
+		}
+	})
+}
+
+// rollback undoes the writes of the transaction, latest first
+func (b *memoryBuckets) rollback() {
+	for i := len(b.undo) - 1; i >= 0; i-- {
+		b.undo[i]()
+	}
+}
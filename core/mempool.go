@@ -0,0 +1,704 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"slices"
+	"time"
+)
+
+var (
+	ErrMempoolConflict = errors.New("transaction spends an output already spent by a pending transaction")
+	ErrMempoolFull     = errors.New("mempool is full")
+)
+
+// MempoolEntry is a transaction waiting to be mined, with the time it entered the mempool, its fee and its serialized size
+type MempoolEntry struct {
+	Transaction Transaction
+	// AddedAt is the Unix time in nanoseconds the transaction entered the mempool
+	AddedAt int64
+	Fee     int
+	Size    int
+}
+
+// FeeRate returns the fee paid per serialized byte of the transaction
+func (e *MempoolEntry) FeeRate() float64 {
+	if e.Size == 0 {
+		return 0
+	}
+
+	return float64(e.Fee) / float64(e.Size)
+}
+
+// Serialize serializes the entry
+func (e *MempoolEntry) Serialize() ([]byte, error) {
+	var encoded bytes.Buffer
+
+	enc := gob.NewEncoder(&encoded)
+	err := enc.Encode(e)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return encoded.Bytes(), nil
+}
+
+// DeserializeMempoolEntry deserializes a mempool entry
+func DeserializeMempoolEntry(data []byte) (*MempoolEntry, error) {
+	var entry MempoolEntry
+
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&entry)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &entry, nil
+}
+
+// Mempool keeps the transactions waiting to be mined in a bucket of the blockchain database, so they survive restarts
+type Mempool struct {
+	cfg        *model.Config
+	Blockchain *Blockchain
+}
+
+// NewMempool returns the mempool of a blockchain
+func NewMempool(cfg *model.Config, blockchain *Blockchain) *Mempool {
+	return &Mempool{cfg: cfg, Blockchain: blockchain}
+}
+
+// Entries returns the pending transactions in the order they entered the mempool
+func (m *Mempool) Entries() ([]MempoolEntry, error) {
+	var entries []MempoolEntry
+
+	err := m.Blockchain.Store.View(func(tx ChainTx) error {
+		var err error
+		entries, err = readMempoolEntries(tx, m.cfg.DatabaseConfig.MempoolBucket)
+
+		return err
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return entries, nil
+}
+
+// Get returns the pending transaction with the given ID
+func (m *Mempool) Get(ID []byte) (*MempoolEntry, error) {
+	entries, err := m.Entries()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	for _, entry := range entries {
+		if bytes.Equal(entry.Transaction.ID, ID) {
+			return &entry, nil
+		}
+	}
+
+	return nil, ErrTransactionNotFound
+}
+
+// Add verifies a transaction against the UTXO set and the pending transactions and adds it to the mempool.
+// A transaction spending outputs already spent by pending ones replaces them and their descendants when it pays a
+// strictly higher fee and fee rate than all of them. Expired transactions are dropped first and the lowest fee rates
+// evicted while the mempool is above its size limit, returning ErrMempoolFull when that evicts the new transaction.
+func (m *Mempool) Add(tx *Transaction) (*MempoolEntry, error) {
+	entries, err := m.Entries()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	now := time.Now()
+	pool := m.unexpired(entries, now)
+
+	replaced := replacedTransactions(tx, pool)
+	remaining := withoutTransactions(pool, replaced)
+
+	entry, err := m.check(tx, remaining, now)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = checkReplacement(entry, pool, replaced)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	entry.AddedAt = now.UnixNano()
+
+	pool, evicted := m.limitSize(append(remaining, *entry))
+
+	err = m.save(pool)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if evicted[hex.EncodeToString(tx.ID)] {
+		return nil, fmt.Errorf("%w: fee rate %.4f is too low to stay in the mempool", ErrMempoolFull, entry.FeeRate())
+	}
+
+	return entry, nil
+}
+
+// Reload verifies the pending transactions again against the current UTXO set, as at startup, dropping the
+// expired and no longer valid ones together with their descendants, and returns how many were dropped
+func (m *Mempool) Reload() (*int, error) {
+	entries, err := m.Entries()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	now := time.Now()
+
+	var pool []MempoolEntry
+
+	// Entries are checked in the order they were added, so a parent is always back in the pool before its children
+	for _, entry := range m.unexpired(entries, now) {
+		checked, err := m.check(&entry.Transaction, pool, now)
+		if err != nil {
+			if isRejection(err) {
+				continue
+			}
+
+			return nil, utils.CatchErr(err)
+		}
+
+		checked.AddedAt = entry.AddedAt
+		pool = append(pool, *checked)
+	}
+
+	pool, _ = m.limitSize(pool)
+
+	err = m.save(pool)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	dropped := len(entries) - len(pool)
+
+	return &dropped, nil
+}
+
+// BlockTransactions returns the pending transactions for a new block together with the sum of their fees.
+// Every transaction is ranked with the pending ancestors it needs as a package by their combined fee rate, so that
+// a child paying a high fee pulls its low fee parents into the block, and parents always come before their children.
+// Packages are added until no other one fits the maximum block size, the rest waits for later blocks.
+func (m *Mempool) BlockTransactions() ([]*Transaction, *int, error) {
+	entries, err := m.Entries()
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	byID := make(map[string]*MempoolEntry)
+	for i, entry := range entries {
+		byID[hex.EncodeToString(entry.Transaction.ID)] = &entries[i]
+	}
+
+	maxBlockSize := m.cfg.MempoolConfig.MaxBlockSize
+	included := make(map[string]bool)
+	var transactions []*Transaction
+	fees, blockSize := 0, 0
+
+	for len(transactions) < len(entries) {
+		var best []MempoolEntry
+		bestFee, bestSize := 0, 0
+
+		for i, entry := range entries {
+			if included[hex.EncodeToString(entry.Transaction.ID)] {
+				continue
+			}
+
+			pkg := ancestorPackage(&entries[i], byID, included)
+			fee, size := packageFeeAndSize(pkg)
+
+			if maxBlockSize > 0 && blockSize+size > maxBlockSize {
+				continue
+			}
+
+			if best == nil || fee*bestSize > bestFee*size {
+				best, bestFee, bestSize = pkg, fee, size
+			}
+		}
+
+		if best == nil {
+			break
+		}
+
+		for _, entry := range best {
+			included[hex.EncodeToString(entry.Transaction.ID)] = true
+			transactions = append(transactions, &entry.Transaction)
+			fees += entry.Fee
+		}
+		blockSize += bestSize
+	}
+
+	return transactions, &fees, nil
+}
+
+// check verifies a transaction against the UTXO set and the pending transactions of the pool and returns its entry
+func (m *Mempool) check(tx *Transaction, pool []MempoolEntry, now time.Time) (*MempoolEntry, error) {
+	if tx.IsCoinbase() {
+		return nil, fmt.Errorf("%w: coinbase transactions cannot be pending", ErrInvalidTransaction)
+	}
+
+	pending := make(map[string]Transaction)
+	spentBy := make(map[string][]byte)
+
+	for _, entry := range pool {
+		pending[hex.EncodeToString(entry.Transaction.ID)] = entry.Transaction
+
+		for _, vin := range entry.Transaction.InputValue {
+			spentBy[outpointKey(vin)] = entry.Transaction.ID
+		}
+	}
+
+	if _, ok := pending[hex.EncodeToString(tx.ID)]; ok {
+		return nil, fmt.Errorf("%w: transaction %x is already pending", ErrInvalidTransaction, tx.ID)
+	}
+
+	err := tx.CheckID()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = tx.CheckDataOutputs(m.cfg.TransactionConfig.MaxDataSize)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = tx.CheckOutputValues()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	utxoSet := NewUTXOSet(m.cfg, m.Blockchain)
+	spent := make(map[string]bool)
+
+	for _, vin := range tx.InputValue {
+		key := outpointKey(vin)
+
+		if spent[key] {
+			return nil, fmt.Errorf("%w: output %s is spent twice", ErrInvalidTransaction, key)
+		}
+		spent[key] = true
+
+		if spender, ok := spentBy[key]; ok {
+			return nil, fmt.Errorf("%w: output %s is spent by %x", ErrMempoolConflict, key, spender)
+		}
+
+		if _, isPending := pending[hex.EncodeToString(vin.TransactionID)]; isPending {
+			continue
+		}
+
+		isUnspent, err := utxoSet.IsUnspent(vin.TransactionID, vin.OutputIndex)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if !*isUnspent {
+			return nil, fmt.Errorf("%w: output %s is not unspent", ErrInvalidTransaction, key)
+		}
+	}
+
+	verified, err := m.Blockchain.verifyTransaction(tx, pending)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !*verified {
+		return nil, ErrInvalidTransaction
+	}
+
+	// Only transactions that could go into the next block are accepted
+	height, err := m.Blockchain.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = m.Blockchain.checkLocks(tx, *height+1, now.Unix(), pending)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	prevTXs, err := m.Blockchain.prevTransactions(tx, pending)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	fee := tx.Fee(prevTXs)
+	if fee < 0 {
+		return nil, fmt.Errorf("%w: outputs spend more than the inputs", ErrInvalidTransaction)
+	}
+
+	serializedTx, err := tx.Serialize()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	entry := MempoolEntry{Transaction: *tx, Fee: fee, Size: len(serializedTx)}
+
+	return &entry, nil
+}
+
+// unexpired returns the entries younger than the maximum age, dropping the descendants of expired entries with them
+func (m *Mempool) unexpired(entries []MempoolEntry, now time.Time) []MempoolEntry {
+	maxAge := m.cfg.MempoolConfig.MaxAge
+	if maxAge <= 0 {
+		return entries
+	}
+
+	var expired []string
+	for _, entry := range entries {
+		if now.Sub(time.Unix(0, entry.AddedAt)) > maxAge {
+			expired = append(expired, hex.EncodeToString(entry.Transaction.ID))
+		}
+	}
+
+	return withoutTransactions(entries, descendants(entries, expired...))
+}
+
+// limitSize evicts the entry with the lowest fee rate together with its descendants until the entries fit the
+// maximum size, and returns the remaining entries and the IDs of the evicted ones
+func (m *Mempool) limitSize(entries []MempoolEntry) ([]MempoolEntry, map[string]bool) {
+	maxSize := m.cfg.MempoolConfig.MaxSize
+	evicted := make(map[string]bool)
+
+	size := 0
+	for _, entry := range entries {
+		size += entry.Size
+	}
+
+	// Every entry is ranked together with its descendants, so a parent is kept for as long as a child pays for it
+	for maxSize > 0 && size > maxSize {
+		var lowest map[string]bool
+		lowestFee, lowestSize := 0, 0
+
+		for _, entry := range entries {
+			txID := hex.EncodeToString(entry.Transaction.ID)
+			if evicted[txID] {
+				continue
+			}
+
+			pkg := withoutTransactions(withTransactions(entries, descendants(entries, txID)), evicted)
+			fee, pkgSize := packageFeeAndSize(pkg)
+
+			// Of equal fee rates the newest entry goes first
+			if lowest == nil || fee*lowestSize <= lowestFee*pkgSize {
+				lowestFee, lowestSize = fee, pkgSize
+
+				lowest = make(map[string]bool)
+				for _, pkgEntry := range pkg {
+					lowest[hex.EncodeToString(pkgEntry.Transaction.ID)] = true
+				}
+			}
+		}
+
+		for txID := range lowest {
+			evicted[txID] = true
+		}
+		size -= lowestSize
+	}
+
+	return withoutTransactions(entries, evicted), evicted
+}
+
+// save replaces the stored entries with the given ones
+func (m *Mempool) save(entries []MempoolEntry) error {
+	mempoolBucket := m.cfg.DatabaseConfig.MempoolBucket
+
+	return m.Blockchain.Store.Update(func(tx ChainTx) error {
+		kept := make(map[string]bool)
+		for _, entry := range entries {
+			kept[string(entry.Transaction.ID)] = true
+		}
+
+		err := tx.ForEachRecord(mempoolBucket, func(k, v []byte) error {
+			if kept[string(k)] {
+				return nil
+			}
+
+			return tx.DeleteRecord(mempoolBucket, k)
+		})
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, entry := range entries {
+			serializedEntry, err := entry.Serialize()
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			err = tx.PutRecord(mempoolBucket, entry.Transaction.ID, serializedEntry)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// replacedTransactions returns the IDs of the pending transactions spending an output the transaction spends, with their descendants
+func replacedTransactions(tx *Transaction, pool []MempoolEntry) map[string]bool {
+	spent := make(map[string]bool)
+	for _, vin := range tx.InputValue {
+		spent[outpointKey(vin)] = true
+	}
+
+	var conflicts []string
+
+	for _, entry := range pool {
+		if bytes.Equal(entry.Transaction.ID, tx.ID) {
+			continue
+		}
+
+		for _, vin := range entry.Transaction.InputValue {
+			if spent[outpointKey(vin)] {
+				conflicts = append(conflicts, hex.EncodeToString(entry.Transaction.ID))
+				break
+			}
+		}
+	}
+
+	return descendants(pool, conflicts...)
+}
+
+// checkReplacement checks that the entry pays a strictly higher fee than the replaced transactions together
+// and a strictly higher fee rate than each of them
+func checkReplacement(entry *MempoolEntry, pool []MempoolEntry, replaced map[string]bool) error {
+	replacedFee := 0
+
+	for _, poolEntry := range pool {
+		if !replaced[hex.EncodeToString(poolEntry.Transaction.ID)] {
+			continue
+		}
+
+		replacedFee += poolEntry.Fee
+
+		if entry.Fee*poolEntry.Size <= poolEntry.Fee*entry.Size {
+			return fmt.Errorf("%w: fee rate %.4f does not exceed the fee rate %.4f of %x", ErrMempoolConflict, entry.FeeRate(), poolEntry.FeeRate(), poolEntry.Transaction.ID)
+		}
+	}
+
+	if len(replaced) > 0 && entry.Fee <= replacedFee {
+		return fmt.Errorf("%w: fee %d does not exceed the fee %d of the replaced transactions", ErrMempoolConflict, entry.Fee, replacedFee)
+	}
+
+	return nil
+}
+
+// removeMinedTransactions removes the transactions of a new block from the mempool, together with the pending
+// transactions that spend the same outputs and their descendants, which can no longer be mined
+func removeMinedTransactions(tx ChainTx, mempoolBucket string, block *Block) error {
+	entries, err := readMempoolEntries(tx, mempoolBucket)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	mined := make(map[string]bool)
+	spent := make(map[string]bool)
+
+	for _, transaction := range block.Transactions {
+		mined[hex.EncodeToString(transaction.ID)] = true
+
+		if transaction.IsCoinbase() {
+			continue
+		}
+
+		for _, vin := range transaction.InputValue {
+			spent[outpointKey(vin)] = true
+		}
+	}
+
+	var conflicts []string
+
+	for _, entry := range entries {
+		txID := hex.EncodeToString(entry.Transaction.ID)
+		if mined[txID] {
+			continue
+		}
+
+		for _, vin := range entry.Transaction.InputValue {
+			if spent[outpointKey(vin)] {
+				conflicts = append(conflicts, txID)
+				break
+			}
+		}
+	}
+
+	removed := descendants(entries, conflicts...)
+	for txID := range mined {
+		removed[txID] = true
+	}
+
+	for txID := range removed {
+		ID, err := hex.DecodeString(txID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.DeleteRecord(mempoolBucket, ID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	return nil
+}
+
+// pendingSpends returns the outputs spent by pending transactions, keyed like outpointKey
+func pendingSpends(tx ChainTx, mempoolBucket string) (map[string]bool, error) {
+	spent := make(map[string]bool)
+
+	entries, err := readMempoolEntries(tx, mempoolBucket)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	for _, entry := range entries {
+		for _, vin := range entry.Transaction.InputValue {
+			spent[outpointKey(vin)] = true
+		}
+	}
+
+	return spent, nil
+}
+
+// readMempoolEntries reads the entries of the mempool bucket in the order they entered the mempool
+func readMempoolEntries(tx ChainTx, mempoolBucket string) ([]MempoolEntry, error) {
+	var entries []MempoolEntry
+
+	err := tx.ForEachRecord(mempoolBucket, func(k, v []byte) error {
+		entry, err := DeserializeMempoolEntry(v)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		entries = append(entries, *entry)
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	slices.SortStableFunc(entries, func(a MempoolEntry, b MempoolEntry) int {
+		return cmp.Compare(a.AddedAt, b.AddedAt)
+	})
+
+	return entries, nil
+}
+
+// descendants returns the given transaction IDs and the IDs of the entries spending their outputs, directly or through other entries
+func descendants(entries []MempoolEntry, IDs ...string) map[string]bool {
+	result := make(map[string]bool)
+	for _, ID := range IDs {
+		result[ID] = true
+	}
+
+	for found := len(IDs) > 0; found; {
+		found = false
+
+		for _, entry := range entries {
+			txID := hex.EncodeToString(entry.Transaction.ID)
+			if result[txID] {
+				continue
+			}
+
+			for _, vin := range entry.Transaction.InputValue {
+				if result[hex.EncodeToString(vin.TransactionID)] {
+					result[txID] = true
+					found = true
+					break
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// withoutTransactions returns the entries whose transaction ID is not in the set
+func withoutTransactions(entries []MempoolEntry, IDs map[string]bool) []MempoolEntry {
+	var remaining []MempoolEntry
+
+	for _, entry := range entries {
+		if !IDs[hex.EncodeToString(entry.Transaction.ID)] {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	return remaining
+}
+
+// withTransactions returns the entries whose transaction ID is in the set
+func withTransactions(entries []MempoolEntry, IDs map[string]bool) []MempoolEntry {
+	var selected []MempoolEntry
+
+	for _, entry := range entries {
+		if IDs[hex.EncodeToString(entry.Transaction.ID)] {
+			selected = append(selected, entry)
+		}
+	}
+
+	return selected
+}
+
+// ancestorPackage returns the entry preceded by its pending ancestors that are not included yet, parents before children
+func ancestorPackage(entry *MempoolEntry, byID map[string]*MempoolEntry, included map[string]bool) []MempoolEntry {
+	var pkg []MempoolEntry
+	visited := make(map[string]bool)
+
+	var visit func(entry *MempoolEntry)
+	visit = func(entry *MempoolEntry) {
+		txID := hex.EncodeToString(entry.Transaction.ID)
+		if visited[txID] || included[txID] {
+			return
+		}
+		visited[txID] = true
+
+		for _, vin := range entry.Transaction.InputValue {
+			if parent, ok := byID[hex.EncodeToString(vin.TransactionID)]; ok {
+				visit(parent)
+			}
+		}
+
+		pkg = append(pkg, *entry)
+	}
+
+	visit(entry)
+
+	return pkg
+}
+
+// packageFeeAndSize returns the combined fee and size of a package of entries
+func packageFeeAndSize(pkg []MempoolEntry) (int, int) {
+	fee, size := 0, 0
+
+	for _, entry := range pkg {
+		fee += entry.Fee
+		size += entry.Size
+	}
+
+	return fee, size
+}
+
+// outpointKey identifies the output an input spends
+func outpointKey(vin TXInput) string {
+	return fmt.Sprintf("%x:%d", vin.TransactionID, vin.OutputIndex)
+}
+
+// isRejection checks whether an error rejects a transaction rather than failing to check it
+func isRejection(err error) bool {
+	return errors.Is(err, ErrInvalidTransaction) || errors.Is(err, ErrTransactionLocked) ||
+		errors.Is(err, ErrMempoolConflict) || errors.Is(err, ErrTransactionNotFound)
+}
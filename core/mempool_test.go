@@ -0,0 +1,230 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBlockTransactions(t *testing.T) {
+	// The parent pays a lower fee rate than the other transaction, its child a higher one than both
+	parent := MempoolEntry{Transaction: Transaction{ID: []byte{1}}, Fee: 10, Size: 100}
+	child := MempoolEntry{Transaction: Transaction{ID: []byte{2}, InputValue: []TXInput{{TransactionID: []byte{1}}}}, Fee: 90, Size: 100}
+	other := MempoolEntry{Transaction: Transaction{ID: []byte{3}}, Fee: 40, Size: 100}
+
+	tests := []struct {
+		name         string
+		entries      []MempoolEntry
+		maxBlockSize int
+		expected     [][]byte
+		expectedFees int
+	}{
+		{name: "child pulling its parent in", entries: []MempoolEntry{parent, child, other}, maxBlockSize: 200, expected: [][]byte{{1}, {2}}, expectedFees: 100},
+		{name: "parent without its child", entries: []MempoolEntry{parent, other}, maxBlockSize: 100, expected: [][]byte{{3}}, expectedFees: 40},
+		{name: "package too large for the block", entries: []MempoolEntry{parent, child, other}, maxBlockSize: 150, expected: [][]byte{{3}}, expectedFees: 40},
+		{name: "no maximum block size", entries: []MempoolEntry{parent, child, other}, expected: [][]byte{{1}, {2}, {3}}, expectedFees: 140},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MempoolConfig.MaxBlockSize = test.maxBlockSize
+
+			mempool := NewMempool(cfg, &Blockchain{cfg: cfg, Store: NewMemoryChainStore(cfg)})
+
+			err := mempool.save(test.entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			transactions, fees, err := mempool.BlockTransactions()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(transactions) != len(test.expected) {
+				t.Fatalf("selected %d transactions, expected %d", len(transactions), len(test.expected))
+			}
+
+			for i, tx := range transactions {
+				if !bytes.Equal(tx.ID, test.expected[i]) {
+					t.Errorf("transaction %d is %x, expected %x", i, tx.ID, test.expected[i])
+				}
+			}
+
+			if *fees != test.expectedFees {
+				t.Errorf("fees are %d, expected %d", *fees, test.expectedFees)
+			}
+		})
+	}
+}
+
+func TestCheckReplacement(t *testing.T) {
+	// The pending transaction and its child both pay a fee rate of 1
+	pending := MempoolEntry{Transaction: Transaction{ID: []byte{1}}, Fee: 100, Size: 100}
+	child := MempoolEntry{Transaction: Transaction{ID: []byte{2}, InputValue: []TXInput{{TransactionID: []byte{1}}}}, Fee: 100, Size: 100}
+	pool := []MempoolEntry{pending, child}
+
+	tests := []struct {
+		name     string
+		fee      int
+		size     int
+		replaced map[string]bool
+		expected error
+	}{
+		{name: "nothing replaced", fee: 0, size: 100, replaced: map[string]bool{}},
+		{name: "higher fee and fee rate", fee: 150, size: 100, replaced: map[string]bool{"01": true}},
+		{name: "same fee and fee rate", fee: 100, size: 100, replaced: map[string]bool{"01": true}, expected: ErrMempoolConflict},
+		{name: "higher fee at a lower fee rate", fee: 150, size: 200, replaced: map[string]bool{"01": true}, expected: ErrMempoolConflict},
+		{name: "higher fee rate at a lower fee", fee: 90, size: 50, replaced: map[string]bool{"01": true}, expected: ErrMempoolConflict},
+		{name: "fee below the conflicting descendants", fee: 150, size: 100, replaced: map[string]bool{"01": true, "02": true}, expected: ErrMempoolConflict},
+		{name: "fee above the conflicting descendants", fee: 250, size: 100, replaced: map[string]bool{"01": true, "02": true}},
+		{name: "fee rate below a conflicting descendant", fee: 250, size: 300, replaced: map[string]bool{"01": true, "02": true}, expected: ErrMempoolConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := MempoolEntry{Transaction: Transaction{ID: []byte{3}}, Fee: test.fee, Size: test.size}
+
+			err := checkReplacement(&entry, pool, test.replaced)
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("checked with %v, expected %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestUnexpired(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-2 * time.Hour).UnixNano()
+
+	expired := MempoolEntry{Transaction: Transaction{ID: []byte{1}}, AddedAt: old}
+	child := MempoolEntry{Transaction: Transaction{ID: []byte{2}, InputValue: []TXInput{{TransactionID: []byte{1}}}}, AddedAt: now.UnixNano()}
+	fresh := MempoolEntry{Transaction: Transaction{ID: []byte{3}}, AddedAt: now.UnixNano()}
+
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		expected [][]byte
+	}{
+		{name: "expired entry dropped with its child", maxAge: time.Hour, expected: [][]byte{{3}}},
+		{name: "no maximum age", expected: [][]byte{{1}, {2}, {3}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MempoolConfig.MaxAge = test.maxAge
+
+			mempool := NewMempool(cfg, nil)
+
+			entries := mempool.unexpired([]MempoolEntry{expired, child, fresh}, now)
+			if len(entries) != len(test.expected) {
+				t.Fatalf("kept %d entries, expected %d", len(entries), len(test.expected))
+			}
+
+			for i, entry := range entries {
+				if !bytes.Equal(entry.Transaction.ID, test.expected[i]) {
+					t.Errorf("entry %d is %x, expected %x", i, entry.Transaction.ID, test.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimitSize(t *testing.T) {
+	// The parent pays the lowest fee rate, but its child makes their package the highest
+	parent := MempoolEntry{Transaction: Transaction{ID: []byte{1}}, Fee: 10, Size: 100}
+	child := MempoolEntry{Transaction: Transaction{ID: []byte{2}, InputValue: []TXInput{{TransactionID: []byte{1}}}}, Fee: 90, Size: 100}
+	low := MempoolEntry{Transaction: Transaction{ID: []byte{3}}, Fee: 20, Size: 100}
+	high := MempoolEntry{Transaction: Transaction{ID: []byte{4}}, Fee: 40, Size: 100}
+
+	tests := []struct {
+		name            string
+		entries         []MempoolEntry
+		maxSize         int
+		expectedEvicted []string
+	}{
+		{name: "below the maximum size", entries: []MempoolEntry{low, high}, maxSize: 200},
+		{name: "lowest fee rate evicted", entries: []MempoolEntry{low, high}, maxSize: 150, expectedEvicted: []string{"03"}},
+		{name: "parent kept for its child", entries: []MempoolEntry{parent, child, low, high}, maxSize: 300, expectedEvicted: []string{"03"}},
+		{name: "parent evicted with its child", entries: []MempoolEntry{parent, child, low}, maxSize: 50, expectedEvicted: []string{"01", "02", "03"}},
+		{name: "no maximum size", entries: []MempoolEntry{parent, child, low, high}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MempoolConfig.MaxSize = test.maxSize
+
+			mempool := NewMempool(cfg, nil)
+
+			entries, evicted := mempool.limitSize(test.entries)
+			if len(evicted) != len(test.expectedEvicted) {
+				t.Fatalf("evicted %d entries, expected %d", len(evicted), len(test.expectedEvicted))
+			}
+
+			for _, txID := range test.expectedEvicted {
+				if !evicted[txID] {
+					t.Errorf("entry %s was not evicted", txID)
+				}
+			}
+
+			if len(entries)+len(evicted) != len(test.entries) {
+				t.Errorf("kept %d entries, expected %d", len(entries), len(test.entries)-len(evicted))
+			}
+		})
+	}
+}
+
+func TestReloadDropsMinedAndSpentTransactions(t *testing.T) {
+	chain := newTestChain(t, 4)
+	cfg := chain.bc.cfg
+
+	mined := testPayment(t, cfg, chain.wallet, chain.blocks[2].Transactions[0], chain.otherAddress, 9)
+	doubleSpent := testPayment(t, cfg, chain.wallet, chain.blocks[3].Transactions[0], chain.address, 9)
+	child := testPayment(t, cfg, chain.wallet, doubleSpent, chain.otherAddress, 8)
+	kept := testPayment(t, cfg, chain.wallet, chain.blocks[4].Transactions[0], chain.otherAddress, 9)
+
+	mempool := NewMempool(cfg, chain.bc)
+
+	for _, tx := range []*Transaction{mined, doubleSpent, child, kept} {
+		_, err := mempool.Add(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := mempool.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The block is mined while the node is down, so its transactions are still stored as pending
+	conflict := testPayment(t, cfg, chain.wallet, chain.blocks[3].Transactions[0], chain.otherAddress, 10)
+	chain.mine(t, testCoinbase(t, cfg, chain.address), mined, conflict)
+
+	err = mempool.save(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := mempool.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *dropped != 3 {
+		t.Errorf("dropped %d transactions, expected 3", *dropped)
+	}
+
+	entries, err = mempool.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || !bytes.Equal(entries[0].Transaction.ID, kept.ID) {
+		t.Errorf("kept %d transactions, expected only %x", len(entries), kept.ID)
+	}
+}
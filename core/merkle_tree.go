@@ -1,44 +1,138 @@
 package core
 
-import "crypto/sha256"
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+const (
+	// merkleLeafTag and merkleNodeTag prefix the hashes of leaves and inner nodes of version 1 trees, so that an inner
+	// node can never be passed off as a leaf or a leaf as an inner node
+	merkleLeafTag = byte(0x00)
+	merkleNodeTag = byte(0x01)
+)
 
 // MerkleTree represent a Merkle tree
 type MerkleTree struct {
 	RootNode *MerkleNode
+	levels   [][]*MerkleNode
+	leaves   int
 }
 
+// MerkleProofStep is a sibling hash on the path from a leaf to the root
+type MerkleProofStep struct {
+	Hash []byte
+	Left bool
+}
+
+// NewMerkleTree builds a Merkle tree level by level, carrying the last node of every level with an odd number of nodes
+// up to the next level unchanged. Duplicating it instead would give a list of leaves the root of the same list with
+// its last leaves repeated
 func NewMerkleTree(data [][]byte) *MerkleTree {
-	var nodes []MerkleNode
+	var level []*MerkleNode
 
-	if len(data)%2 != 0 {
-		data = append(data, data[len(data)-1])
+	for _, datum := range data {
+		level = append(level, NewMerkleNode(nil, nil, datum))
 	}
 
-	for _, datum := range data {
-		node := NewMerkleNode(nil, nil, datum)
-		nodes = append(nodes, *node)
+	if len(level) == 0 {
+		level = append(level, NewMerkleNode(nil, nil, []byte{}))
 	}
 
-	for range len(data) / 2 {
-		var newLevel []MerkleNode
+	levels := [][]*MerkleNode{level}
+
+	for len(level) > 1 {
+		var newLevel []*MerkleNode
 
-		if len(nodes)%2 != 0 {
-			nodes = append(nodes, nodes[len(nodes)-1])
+		for j := 0; j+1 < len(level); j += 2 {
+			newLevel = append(newLevel, NewMerkleNode(level[j], level[j+1], nil))
 		}
 
-		for j := 0; j < len(nodes); j += 2 {
-			node := NewMerkleNode(&nodes[j], &nodes[j+1], nil)
-			newLevel = append(nodes, *node)
+		if len(level)%2 != 0 {
+			newLevel = append(newLevel, level[len(level)-1])
 		}
 
-		nodes = newLevel
+		levels = append(levels, newLevel)
+		level = newLevel
 	}
 
-	mTree := MerkleTree{RootNode: &nodes[0]}
+	mTree := MerkleTree{RootNode: level[0], levels: levels, leaves: len(data)}
 
 	return &mTree
 }
 
+// Proof returns the sibling hashes needed to recompute the root from the leaf at the given index
+func (t *MerkleTree) Proof(txIndex int) ([]MerkleProofStep, error) {
+	if txIndex < 0 || txIndex >= t.leaves {
+		return nil, fmt.Errorf("leaf index %d out of range", txIndex)
+	}
+
+	var proof []MerkleProofStep
+
+	index := txIndex
+
+	for _, level := range t.levels[:len(t.levels)-1] {
+		// The last node of a level with an odd number of nodes has no sibling and is carried up unchanged
+		if index%2 == 0 && index+1 < len(level) {
+			proof = append(proof, MerkleProofStep{Hash: level[index+1].Data, Left: false})
+		} else if index%2 != 0 {
+			proof = append(proof, MerkleProofStep{Hash: level[index-1].Data, Left: true})
+		}
+
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that the leaf hash and the proof hash up to the root
+func VerifyMerkleProof(root []byte, txHash []byte, proof []MerkleProofStep) bool {
+	hash := txHash
+
+	for _, step := range proof {
+		if step.Left {
+			hash = merkleNodeHash(step.Hash, hash)
+		} else {
+			hash = merkleNodeHash(hash, step.Hash)
+		}
+	}
+
+	return bytes.Equal(hash, root)
+}
+
+// LegacyMerkleRoot returns the Merkle root of a version 0 block. The tree builder these blocks were mined with
+// overwrote every level with the leaves, so its root is the untagged hash of the first leaf alone
+func LegacyMerkleRoot(data [][]byte) []byte {
+	if len(data) == 0 {
+		return legacyMerkleLeafHash([]byte{})
+	}
+
+	return legacyMerkleLeafHash(data[0])
+}
+
+// MerkleLeafHash returns the hash of a leaf of a version 1 tree
+func MerkleLeafHash(data []byte) []byte {
+	hash := sha256.Sum256(append([]byte{merkleLeafTag}, data...))
+
+	return hash[:]
+}
+
+// legacyMerkleLeafHash returns the hash of a leaf of a version 0 block, which was not tagged
+func legacyMerkleLeafHash(data []byte) []byte {
+	hash := sha256.Sum256(data)
+
+	return hash[:]
+}
+
+// merkleNodeHash returns the hash of an inner node of a version 1 tree from the hashes of its children
+func merkleNodeHash(left []byte, right []byte) []byte {
+	data := append([]byte{merkleNodeTag}, left...)
+	hash := sha256.Sum256(append(data, right...))
+
+	return hash[:]
+}
+
 // MerkleNode represent a Merkle tree node
 type MerkleNode struct {
 	Left  *MerkleNode
@@ -46,16 +140,15 @@ type MerkleNode struct {
 	Data  []byte
 }
 
+// NewMerkleNode returns a leaf holding the hash of the data, or an inner node holding the hash of its children
 func NewMerkleNode(left *MerkleNode, right *MerkleNode, data []byte) *MerkleNode {
 	mNode := MerkleNode{Left: left, Right: right}
 
-	hash := sha256.Sum256(data)
 	if left != nil || right != nil {
-		prevHashes := append(left.Data, right.Data...)
-		hash = sha256.Sum256(prevHashes)
+		mNode.Data = merkleNodeHash(left.Data, right.Data)
+	} else {
+		mNode.Data = MerkleLeafHash(data)
 	}
 
-	mNode.Data = hash[:]
-
 	return &mNode
 }
@@ -0,0 +1,335 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// merkleLeaves returns count distinct leaves
+func merkleLeaves(count int) [][]byte {
+	var leaves [][]byte
+
+	for i := 0; i < count; i++ {
+		leaves = append(leaves, []byte(fmt.Sprintf("leaf %d", i)))
+	}
+
+	return leaves
+}
+
+func TestNewMerkleTreeRoot(t *testing.T) {
+	h := func(i int) []byte { return MerkleLeafHash([]byte(fmt.Sprintf("leaf %d", i))) }
+
+	tests := []struct {
+		name   string
+		leaves int
+		root   []byte
+	}{
+		{name: "one leaf", leaves: 1, root: h(0)},
+		{name: "two leaves", leaves: 2, root: merkleNodeHash(h(0), h(1))},
+		{name: "three leaves", leaves: 3, root: merkleNodeHash(merkleNodeHash(h(0), h(1)), h(2))},
+		{name: "four leaves", leaves: 4, root: merkleNodeHash(merkleNodeHash(h(0), h(1)), merkleNodeHash(h(2), h(3)))},
+		{
+			name:   "five leaves",
+			leaves: 5,
+			root: merkleNodeHash(
+				merkleNodeHash(merkleNodeHash(h(0), h(1)), merkleNodeHash(h(2), h(3))),
+				h(4),
+			),
+		},
+		{
+			name:   "six leaves",
+			leaves: 6,
+			root: merkleNodeHash(
+				merkleNodeHash(merkleNodeHash(h(0), h(1)), merkleNodeHash(h(2), h(3))),
+				merkleNodeHash(h(4), h(5)),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mTree := NewMerkleTree(merkleLeaves(test.leaves))
+
+			if !bytes.Equal(mTree.RootNode.Data, test.root) {
+				t.Errorf("root is %x, expected %x", mTree.RootNode.Data, test.root)
+			}
+		})
+	}
+}
+
+func TestNewMerkleTreeEmpty(t *testing.T) {
+	mTree := NewMerkleTree(nil)
+
+	if !bytes.Equal(mTree.RootNode.Data, MerkleLeafHash([]byte{})) {
+		t.Errorf("root of an empty tree is %x, expected the hash of an empty leaf", mTree.RootNode.Data)
+	}
+}
+
+func TestMerkleProofRoundTrip(t *testing.T) {
+	for _, count := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9} {
+		leaves := merkleLeaves(count)
+		mTree := NewMerkleTree(leaves)
+
+		for index, leaf := range leaves {
+			proof, err := mTree.Proof(index)
+			if err != nil {
+				t.Fatalf("%d leaves: proof of leaf %d: %v", count, index, err)
+			}
+
+			if !VerifyMerkleProof(mTree.RootNode.Data, MerkleLeafHash(leaf), proof) {
+				t.Errorf("%d leaves: proof of leaf %d does not verify", count, index)
+			}
+		}
+	}
+}
+
+func TestMerkleProofOutOfRange(t *testing.T) {
+	mTree := NewMerkleTree(merkleLeaves(3))
+
+	for _, index := range []int{-1, 3, 4} {
+		_, err := mTree.Proof(index)
+		if err == nil {
+			t.Errorf("proof of leaf %d of 3 succeeded", index)
+		}
+	}
+}
+
+func TestMerkleProofTampered(t *testing.T) {
+	leaves := merkleLeaves(5)
+	mTree := NewMerkleTree(leaves)
+	root := mTree.RootNode.Data
+
+	proof, err := mTree.Proof(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copyProof := func() []MerkleProofStep {
+		var steps []MerkleProofStep
+
+		for _, step := range proof {
+			steps = append(steps, MerkleProofStep{Hash: bytes.Clone(step.Hash), Left: step.Left})
+		}
+
+		return steps
+	}
+
+	flippedHash := copyProof()
+	flippedHash[1].Hash[0] ^= 0xff
+
+	flippedSide := copyProof()
+	flippedSide[0].Left = !flippedSide[0].Left
+
+	tests := []struct {
+		name  string
+		leaf  []byte
+		proof []MerkleProofStep
+	}{
+		{name: "other leaf", leaf: leaves[3], proof: copyProof()},
+		{name: "tampered sibling hash", leaf: leaves[2], proof: flippedHash},
+		{name: "swapped sibling side", leaf: leaves[2], proof: flippedSide},
+		{name: "missing step", leaf: leaves[2], proof: copyProof()[:len(proof)-1]},
+		{name: "no steps", leaf: leaves[2], proof: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if VerifyMerkleProof(root, MerkleLeafHash(test.leaf), test.proof) {
+				t.Error("tampered proof verifies")
+			}
+		})
+	}
+}
+
+func TestLegacyMerkleRoot(t *testing.T) {
+	leaves := merkleLeaves(3)
+
+	if !bytes.Equal(LegacyMerkleRoot(leaves), legacyMerkleLeafHash(leaves[0])) {
+		t.Error("legacy root is not the hash of the first leaf")
+	}
+
+	if !bytes.Equal(LegacyMerkleRoot(nil), legacyMerkleLeafHash([]byte{})) {
+		t.Error("legacy root of no leaves is not the hash of an empty leaf")
+	}
+}
+
+// merkleTestTransactions returns a coinbase and two transactions spending it, with their IDs set to their hashes
+func merkleTestTransactions(t *testing.T) []*Transaction {
+	t.Helper()
+
+	transactions := []*Transaction{
+		{InputValue: []TXInput{{OutputIndex: -1}}, OutputValue: []TXOutput{{Value: 10}}},
+		{InputValue: []TXInput{{TransactionID: []byte{1}}}, OutputValue: []TXOutput{{Value: 5}}},
+		{InputValue: []TXInput{{TransactionID: []byte{1}}}, OutputValue: []TXOutput{{Value: 4}}},
+	}
+
+	for _, tx := range transactions {
+		hash, err := tx.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx.ID = hash
+	}
+
+	return transactions
+}
+
+func TestBlockMerkleRootByVersion(t *testing.T) {
+	transactions := merkleTestTransactions(t)
+
+	legacyBlock := Block{Version: 0, Transactions: transactions}
+	block := Block{Version: BlockVersion, Transactions: transactions}
+
+	legacyRoot, err := legacyBlock.HashTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := transactions[0].Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(legacyRoot, legacyMerkleLeafHash(first)) {
+		t.Error("version 0 root is not the hash of the first transaction")
+	}
+
+	root, err := block.HashTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(root, legacyRoot) {
+		t.Error("version 1 root only covers the first transaction")
+	}
+
+	for _, tx := range transactions {
+		proof, err := block.ProveTransaction(tx.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !proof.Verify() {
+			t.Errorf("proof of %x does not verify", tx.ID)
+		}
+	}
+
+	proof, err := legacyBlock.ProveTransaction(transactions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !proof.Verify() {
+		t.Error("proof of the first transaction of a version 0 block does not verify")
+	}
+
+	_, err = legacyBlock.ProveTransaction(transactions[1].ID)
+	if err == nil {
+		t.Error("proved a transaction a version 0 block does not commit to")
+	}
+}
+
+func TestMerkleTreeRepeatedLeaves(t *testing.T) {
+	leaves := merkleLeaves(3)
+	root := NewMerkleTree(leaves).RootNode.Data
+
+	for _, repeated := range [][][]byte{slices.Concat(leaves, leaves[2:]), slices.Concat(leaves, leaves[2:], leaves[2:])} {
+		if bytes.Equal(NewMerkleTree(repeated).RootNode.Data, root) {
+			t.Errorf("%d leaves with the last one repeated have the root of 3 leaves", len(repeated))
+		}
+	}
+}
+
+func TestMerkleTreeInnerNodeAsLeaf(t *testing.T) {
+	leaves := merkleLeaves(2)
+	root := NewMerkleTree(leaves).RootNode.Data
+
+	// A leaf made of the hashes of the two leaves must not hash to their parent
+	forged := append(MerkleLeafHash(leaves[0]), MerkleLeafHash(leaves[1])...)
+
+	if bytes.Equal(NewMerkleTree([][]byte{forged}).RootNode.Data, root) {
+		t.Error("a leaf made of two child hashes has the root of the tree of the children")
+	}
+
+	if VerifyMerkleProof(root, MerkleLeafHash(forged), nil) {
+		t.Error("a leaf made of two child hashes verifies as the root")
+	}
+}
+
+func TestTransactionProofVerify(t *testing.T) {
+	transactions := merkleTestTransactions(t)
+	block := Block{Version: BlockVersion, Transactions: transactions}
+
+	proof, err := block.ProveTransaction(transactions[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherRaw, err := transactions[2].Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := *transactions[1]
+	tampered.OutputValue = []TXOutput{{Value: 50}}
+
+	tamperedRaw, err := tampered.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := block.HashTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(proof *TransactionProof)
+		valid  bool
+	}{
+		{name: "valid proof", modify: func(proof *TransactionProof) {}, valid: true},
+		{name: "other transaction ID", modify: func(proof *TransactionProof) { proof.TransactionID = transactions[2].ID }},
+		{name: "raw transaction of another transaction", modify: func(proof *TransactionProof) { proof.RawTransaction = otherRaw }},
+		{name: "tampered raw transaction keeping its ID", modify: func(proof *TransactionProof) { proof.RawTransaction = tamperedRaw }},
+		{name: "leaf hash of another transaction", modify: func(proof *TransactionProof) { proof.LeafHash = MerkleLeafHash(otherRaw) }},
+		{name: "untagged leaf hash", modify: func(proof *TransactionProof) { proof.LeafHash = legacyMerkleLeafHash(proof.RawTransaction) }},
+		{name: "root as the leaf", modify: func(proof *TransactionProof) { proof.LeafHash, proof.Proof = root, nil }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modified := *proof
+			modified.Proof = slices.Clone(proof.Proof)
+			test.modify(&modified)
+
+			if modified.Verify() != test.valid {
+				t.Errorf("proof verifies %t, expected %t", modified.Verify(), test.valid)
+			}
+		})
+	}
+}
+
+func TestCheckBlockVersion(t *testing.T) {
+	tests := []struct {
+		version       int
+		parentVersion int
+		valid         bool
+	}{
+		{version: 0, parentVersion: 0, valid: true},
+		{version: 1, parentVersion: 0, valid: true},
+		{version: 1, parentVersion: 1, valid: true},
+		{version: 0, parentVersion: 1, valid: false},
+		{version: BlockVersion + 1, parentVersion: BlockVersion, valid: false},
+	}
+
+	for _, test := range tests {
+		err := checkBlockVersion(test.version, test.parentVersion)
+		if (err == nil) != test.valid {
+			t.Errorf("version %d after %d: got %v", test.version, test.parentVersion, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"math/big"
+)
+
+// messagePrefix is hashed before signed messages, so that a message signature can never be replayed as a transaction signature
+const messagePrefix = "Burrokuchen Signed Message:\n"
+
+// signatureValueLength is the length r and s are padded to in message and transaction signatures
+const signatureValueLength = 32
+
+// MessageHash returns the double SHA-256 of the prefixed message
+func MessageHash(message string) []byte {
+	firstSHA := sha256.Sum256(append([]byte(messagePrefix), message...))
+	secondSHA := sha256.Sum256(firstSHA[:])
+
+	return secondSHA[:]
+}
+
+// SignMessage signs a message with the private key of the wallet.
+// P-256 public keys cannot be recovered from a signature, so the signature is the length of the public key,
+// the public key and the padded r and s values.
+func (w *Wallet) SignMessage(message string) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, &w.PrivateKey, MessageHash(message))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	signature := append([]byte{byte(len(w.PublicKey))}, w.PublicKey...)
+	signature = append(signature, r.FillBytes(make([]byte, signatureValueLength))...)
+	signature = append(signature, s.FillBytes(make([]byte, signatureValueLength))...)
+
+	return signature, nil
+}
+
+// VerifyMessage checks that a message signature was made by the key of the address
+func VerifyMessage(cfg *model.Config, address string, signature []byte, message string) (*bool, error) {
+	result := false
+
+	if len(signature) == 0 || len(signature) != 1+int(signature[0])+2*signatureValueLength || signature[0] == 0 {
+		return &result, nil
+	}
+
+	isValid, err := ValidateAddress(cfg, address)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		return &result, nil
+	}
+
+	pubKey := signature[1 : 1+signature[0]]
+
+	pubKeyHash, err := HashPubKey(pubKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !bytes.Equal(pubKeyHash, DecodeAddress(cfg, address)) {
+		return &result, nil
+	}
+
+	x, y := publicKeyPoint(pubKey)
+	if x == nil {
+		return &result, nil
+	}
+
+	values := signature[1+len(pubKey):]
+	r := new(big.Int).SetBytes(values[:signatureValueLength])
+	s := new(big.Int).SetBytes(values[signatureValueLength:])
+
+	rawPubKey := ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	result = ecdsa.Verify(&rawPubKey, MessageHash(message), r, s)
+
+	return &result, nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVerifyMessage(t *testing.T) {
+	cfg := testConfig()
+	wallet, address := testWallet(t, cfg)
+	otherWallet, otherAddress := testWallet(t, cfg)
+
+	message := "burrokuchen"
+
+	signature, err := wallet.SignMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSignature, err := otherWallet.SignMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamperedValue := slices.Clone(signature)
+	tamperedValue[len(tamperedValue)-1] ^= 1
+
+	tamperedKey := slices.Clone(signature)
+	tamperedKey[1+len(wallet.PublicKey)/2] ^= 1
+
+	tests := []struct {
+		name      string
+		address   string
+		signature []byte
+		message   string
+		expected  bool
+	}{
+		{name: "signed message", address: address, signature: signature, message: message, expected: true},
+		{name: "other message", address: address, signature: signature, message: "burrokuchen!"},
+		{name: "other address", address: otherAddress, signature: signature, message: message},
+		{name: "signed by another key", address: address, signature: otherSignature, message: message},
+		{name: "tampered signature value", address: address, signature: tamperedValue, message: message},
+		{name: "tampered public key", address: address, signature: tamperedKey, message: message},
+		{name: "truncated signature", address: address, signature: signature[:len(signature)-1], message: message},
+		{name: "empty signature", address: address, message: message},
+		{name: "invalid address", address: "burrokuchen", signature: signature, message: message},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isVerified, err := VerifyMessage(cfg, test.address, test.signature, test.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *isVerified != test.expected {
+				t.Errorf("verified as %t, expected %t", *isVerified, test.expected)
+			}
+		})
+	}
+}
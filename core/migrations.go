@@ -0,0 +1,267 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+var (
+	ErrSchemaTooNew       = errors.New("database schema is newer than this version supports")
+	ErrSchemaOutdated     = errors.New("database schema is outdated")
+	ErrIncompatibleBlocks = errors.New("blocks cannot be read in the current format")
+)
+
+// SchemaVersion is the version of the database schema written by this version, the version of the last migration
+const SchemaVersion = 4
+
+// schemaVersionKey is the key of the schema version in the meta bucket
+var schemaVersionKey = []byte("schema_version")
+
+// Migration upgrades a database from the schema version before it to its own
+type Migration struct {
+	Version     int
+	Description string
+	migrate     func(bc *Blockchain) error
+}
+
+// migrations are the upgrades of the database schema in version order. Databases written before the schema was
+// versioned are at version 0
+var migrations = []Migration{
+	{Version: 1, Description: "Index the blocks by height", migrate: (*Blockchain).ensureIndex},
+	{Version: 2, Description: "Build the compact filters of the blocks", migrate: (*Blockchain).ensureFilters},
+	{Version: 3, Description: "Record the block the UTXO set is at", migrate: (*Blockchain).recordUTXOTip},
+	{Version: 4, Description: "Check the blocks against the versioned block and transaction formats", migrate: (*Blockchain).checkBlockFormats},
+}
+
+// ReadSchemaVersion returns the schema version of the database kept in the store
+func ReadSchemaVersion(cfg *model.Config, store ChainStore) (*int, error) {
+	var version int
+
+	err := store.View(func(tx ChainTx) error {
+		version = readSchemaVersion(cfg, tx)
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &version, nil
+}
+
+// readSchemaVersion reads the schema version from the meta bucket, which databases written before versioning lack
+func readSchemaVersion(cfg *model.Config, tx ChainTx) int {
+	encodedVersion := tx.Record(cfg.DatabaseConfig.MetaBucket, schemaVersionKey)
+	if encodedVersion == nil {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint64(encodedVersion))
+}
+
+// putSchemaVersion records the schema version in the meta bucket
+func putSchemaVersion(cfg *model.Config, tx ChainTx, version int) error {
+	encodedVersion := make([]byte, 8)
+	binary.BigEndian.PutUint64(encodedVersion, uint64(version))
+
+	return tx.PutRecord(cfg.DatabaseConfig.MetaBucket, schemaVersionKey, encodedVersion)
+}
+
+// PendingMigrations returns the migrations that upgrade a database from the given schema version
+func PendingMigrations(version int) []Migration {
+	var pending []Migration
+
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending
+}
+
+// MigrationBackupPath returns the path of the backup taken before a database is migrated from the given schema version
+func MigrationBackupPath(cfg *model.Config, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", cfg.DatabaseConfig.DbName, version)
+}
+
+// MigrateStore upgrades the database kept in the store to the current schema version one migration at a time, recording
+// the version after each one so that an interrupted upgrade resumes where it stopped. The store is backed up to backupPath
+// first, unless backupPath is empty, and progress is called after every migration
+func MigrateStore(cfg *model.Config, store ChainStore, backupPath string, progress func(migration Migration)) error {
+	version, err := ReadSchemaVersion(cfg, store)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if *version > SchemaVersion {
+		return fmt.Errorf("%w: version %d, up to %d is supported", ErrSchemaTooNew, *version, SchemaVersion)
+	}
+
+	pending := PendingMigrations(*version)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if backupPath != "" {
+		err = store.Backup(backupPath)
+		if err != nil {
+			return fmt.Errorf("backing up the database before migrating it: %w", err)
+		}
+	}
+
+	var tip []byte
+	var algorithm string
+
+	err = store.View(func(tx ChainTx) error {
+		tip = tx.Tip()
+		algorithm = readPowAlgorithm(cfg, tx)
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	blockchain := Blockchain{cfg: cfg, Tip: tip, Store: store, PowAlgorithm: algorithm}
+
+	for _, migration := range pending {
+		// A database without blocks has nothing to upgrade
+		if tip != nil {
+			err = migration.migrate(&blockchain)
+			if err != nil {
+				return fmt.Errorf("migrating to schema version %d: %w", migration.Version, err)
+			}
+		}
+
+		err = store.Update(func(tx ChainTx) error {
+			return putSchemaVersion(cfg, tx, migration.Version)
+		})
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		progress(migration)
+	}
+
+	return nil
+}
+
+// checkBlockFormats checks that the proof of work of every block and the signatures of its transactions still hash to
+// what they were made over, so that a chain whose blocks or transactions were written in a format this version does not
+// serialize the same way is refused instead of marked as upgraded. Such a chain has to be restored from its backup and
+// imported again from a chain file. Transactions spending outputs of pruned blocks cannot be checked and are skipped
+func (bc *Blockchain) checkBlockFormats() error {
+	var hashes [][]byte
+
+	bci := bc.InitializeIterator()
+
+	for {
+		block, err := bci.Prev()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		hashes = append(hashes, block.Hash)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	parentVersion := 0
+	transactions := make(map[string]Transaction)
+
+	for i := len(hashes) - 1; i >= 0; i-- {
+		var block *Block
+
+		err := bc.Store.View(func(tx ChainTx) error {
+			storedBlock, err := tx.Block(hashes[i])
+			block = storedBlock
+
+			return err
+		})
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = checkBlockVersion(block.Version, parentVersion)
+		if err != nil {
+			return fmt.Errorf("%w: block %x: %w", ErrIncompatibleBlocks, block.Hash, err)
+		}
+		parentVersion = block.Version
+
+		pow, err := NewProofOfWork(bc.cfg, bc.PowAlgorithm, block)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		isValid, err := pow.Validate()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if !*isValid {
+			return fmt.Errorf("%w: the header of version %d block %x does not hash to it", ErrIncompatibleBlocks, block.Version, block.Hash)
+		}
+
+		for _, tx := range block.Transactions {
+			prevTXs := make(map[string]Transaction)
+			spendsPruned := false
+
+			for _, vin := range tx.InputValue {
+				prevTX, ok := transactions[hex.EncodeToString(vin.TransactionID)]
+				if !ok {
+					spendsPruned = true
+					break
+				}
+
+				prevTXs[hex.EncodeToString(vin.TransactionID)] = prevTX
+			}
+
+			if !tx.IsCoinbase() && !spendsPruned {
+				verified, err := tx.Verify(prevTXs)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+
+				if !*verified {
+					return fmt.Errorf("%w: the signatures of transaction %x in block %x do not verify", ErrIncompatibleBlocks, tx.ID, block.Hash)
+				}
+			}
+
+			transactions[hex.EncodeToString(tx.ID)] = *tx
+		}
+	}
+
+	return nil
+}
+
+// ensureSchema checks the schema version of the database, migrating an older one after backing it up when automatic
+// migration is enabled
+func (bc *Blockchain) ensureSchema() error {
+	version, err := ReadSchemaVersion(bc.cfg, bc.Store)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	switch {
+	case *version > SchemaVersion:
+		return fmt.Errorf("%w: version %d, up to %d is supported", ErrSchemaTooNew, *version, SchemaVersion)
+	case *version == SchemaVersion:
+		return nil
+	case !bc.cfg.DatabaseConfig.AutoMigrate:
+		return fmt.Errorf("%w: version %d is older than %d, run migrate first", ErrSchemaOutdated, *version, SchemaVersion)
+	}
+
+	backupPath := MigrationBackupPath(bc.cfg, *version)
+	fmt.Printf("Database schema version %d is outdated, backing it up to %s and migrating it\n", *version, backupPath)
+
+	return MigrateStore(bc.cfg, bc.Store, backupPath, func(migration Migration) {
+		fmt.Printf("Migrated the database to schema version %d: %s\n", migration.Version, migration.Description)
+	})
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"go-burrokuchen/model"
+	"slices"
+	"testing"
+)
+
+// newVersionZeroStore returns a store holding the blocks as a database written before the schema was versioned: the
+// blocks, the tip and the UTXO set, without index, filters or meta records
+func newVersionZeroStore(t *testing.T, cfg *model.Config, blocks []*Block) ChainStore {
+	t.Helper()
+
+	store := NewMemoryChainStore(cfg)
+
+	err := store.Update(func(tx ChainTx) error {
+		for _, block := range blocks {
+			err := tx.PutBlock(block)
+			if err != nil {
+				return err
+			}
+
+			err = applyBlockUTXOs(tx, block)
+			if err != nil {
+				return err
+			}
+
+			err = tx.SetTip(block.Hash)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+// versionZeroBlocks returns a genesis block paying the first wallet and a block with a payment to the second wallet,
+// both mined as version 0 blocks
+func versionZeroBlocks(t *testing.T, cfg *model.Config) []*Block {
+	t.Helper()
+
+	wallet, address := testWallet(t, cfg)
+	_, otherAddress := testWallet(t, cfg)
+
+	genesisCoinbase := testCoinbase(t, cfg, address)
+	genesis := mineTestBlock(t, cfg, 0, []*Transaction{genesisCoinbase}, []byte{})
+
+	payment := testPayment(t, cfg, wallet, genesisCoinbase, otherAddress, 10)
+	block := mineTestBlock(t, cfg, 0, []*Transaction{testCoinbase(t, cfg, otherAddress), payment}, genesis.Hash)
+
+	return []*Block{genesis, block}
+}
+
+// migrateTestStore migrates the store and returns the versions of the migrations that were run
+func migrateTestStore(cfg *model.Config, store ChainStore) ([]int, error) {
+	var migrated []int
+
+	err := MigrateStore(cfg, store, "", func(migration Migration) {
+		migrated = append(migrated, migration.Version)
+	})
+
+	return migrated, err
+}
+
+func TestMigrateVersionZero(t *testing.T) {
+	cfg := testConfig()
+	blocks := versionZeroBlocks(t, cfg)
+	store := newVersionZeroStore(t, cfg, blocks)
+	tip := blocks[len(blocks)-1].Hash
+
+	migrated, err := migrateTestStore(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(migrated, []int{1, 2, 3, 4}) {
+		t.Errorf("ran migrations %v, expected 1 to 4", migrated)
+	}
+
+	version, err := ReadSchemaVersion(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *version != SchemaVersion {
+		t.Errorf("schema version is %d, expected %d", *version, SchemaVersion)
+	}
+
+	bc, err := InitializeBlockchainWithStore(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	height, err := bc.GetBlockHeight(tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *height != 1 {
+		t.Errorf("tip is indexed at height %d, expected 1", *height)
+	}
+
+	for _, block := range blocks {
+		blockFilter, err := bc.GetBlockFilter(block.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !VerifyBlockFilter(block, blockFilter.Filter) {
+			t.Errorf("filter of block %x does not match it", block.Hash)
+		}
+	}
+
+	err = store.View(func(tx ChainTx) error {
+		if !bytes.Equal(utxoTip(cfg, tx), tip) {
+			t.Errorf("UTXO tip is %x, expected %x", utxoTip(cfg, tx), tip)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The migrated chain is extended with version 1 blocks
+	_, address := testWallet(t, cfg)
+
+	block, err := bc.MineBlock([]*Transaction{testCoinbase(t, cfg, address)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Version != BlockVersion {
+		t.Errorf("mined a version %d block, expected %d", block.Version, BlockVersion)
+	}
+}
+
+func TestMigrateTwice(t *testing.T) {
+	cfg := testConfig()
+	store := newVersionZeroStore(t, cfg, versionZeroBlocks(t, cfg))
+
+	_, err := migrateTestStore(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := migrateTestStore(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrated) != 0 {
+		t.Errorf("ran migrations %v on an up to date database", migrated)
+	}
+
+	version, err := ReadSchemaVersion(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *version != SchemaVersion {
+		t.Errorf("schema version is %d, expected %d", *version, SchemaVersion)
+	}
+}
+
+func TestMigrateRefusesIncompatibleBlocks(t *testing.T) {
+	cfg := testConfig()
+
+	tamperedSignature := versionZeroBlocks(t, cfg)
+	// The Merkle root of a version 0 block only covers its coinbase, so only the signature shows the change
+	tamperedSignature[1].Transactions[1].OutputValue[0].Value = 9
+
+	versionOneGenesis := versionZeroBlocks(t, cfg)
+	versionOneGenesis[0] = mineTestBlock(t, cfg, BlockVersion, versionOneGenesis[0].Transactions, []byte{})
+	versionOneGenesis[1] = mineTestBlock(t, cfg, 0, versionOneGenesis[1].Transactions, versionOneGenesis[0].Hash)
+
+	tests := []struct {
+		name   string
+		blocks []*Block
+	}{
+		{name: "transaction no longer matching its signature", blocks: tamperedSignature},
+		{name: "version 0 block after a version 1 block", blocks: versionOneGenesis},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newVersionZeroStore(t, cfg, test.blocks)
+
+			_, err := migrateTestStore(cfg, store)
+			if !errors.Is(err, ErrIncompatibleBlocks) {
+				t.Fatalf("migrated with %v, expected %v", err, ErrIncompatibleBlocks)
+			}
+
+			version, err := ReadSchemaVersion(cfg, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *version == SchemaVersion {
+				t.Error("the refused database was marked as upgraded")
+			}
+		})
+	}
+}
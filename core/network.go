@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+)
+
+// Network profiles a node can be configured with
+const (
+	NetworkMain    = "main"
+	NetworkTest    = "test"
+	NetworkRegtest = "regtest"
+)
+
+var ErrUnknownNetwork = errors.New("unknown network")
+
+// NetworkProfile holds the consensus choices a new blockchain of a network is created with
+type NetworkProfile struct {
+	PowAlgorithm string
+}
+
+// networkProfiles are the profiles of the known networks. The test network uses scrypt, so that it cannot be mined
+// cheaply on commodity hardware
+var networkProfiles = map[string]NetworkProfile{
+	NetworkMain:    {PowAlgorithm: PowSHA256},
+	NetworkTest:    {PowAlgorithm: PowScrypt},
+	NetworkRegtest: {PowAlgorithm: PowSHA256},
+}
+
+// NewChainPowAlgorithm returns the proof of work algorithm a new blockchain is created with: the configured one when
+// it is set, otherwise the one of the network profile. Existing blockchains keep the algorithm they were created with
+func NewChainPowAlgorithm(cfg *model.Config) (string, error) {
+	algorithm := cfg.ProofOfWorkConfig.Algorithm
+
+	if algorithm == "" {
+		profile, ok := networkProfiles[cfg.ServerConfig.Network]
+		if !ok {
+			return "", fmt.Errorf("%w: %q, expected %s, %s or %s", ErrUnknownNetwork, cfg.ServerConfig.Network, NetworkMain, NetworkTest, NetworkRegtest)
+		}
+
+		algorithm = profile.PowAlgorithm
+	}
+
+	_, err := NewPowHasher(algorithm)
+	if err != nil {
+		return "", utils.CatchErr(err)
+	}
+
+	return algorithm, nil
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewChainPowAlgorithm(t *testing.T) {
+	tests := []struct {
+		name      string
+		network   string
+		algorithm string
+		expected  string
+		err       error
+	}{
+		{name: "main network", network: NetworkMain, expected: PowSHA256},
+		{name: "test network", network: NetworkTest, expected: PowScrypt},
+		{name: "regtest network", network: NetworkRegtest, expected: PowSHA256},
+		{name: "configured algorithm", network: NetworkTest, algorithm: PowDoubleSHA256, expected: PowDoubleSHA256},
+		{name: "unknown network", network: "other", err: ErrUnknownNetwork},
+		{name: "unknown algorithm", network: NetworkMain, algorithm: "md5", err: ErrUnknownPowAlgorithm},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ServerConfig.Network = test.network
+			cfg.ProofOfWorkConfig.Algorithm = test.algorithm
+
+			algorithm, err := NewChainPowAlgorithm(cfg)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("returned %v, expected %v", err, test.err)
+			}
+
+			if algorithm != test.expected {
+				t.Errorf("returned %q, expected %q", algorithm, test.expected)
+			}
+		})
+	}
+}
+
+func TestBlockchainKeepsItsPowAlgorithm(t *testing.T) {
+	cfg := testConfig()
+	cfg.ProofOfWorkConfig.Algorithm = ""
+	cfg.ServerConfig.Network = NetworkTest
+
+	_, address := testWallet(t, cfg)
+	store := NewMemoryChainStore(cfg)
+
+	_, err := NewBlockchainWithStore(cfg, store, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The blockchain is reopened by a node configured for another network
+	otherCfg := testConfig()
+	otherCfg.ServerConfig.Network = NetworkMain
+
+	bc, err := InitializeBlockchainWithStore(otherCfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bc.PowAlgorithm != PowScrypt {
+		t.Fatalf("blockchain uses %s, expected %s", bc.PowAlgorithm, PowScrypt)
+	}
+
+	block, err := bc.MineBlock([]*Transaction{testCoinbase(t, otherCfg, address)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for algorithm, expected := range map[string]bool{PowScrypt: true, PowSHA256: false} {
+		pow, err := NewProofOfWork(otherCfg, algorithm, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isValid, err := pow.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *isValid != expected {
+			t.Errorf("block validates with %s: %t, expected %t", algorithm, *isValid, expected)
+		}
+	}
+}
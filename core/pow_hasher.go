@@ -0,0 +1,110 @@
+package core
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Proof of work algorithms a blockchain can be created with
+const (
+	PowSHA256       = "sha256"
+	PowDoubleSHA256 = "double-sha256"
+	PowScrypt       = "scrypt"
+)
+
+// The scrypt parameters are part of the consensus rules, so they are fixed rather than configured. With N = 1024 and
+// r = 1 every hash needs 128 KiB of memory
+const (
+	scryptN      = 1024
+	scryptR      = 1
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+var (
+	ErrUnknownPowAlgorithm  = errors.New("unknown proof of work algorithm")
+	ErrPowAlgorithmMismatch = errors.New("proof of work algorithm does not match the blockchain")
+)
+
+// powAlgorithmKey is the key in the meta bucket of the proof of work algorithm the blockchain was created with
+var powAlgorithmKey = []byte("pow_algorithm")
+
+// PowHasher hashes the header data of a block for its proof of work
+type PowHasher interface {
+	Hash(data []byte) ([]byte, error)
+}
+
+// NewPowHasher returns the hasher of a proof of work algorithm
+func NewPowHasher(algorithm string) (PowHasher, error) {
+	switch algorithm {
+	case PowSHA256:
+		return sha256Hasher{}, nil
+	case PowDoubleSHA256:
+		return doubleSHA256Hasher{}, nil
+	case PowScrypt:
+		return scryptHasher{}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q, expected %s, %s or %s", ErrUnknownPowAlgorithm, algorithm, PowSHA256, PowDoubleSHA256, PowScrypt)
+}
+
+// sha256Hasher hashes with a single SHA-256
+type sha256Hasher struct{}
+
+// Hash returns the SHA-256 of the data
+func (sha256Hasher) Hash(data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+
+	return hash[:], nil
+}
+
+// doubleSHA256Hasher hashes with SHA-256 applied twice
+type doubleSHA256Hasher struct{}
+
+// Hash returns the SHA-256 of the SHA-256 of the data
+func (doubleSHA256Hasher) Hash(data []byte) ([]byte, error) {
+	firstSHA := sha256.Sum256(data)
+	secondSHA := sha256.Sum256(firstSHA[:])
+
+	return secondSHA[:], nil
+}
+
+// scryptHasher hashes with scrypt, whose memory use makes mining on dedicated hardware less of an advantage
+type scryptHasher struct{}
+
+// Hash returns the scrypt key of the data, salted with the data itself
+func (scryptHasher) Hash(data []byte) ([]byte, error) {
+	hash, err := scrypt.Key(data, data, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return hash, nil
+}
+
+// readPowAlgorithm reads the proof of work algorithm of the blockchain from the meta bucket. Blockchains created before
+// the algorithm was recorded use SHA-256
+func readPowAlgorithm(cfg *model.Config, tx ChainTx) string {
+	algorithm := tx.Record(cfg.DatabaseConfig.MetaBucket, powAlgorithmKey)
+	if algorithm == nil {
+		return PowSHA256
+	}
+
+	return string(algorithm)
+}
+
+// putPowAlgorithm records the proof of work algorithm of the blockchain, which its blocks are validated and new ones
+// mined with from then on
+func putPowAlgorithm(cfg *model.Config, tx ChainTx, algorithm string) error {
+	_, err := NewPowHasher(algorithm)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return tx.PutRecord(cfg.DatabaseConfig.MetaBucket, powAlgorithmKey, []byte(algorithm))
+}
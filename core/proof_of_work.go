@@ -2,7 +2,6 @@ package core
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"fmt"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
@@ -16,32 +15,52 @@ var (
 
 // ProofOfWork represents a proof-of-work
 type ProofOfWork struct {
-	cfg    *model.Config
-	block  *Block
-	target *big.Int
+	cfg       *model.Config
+	algorithm string
+	header    *BlockHeader
+	target    *big.Int
 }
 
-// NewProofOfWork generates and returns a proof of work
-func NewProofOfWork(cfg *model.Config, b *Block) (*ProofOfWork, error) {
+// NewProofOfWork generates and returns a proof of work with the hash algorithm of the blockchain
+func NewProofOfWork(cfg *model.Config, algorithm string, b *Block) (*ProofOfWork, error) {
+	header, err := b.Header()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return NewHeaderProofOfWork(cfg, algorithm, header), nil
+}
+
+// NewHeaderProofOfWork generates and returns a proof of work over a block header with the hash algorithm of the blockchain
+func NewHeaderProofOfWork(cfg *model.Config, algorithm string, header *BlockHeader) *ProofOfWork {
 	targetBits := cfg.ProofOfWorkConfig.TargetBits
 
 	target := big.NewInt(1)
 	target.Lsh(target, uint(256-targetBits))
 
 	pow := &ProofOfWork{
-		cfg:    cfg,
-		block:  b,
-		target: target,
+		cfg:       cfg,
+		algorithm: algorithm,
+		header:    header,
+		target:    target,
 	}
 
-	return pow, nil
+	return pow
+}
+
+// Work returns the expected number of hashes needed to find a proof of work below the target
+func (pow *ProofOfWork) Work() *big.Int {
+	maxHash := new(big.Int).Lsh(big.NewInt(1), 256)
+	targetRange := new(big.Int).Add(pow.target, big.NewInt(1))
+
+	return maxHash.Div(maxHash, targetRange)
 }
 
 // prepareData prepares data for the proof of work
 func (pow *ProofOfWork) prepareData(nonce int) ([]byte, error) {
 	targetBits := pow.cfg.ProofOfWorkConfig.TargetBits
 
-	timestampBytes, err := utils.IntToHex(pow.block.Timestamp)
+	timestampBytes, err := utils.IntToHex(pow.header.Timestamp)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
@@ -56,30 +75,38 @@ func (pow *ProofOfWork) prepareData(nonce int) ([]byte, error) {
 		return nil, utils.CatchErr(err)
 	}
 
-	hashedTransactions, err := pow.block.HashTransactions()
-	if err != nil {
-		return nil, utils.CatchErr(err)
+	fields := [][]byte{
+		pow.header.PrevBlockHash,
+		pow.header.MerkleRoot,
+		timestampBytes,
+		targetBitsBytes,
+		nonceBytes,
+	}
+
+	// Version 0 blocks were mined before the version was part of the header
+	if pow.header.Version >= 1 {
+		versionBytes, err := utils.IntToHex(int64(pow.header.Version))
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		fields = append([][]byte{versionBytes}, fields...)
 	}
 
-	data := bytes.Join(
-		[][]byte{
-			pow.block.PrevBlockHash,
-			hashedTransactions,
-			timestampBytes,
-			targetBitsBytes,
-			nonceBytes,
-		}, []byte{},
-	)
-
-	return data, nil
+	return bytes.Join(fields, []byte{}), nil
 }
 
-// Run runs the proof of work
+// Run runs the proof of work with the hash algorithm of the blockchain
 func (pow *ProofOfWork) Run() (*int, []byte, error) {
 	var hashInt big.Int
-	var hash [32]byte
+	var hash []byte
 	nonce := 0
 
+	hasher, err := NewPowHasher(pow.algorithm)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
 	fmt.Println("Mining a new block...")
 	for nonce < maxNonce {
 		data, err := pow.prepareData(nonce)
@@ -87,9 +114,13 @@ func (pow *ProofOfWork) Run() (*int, []byte, error) {
 			return nil, nil, utils.CatchErr(err)
 		}
 
-		hash = sha256.Sum256(data)
+		hash, err = hasher.Hash(data)
+		if err != nil {
+			return nil, nil, utils.CatchErr(err)
+		}
+
 		fmt.Printf("\r%x", hash)
-		hashInt.SetBytes(hash[:])
+		hashInt.SetBytes(hash)
 
 		if hashInt.Cmp(pow.target) == -1 {
 			break
@@ -99,22 +130,31 @@ func (pow *ProofOfWork) Run() (*int, []byte, error) {
 	}
 	fmt.Print("\n\n")
 
-	return &nonce, hash[:], nil
+	return &nonce, hash, nil
 }
 
-// Validate validates a block's proof of work
+// Validate validates a block's proof of work with the hash algorithm of the blockchain and that it hashes to the stored block hash
 func (pow *ProofOfWork) Validate() (*bool, error) {
 	var hashInt big.Int
 
-	data, err := pow.prepareData(pow.block.Nonce)
+	hasher, err := NewPowHasher(pow.algorithm)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	data, err := pow.prepareData(pow.header.Nonce)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	hash, err := hasher.Hash(data)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	hash := sha256.Sum256(data)
-	hashInt.SetBytes(hash[:])
+	hashInt.SetBytes(hash)
 
-	isValid := hashInt.Cmp(pow.target) == -1
+	isValid := hashInt.Cmp(pow.target) == -1 && bytes.Equal(hash, pow.header.Hash)
 
 	return &isValid, nil
 }
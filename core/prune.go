@@ -0,0 +1,198 @@
+package core
+
+import (
+	"go-burrokuchen/utils"
+)
+
+// IsPruned checks whether the transactions of the block were deleted, leaving only its header
+func (b *Block) IsPruned() bool {
+	return len(b.Transactions) == 0 && b.MerkleRoot != nil
+}
+
+// prunedBlock returns a copy of the block without its transactions, keeping what its header needs
+func (b *Block) prunedBlock() (*Block, error) {
+	header, err := b.Header()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	block := Block{
+		Version:       b.Version,
+		Timestamp:     b.Timestamp,
+		PrevBlockHash: b.PrevBlockHash,
+		Hash:          b.Hash,
+		Nonce:         b.Nonce,
+		MerkleRoot:    header.MerkleRoot,
+	}
+
+	return &block, nil
+}
+
+// Prune deletes the transactions of the blocks deeper than the configured prune depth, keeping their headers,
+// the height index, the filters and the UTXO set, and returns how many blocks were pruned. Blocks a webhook has not
+// scanned yet are kept, so that the payments they carry are still notified
+func (bc *Blockchain) Prune() (*int, error) {
+	depth := bc.cfg.DatabaseConfig.PruneDepth
+	pruned := 0
+
+	if depth <= 0 {
+		return &pruned, nil
+	}
+
+	bestHeight, err := bc.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	pruneHeight, err := bc.PruneHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	lastHeight := *bestHeight - depth
+
+	webhooks, err := NewWebhooks(bc.cfg, bc).List()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	for _, webhook := range webhooks {
+		lastHeight = min(lastHeight, webhook.ScannedHeight)
+	}
+
+	err = bc.Store.Update(func(tx ChainTx) error {
+		for height := *pruneHeight + 1; height <= lastHeight; height++ {
+			hash, err := tx.BlockHash(height)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			block, err := tx.Block(hash)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			prunedBlock, err := block.prunedBlock()
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			err = tx.PutBlock(prunedBlock)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			pruned++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &pruned, nil
+}
+
+// PruneHeight returns the height of the last pruned block, or -1 when no block was pruned
+func (bc *Blockchain) PruneHeight() (*int, error) {
+	bestHeight, err := bc.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// Blocks are pruned from the genesis block up, so the pruned blocks are found by a binary search
+	low, high := 0, *bestHeight+1
+
+	err = bc.Store.View(func(tx ChainTx) error {
+		for low < high {
+			middle := (low + high) / 2
+
+			hash, err := tx.BlockHash(middle)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			block, err := tx.Block(hash)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			if block.IsPruned() {
+				low = middle + 1
+			} else {
+				high = middle
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	pruneHeight := low - 1
+
+	return &pruneHeight, nil
+}
+
+// GetBlockHeader returns the header of the block with the given hash, which is kept when the block is pruned
+func (bc *Blockchain) GetBlockHeader(hash []byte) (*BlockHeader, error) {
+	var header *BlockHeader
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		block, err := tx.Block(hash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		header, err = block.Header()
+
+		return err
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return header, nil
+}
+
+// unspentOutputsTransaction rebuilds the outputs of a transaction of a pruned block that are still in the UTXO set,
+// enough to verify and sign inputs spending them, returning prunedErr when none are left
+func (bc *Blockchain) unspentOutputsTransaction(ID []byte, prunedErr error) (*Transaction, error) {
+	var transaction *Transaction
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		outs, err := tx.UTXOs(ID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if outs == nil {
+			return prunedErr
+		}
+
+		transaction = outputsTransaction(ID, *outs)
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return transaction, nil
+}
+
+// outputsTransaction rebuilds a transaction from its unspent outputs, leaving spent outputs empty at their index
+func outputsTransaction(ID []byte, outs TXOutputs) *Transaction {
+	var outputs []TXOutput
+	for i, out := range outs.Outputs {
+		for len(outputs) <= outs.Index(i) {
+			outputs = append(outputs, TXOutput{})
+		}
+
+		outputs[outs.Index(i)] = out
+	}
+
+	return &Transaction{ID: ID, OutputValue: outputs}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testChain is a blockchain in memory whose genesis block pays the wallet, with a payment of the genesis reward to
+// another address in block 1 followed by blocks paying coinbase rewards to the wallet
+type testChain struct {
+	bc           *Blockchain
+	wallet       *Wallet
+	address      string
+	otherAddress string
+	blocks       []*Block
+}
+
+// newTestChain mines a test chain up to the given height, which is at least 1
+func newTestChain(t *testing.T, height int) *testChain {
+	t.Helper()
+
+	cfg := testConfig()
+	wallet, address := testWallet(t, cfg)
+	_, otherAddress := testWallet(t, cfg)
+
+	bc, err := NewBlockchainWithStore(cfg, NewMemoryChainStore(cfg), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis, err := bc.GetBlock(bc.Tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := &testChain{bc: bc, wallet: wallet, address: address, otherAddress: otherAddress, blocks: []*Block{genesis}}
+
+	payment := testPayment(t, cfg, wallet, genesis.Transactions[0], otherAddress, 10)
+	chain.mine(t, testCoinbase(t, cfg, address), payment)
+
+	for len(chain.blocks) <= height {
+		chain.mine(t, testCoinbase(t, cfg, address))
+	}
+
+	return chain
+}
+
+// mine mines the transactions into a new block of the chain
+func (c *testChain) mine(t *testing.T, transactions ...*Transaction) *Block {
+	t.Helper()
+
+	block, err := c.bc.MineBlock(transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.blocks = append(c.blocks, block)
+
+	return block
+}
+
+// prune prunes the chain at the given depth and returns the height of the last pruned block
+func (c *testChain) prune(t *testing.T, depth int) int {
+	t.Helper()
+
+	c.bc.cfg.DatabaseConfig.PruneDepth = depth
+
+	_, err := c.bc.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruneHeight, err := c.bc.PruneHeight()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *pruneHeight
+}
+
+// exportTestChain writes the chain file of the blocks up to the given height
+func exportTestChain(t *testing.T, chain *testChain, height int) []byte {
+	t.Helper()
+
+	var file bytes.Buffer
+
+	err := writeChainFileHeader(&file, ChainFileHeader{PowAlgorithm: chain.bc.PowAlgorithm})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range chain.blocks[:height+1] {
+		writeTestChainFileBlock(t, &file, block)
+	}
+
+	return file.Bytes()
+}
+
+// writeTestChainFileBlock writes a block to a chain file, preceded by its length
+func writeTestChainFileBlock(t *testing.T, file *bytes.Buffer, block *Block) {
+	t.Helper()
+
+	serializedBlock, err := block.SerializeBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary.Write(file, binary.BigEndian, uint32(len(serializedBlock)))
+	file.Write(serializedBlock)
+}
+
+// importTestChain imports a chain file into the blockchain
+func importTestChain(bc *Blockchain, file []byte) error {
+	r := bufio.NewReader(bytes.NewReader(file))
+
+	_, err := ReadChainFileHeader(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = bc.ImportChain(r, func(height int) {})
+
+	return err
+}
+
+func TestTrackAddressesSkipsPrunedBlocks(t *testing.T) {
+	chain := newTestChain(t, 4)
+
+	if pruneHeight := chain.prune(t, 2); pruneHeight != 2 {
+		t.Fatalf("pruned up to height %d, expected 2", pruneHeight)
+	}
+
+	tracker, err := chain.bc.TrackAddresses([][]byte{DecodeAddress(chain.bc.cfg, chain.address)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tracker.PrunedHeight != 2 {
+		t.Errorf("tracker reports pruned height %d, expected 2", tracker.PrunedHeight)
+	}
+
+	for _, entry := range tracker.History {
+		if entry.Height <= 2 {
+			t.Errorf("history lists a transaction of pruned block %d", entry.Height)
+		}
+	}
+
+	if len(tracker.History) != 2 {
+		t.Errorf("history has %d entries, expected the rewards of blocks 3 and 4", len(tracker.History))
+	}
+
+	// The genesis reward was spent, the rewards of blocks 1 to 4 are unspent
+	balance := 0
+	for _, utxo := range tracker.UTXOs {
+		balance += utxo.Output.Value
+	}
+
+	if len(tracker.UTXOs) != 4 || balance != 40 {
+		t.Errorf("tracked %d outputs worth %d, expected 4 outputs worth 40", len(tracker.UTXOs), balance)
+	}
+
+	// The payment to the other address was only confirmed in a pruned block
+	otherTracker, err := chain.bc.TrackAddresses([][]byte{DecodeAddress(chain.bc.cfg, chain.otherAddress)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(otherTracker.UTXOs) != 1 || otherTracker.UTXOs[0].Output.Value != 10 {
+		t.Errorf("tracked %+v, expected the payment of block 1", otherTracker.UTXOs)
+	}
+}
+
+func TestPruneKeepsBlocksWebhooksHaveNotScanned(t *testing.T) {
+	chain := newTestChain(t, 4)
+
+	webhook := &Webhook{ID: "webhook", Address: chain.address, URL: "http://localhost", Confirmations: 1, ScannedHeight: 1}
+
+	err := NewWebhooks(chain.bc.cfg, chain.bc).Put(webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pruneHeight := chain.prune(t, 1); pruneHeight != 1 {
+		t.Errorf("pruned up to height %d, expected the scanned height 1", pruneHeight)
+	}
+}
+
+func TestImportChainOnPrunedBlockchain(t *testing.T) {
+	source := newTestChain(t, 4)
+	cfg := testConfig()
+
+	target := &Blockchain{cfg: cfg, Store: NewMemoryChainStore(cfg), PowAlgorithm: source.bc.PowAlgorithm}
+
+	err := importTestChain(target, exportTestChain(t, source, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.DatabaseConfig.PruneDepth = 1
+
+	_, err = target.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rest of the chain is imported on top of the pruned blocks
+	err = importTestChain(target, exportTestChain(t, source, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(target.Tip, source.bc.Tip) {
+		t.Errorf("imported up to %x, expected %x", target.Tip, source.bc.Tip)
+	}
+}
+
+func TestImportChainRejectsSpentOutputs(t *testing.T) {
+	source := newTestChain(t, 2)
+	cfg := source.bc.cfg
+
+	target := &Blockchain{cfg: cfg, Store: NewMemoryChainStore(cfg), PowAlgorithm: source.bc.PowAlgorithm}
+
+	err := importTestChain(target, exportTestChain(t, source, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The genesis reward was already paid to the other address in block 1
+	doubleSpend := testPayment(t, cfg, source.wallet, source.blocks[0].Transactions[0], source.address, 10)
+	block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{testCoinbase(t, cfg, source.address), doubleSpend}, source.bc.Tip)
+
+	file := bytes.NewBuffer(exportTestChain(t, source, 2))
+	writeTestChainFileBlock(t, file, block)
+
+	err = importTestChain(target, file.Bytes())
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("imported a block spending a spent output with %v, expected %v", err, ErrInvalidTransaction)
+	}
+}
+
+func TestExportChainRefusesPrunedBlockchain(t *testing.T) {
+	chain := newTestChain(t, 4)
+	chain.prune(t, 2)
+
+	var file bytes.Buffer
+
+	err := chain.bc.ExportChain(&file, func(height int) {})
+	if !errors.Is(err, ErrBlockPruned) {
+		t.Fatalf("exported with %v, expected %v", err, ErrBlockPruned)
+	}
+
+	if file.Len() != 0 {
+		t.Errorf("wrote %d bytes before refusing the export", file.Len())
+	}
+}
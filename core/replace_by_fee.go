@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"go-burrokuchen/utils"
+)
+
+// NewFeeBumpTransaction returns an unsigned replacement of a pending transaction spending the same inputs with a higher fee,
+// taking the difference from its change output, the last plain output locked to the key of one of its inputs
+func NewFeeBumpTransaction(entry *MempoolEntry, fee int) (*Transaction, error) {
+	increase := fee - entry.Fee
+	if increase <= 0 {
+		return nil, fmt.Errorf("fee must be higher than the current fee of %d", entry.Fee)
+	}
+
+	tx := entry.Transaction
+	change := -1
+
+	for _, vin := range tx.InputValue {
+		pubKeyHash, err := HashPubKey(vin.PubKey)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		for i, out := range tx.OutputValue {
+			if i > change && out.HTLC == nil && !out.IsData() && out.IsLockedWithKey(pubKeyHash) {
+				change = i
+			}
+		}
+	}
+
+	if change < 0 {
+		return nil, fmt.Errorf("transaction %x has no change output to take the fee from", tx.ID)
+	}
+
+	if tx.OutputValue[change].Value < increase {
+		return nil, fmt.Errorf("change of %d cannot pay a fee increase of %d", tx.OutputValue[change].Value, increase)
+	}
+
+	var inputs []TXInput
+	for _, vin := range tx.InputValue {
+		vin.Signature = nil
+		inputs = append(inputs, vin)
+	}
+
+	var outputs []TXOutput
+	for i, out := range tx.OutputValue {
+		if i == change {
+			out.Value -= increase
+
+			// A change output left empty is dropped
+			if out.Value == 0 {
+				continue
+			}
+		}
+
+		outputs = append(outputs, out)
+	}
+
+	replacement := Transaction{ID: nil, InputValue: inputs, OutputValue: outputs, LockTime: tx.LockTime}
+	hash, err := replacement.Hash()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	replacement.ID = hash
+
+	return &replacement, nil
+}
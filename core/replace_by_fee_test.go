@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewFeeBumpTransaction(t *testing.T) {
+	cfg := testConfig()
+	wallet, address := testWallet(t, cfg)
+	_, otherAddress := testWallet(t, cfg)
+
+	payment := TXOutput{Value: 50, PubKeyHash: DecodeAddress(cfg, otherAddress)}
+	change := TXOutput{Value: 40, PubKeyHash: DecodeAddress(cfg, address)}
+	inputs := []TXInput{{TransactionID: []byte{1}, PubKey: wallet.PublicKey, Signature: []byte{1}}}
+
+	tests := []struct {
+		name        string
+		outputs     []TXOutput
+		fee         int
+		expected    []int
+		expectedErr bool
+	}{
+		{name: "fee taken from the change", outputs: []TXOutput{payment, change}, fee: 20, expected: []int{50, 30}},
+		{name: "change used up", outputs: []TXOutput{payment, change}, fee: 50, expected: []int{50}},
+		{name: "fee not increased", outputs: []TXOutput{payment, change}, fee: 10, expectedErr: true},
+		{name: "change too small", outputs: []TXOutput{payment, change}, fee: 60, expectedErr: true},
+		{name: "no change output", outputs: []TXOutput{payment}, fee: 20, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := MempoolEntry{Transaction: Transaction{ID: []byte{2}, InputValue: inputs, OutputValue: test.outputs}, Fee: 10}
+
+			replacement, err := NewFeeBumpTransaction(&entry, test.fee)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatal("bumped the fee without an error")
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(replacement.OutputValue) != len(test.expected) {
+				t.Fatalf("replacement has %d outputs, expected %d", len(replacement.OutputValue), len(test.expected))
+			}
+
+			for i, out := range replacement.OutputValue {
+				if out.Value != test.expected[i] {
+					t.Errorf("output %d is worth %d, expected %d", i, out.Value, test.expected[i])
+				}
+			}
+
+			if replacement.InputValue[0].Signature != nil {
+				t.Error("replacement kept the signature of the pending transaction")
+			}
+
+			err = replacement.CheckID()
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFeeBumpReplacesPendingTransaction(t *testing.T) {
+	chain := newTestChain(t, 2)
+	cfg := chain.bc.cfg
+	coinbase := chain.blocks[2].Transactions[0]
+
+	tx := &Transaction{
+		InputValue: []TXInput{{TransactionID: coinbase.ID, OutputIndex: 0, PubKey: chain.wallet.PublicKey}},
+		OutputValue: []TXOutput{
+			{Value: 4, PubKeyHash: DecodeAddress(cfg, chain.otherAddress)},
+			{Value: 5, PubKeyHash: DecodeAddress(cfg, chain.address)},
+		},
+	}
+
+	hash, err := tx.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.ID = hash
+
+	err = chain.bc.SignTransaction(tx, chain.wallet.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mempool := NewMempool(cfg, chain.bc)
+
+	entry, err := mempool.Add(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacement, err := NewFeeBumpTransaction(entry, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mempool.Add(replacement)
+	if err == nil {
+		t.Fatal("added the replacement before it was signed again")
+	}
+
+	err = chain.bc.SignTransaction(replacement, chain.wallet.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacementEntry, err := mempool.Add(replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replacementEntry.Fee != 3 {
+		t.Errorf("replacement pays a fee of %d, expected 3", replacementEntry.Fee)
+	}
+
+	_, err = mempool.Get(tx.ID)
+	if !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("got the replaced transaction with %v, expected %v", err, ErrTransactionNotFound)
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/utils"
+)
+
+// LockTimeThreshold separates lock times given as a block height from lock times given as a Unix timestamp
+const LockTimeThreshold = 500000000
+
+// IsFinal checks whether the transaction can be mined into a block with the given height and timestamp
+func (tx *Transaction) IsFinal(height int, timestamp int64) bool {
+	if tx.LockTime <= 0 {
+		return true
+	}
+
+	if tx.LockTime < LockTimeThreshold {
+		return int64(height) >= tx.LockTime
+	}
+
+	return timestamp >= tx.LockTime
+}
+
+// IsMature checks whether an output confirmed at the given height can be spent in a block with the given height
+func (out *TXOutput) IsMature(confirmedHeight int, height int) bool {
+	return height >= confirmedHeight+out.RelativeLock
+}
+
+// CheckLocks checks the lock time of the transaction and the relative locks of the outputs it spends
+// against a block with the given height and timestamp, returning ErrTransactionLocked if it cannot be mined yet
+func (bc *Blockchain) CheckLocks(tx *Transaction, height int, timestamp int64) error {
+	return bc.checkLocks(tx, height, timestamp, nil)
+}
+
+// checkLocks is CheckLocks for a transaction that may spend outputs of pending transactions, which would
+// confirm in the same block at the earliest
+func (bc *Blockchain) checkLocks(tx *Transaction, height int, timestamp int64, pending map[string]Transaction) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	if !tx.IsFinal(height, timestamp) {
+		return fmt.Errorf("%w: lock time %d is not reached", ErrTransactionLocked, tx.LockTime)
+	}
+
+	prevTXs, err := bc.prevTransactions(tx, pending)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, vin := range tx.InputValue {
+		prevTXID := hex.EncodeToString(vin.TransactionID)
+		prevTX := prevTXs[prevTXID]
+
+		if vin.OutputIndex < 0 || vin.OutputIndex >= len(prevTX.OutputValue) {
+			return ErrInvalidTransaction
+		}
+
+		out := prevTX.OutputValue[vin.OutputIndex]
+		if out.RelativeLock <= 0 {
+			continue
+		}
+
+		confirmedHeight := &height
+
+		if _, isPending := pending[prevTXID]; !isPending {
+			confirmedHeight, err = bc.confirmationHeight(vin.TransactionID)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		if !out.IsMature(*confirmedHeight, height) {
+			return fmt.Errorf("%w: output %x:%d is spendable from height %d", ErrTransactionLocked, vin.TransactionID, vin.OutputIndex, *confirmedHeight+out.RelativeLock)
+		}
+	}
+
+	return nil
+}
+
+// confirmationHeight returns the height of the block that confirmed the transaction or, when that block was pruned,
+// the height of the last pruned block, which is never below it and so never lets a locked output mature early
+func (bc *Blockchain) confirmationHeight(ID []byte) (*int, error) {
+	height, err := bc.GetTransactionHeight(ID)
+	if errors.Is(err, ErrBlockPruned) {
+		return bc.PruneHeight()
+	}
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return height, nil
+}
+
+// GetTransactionHeight returns the height of the block that confirmed the transaction
+func (bc *Blockchain) GetTransactionHeight(ID []byte) (*int, error) {
+	block, err := bc.FindTransactionBlock(ID)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	height, err := bc.GetBlockHeight(block.Hash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return height, nil
+}
@@ -9,22 +9,45 @@ import (
 	"encoding/gob"
 	"encoding/hex"
 	"fmt"
+	legacy "go-burrokuchen/core/legacy"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
+	"io"
 	"math/big"
 )
 
+// init registers the hashed types with gob in a fixed order, after the version 0 transaction types. gob numbers types
+// in the order a process first sees them and writes these numbers into its output, so without this a transaction
+// serialized after a block was decoded hashes differently, breaking signatures, Merkle proofs and UTXO snapshot hashes
+// across processes.
+func init() {
+	gob.NewEncoder(io.Discard).Encode(Transaction{})
+	gob.NewEncoder(io.Discard).Encode(Block{})
+	gob.NewEncoder(io.Discard).Encode(TXOutputs{})
+}
+
 // Transaction represents a transaction
 type Transaction struct {
 	ID          []byte
 	InputValue  []TXInput
 	OutputValue []TXOutput
+	LockTime    int64
 }
 
-// Serialize returns a serialized Transaction
+// Serialize returns a serialized Transaction. Transactions that use none of the lock time, contract and data fields are
+// serialized in the version 0 format, so that their IDs and the signatures and Merkle roots over them do not change
 func (tx Transaction) Serialize() ([]byte, error) {
 	var encoded bytes.Buffer
 
+	if !tx.usesExtendedFields() {
+		serializedTx, err := tx.legacyTransaction().Serialize()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return serializedTx, nil
+	}
+
 	enc := gob.NewEncoder(&encoded)
 	err := enc.Encode(tx)
 	if err != nil {
@@ -34,6 +57,42 @@ func (tx Transaction) Serialize() ([]byte, error) {
 	return encoded.Bytes(), nil
 }
 
+// usesExtendedFields checks whether the transaction sets any of the fields added after the version 0 format
+func (tx *Transaction) usesExtendedFields() bool {
+	if tx.LockTime != 0 {
+		return true
+	}
+
+	for _, vin := range tx.InputValue {
+		if len(vin.Secret) != 0 {
+			return true
+		}
+	}
+
+	for _, vout := range tx.OutputValue {
+		if vout.RelativeLock != 0 || vout.HTLC != nil || len(vout.Data) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// legacyTransaction returns the transaction in the version 0 format
+func (tx *Transaction) legacyTransaction() legacy.Transaction {
+	legacyTx := legacy.Transaction{ID: tx.ID}
+
+	for _, vin := range tx.InputValue {
+		legacyTx.InputValue = append(legacyTx.InputValue, legacy.TXInput{TransactionID: vin.TransactionID, OutputIndex: vin.OutputIndex, Signature: vin.Signature, PubKey: vin.PubKey})
+	}
+
+	for _, vout := range tx.OutputValue {
+		legacyTx.OutputValue = append(legacyTx.OutputValue, legacy.TXOutput{Value: vout.Value, PubKeyHash: vout.PubKeyHash})
+	}
+
+	return legacyTx
+}
+
 // Hash returns the hash of the Transaction
 func (tx *Transaction) Hash() ([]byte, error) {
 	var hash [32]byte
@@ -51,12 +110,47 @@ func (tx *Transaction) Hash() ([]byte, error) {
 	return hash[:], nil
 }
 
+// CheckID checks that the ID of the transaction is the hash of the transaction before it was signed, so that a
+// transaction cannot take the ID of another one and overwrite its outputs in the UTXO set
+func (tx *Transaction) CheckID() error {
+	txCopy := *tx
+	txCopy.InputValue = make([]TXInput, len(tx.InputValue))
+
+	for i, vin := range tx.InputValue {
+		vin.Signature = nil
+		txCopy.InputValue[i] = vin
+	}
+
+	hash, err := txCopy.Hash()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !bytes.Equal(tx.ID, hash) {
+		return fmt.Errorf("%w: ID %x is not the hash %x of the transaction", ErrInvalidTransaction, tx.ID, hash)
+	}
+
+	return nil
+}
+
 // NewCoinbaseTX generates and returns a new coinbase transaction
 func NewCoinbaseTX(cfg *model.Config, to string, data string) (*Transaction, error) {
-	subsidy := cfg.TransactionConfig.Subsidy
+	return NewCoinbaseTXWithFees(cfg, to, data, 0)
+}
+
+// NewCoinbaseTXWithFees generates and returns a new coinbase transaction paying the subsidy and the fees of the other transactions of its block
+func NewCoinbaseTXWithFees(cfg *model.Config, to string, data string, fees int) (*Transaction, error) {
+	subsidy := cfg.TransactionConfig.Subsidy + fees
 
 	if data == "" {
-		data = fmt.Sprintf("Reward sent to: %s", to)
+		// Random bytes keep coinbase transactions to the same address from sharing an ID
+		randData := make([]byte, 20)
+		_, err := rand.Read(randData)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		data = fmt.Sprintf("Reward sent to: %s (%x)", to, randData)
 	}
 
 	txIn := TXInput{
@@ -81,38 +175,103 @@ func NewCoinbaseTX(cfg *model.Config, to string, data string) (*Transaction, err
 	return &tx, nil
 }
 
+// Fee returns what the inputs of the transaction leave to the miner on top of its outputs,
+// which is negative when the outputs spend more than the inputs hold
+func (tx *Transaction) Fee(prevTXs map[string]Transaction) int {
+	if tx.IsCoinbase() {
+		return 0
+	}
+
+	fee := 0
+
+	for _, vin := range tx.InputValue {
+		prevTX := prevTXs[hex.EncodeToString(vin.TransactionID)]
+		if vin.OutputIndex >= 0 && vin.OutputIndex < len(prevTX.OutputValue) {
+			fee += prevTX.OutputValue[vin.OutputIndex].Value
+		}
+	}
+
+	for _, vout := range tx.OutputValue {
+		fee -= vout.Value
+	}
+
+	return fee
+}
+
 // IsCoinbase checks whether the transaction is coinbase or not
 func (tx *Transaction) IsCoinbase() bool {
 	return len(tx.InputValue) == 1 && len(tx.InputValue[0].TransactionID) == 0 && tx.InputValue[0].OutputIndex == -1
 }
 
-// NewUTXOTransaction generates and returns a new transaction
-func NewUTXOTransaction(utxoSet UTXOSet, from string, to string, amount int) (*Transaction, error) {
-	var inputs []TXInput
-	var outputs []TXOutput
+// TransactionOptions are the fee and time locks of a new transaction
+type TransactionOptions struct {
+	// Fee is the amount left to the miner by the inputs on top of the outputs
+	Fee int
+	// LockTime is the height, or the timestamp from LockTimeThreshold on, before which the transaction cannot be mined
+	LockTime int64
+	// RelativeLock is the number of blocks after the payment output confirms before it can be spent
+	RelativeLock int
+}
 
+// NewUTXOTransaction generates and returns a new transaction
+func NewUTXOTransaction(utxoSet UTXOSet, from string, to string, amount int, options TransactionOptions) (*Transaction, error) {
 	wallets, err := NewWallets(utxoSet.cfg)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
-	wallet := wallets.GetWallet(from)
+	wallet, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
 	pubKeyHash, err := HashPubKey(wallet.PublicKey)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	balance, validOutputs, err := utxoSet.FindSpendableOutputs(pubKeyHash, amount)
+	balance, validOutputs, err := utxoSet.FindSpendableOutputs(pubKeyHash, amount+options.Fee)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tx, err := NewTransaction(utxoSet.cfg, wallet.PublicKey, from, to, amount, *balance, validOutputs, options)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = utxoSet.Blockchain.SignTransaction(tx, wallet.PrivateKey)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	if *balance < amount {
+	return tx, nil
+}
+
+// NewTransaction generates and returns an unsigned transaction spending the given outputs, sending the change back to the sender
+func NewTransaction(cfg *model.Config, pubKey []byte, from string, to string, amount int, balance int, validOutputs map[string][]int, options TransactionOptions) (*Transaction, error) {
+	if balance < amount+options.Fee {
 		err := fmt.Errorf("%s doesn't have enough funds", from)
 
 		return nil, utils.CatchErr(err)
 	}
 
-	// Build a list of inputs
+	inputs, err := newInputs(pubKey, validOutputs)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	tx, err := NewTransactionFromInputs(cfg, inputs, to, from, amount, balance, options)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return tx, nil
+}
+
+// newInputs builds unsigned inputs spending the given outputs with the public key
+func newInputs(pubKey []byte, validOutputs map[string][]int) ([]TXInput, error) {
+	var inputs []TXInput
+
 	for txID, outputs := range validOutputs {
 		transactionID, err := hex.DecodeString(txID)
 		if err != nil {
@@ -124,23 +283,128 @@ func NewUTXOTransaction(utxoSet UTXOSet, from string, to string, amount int) (*T
 				TransactionID: transactionID,
 				OutputIndex:   outIndex,
 				Signature:     nil,
-				PubKey:        wallet.PublicKey,
+				PubKey:        pubKey,
 			}
 
 			inputs = append(inputs, input)
 		}
 	}
 
-	// Build a list of outputs
-	output, err := NewTXOutput(utxoSet.cfg, amount, to)
+	return inputs, nil
+}
+
+// NewAccountTransaction generates and returns a transaction spending outputs of the addresses of an account, signed with the key of each input
+func NewAccountTransaction(utxoSet UTXOSet, wallets *Wallets, account string, to string, amount int, options TransactionOptions) (*Transaction, error) {
+	addresses, err := wallets.GetAccountAddresses(account)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var inputs []TXInput
+	var privKeys []ecdsa.PrivateKey
+	var change string
+
+	accumulated := 0
+	total := amount + options.Fee
+
+	for _, address := range addresses {
+		if accumulated >= total {
+			break
+		}
+
+		// Watch-only addresses count towards the balance of the account but cannot pay
+		if wallets.IsWatchOnly(address) {
+			continue
+		}
+
+		wallet := wallets.Wallets[address]
+
+		pubKeyHash, err := HashPubKey(wallet.PublicKey)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		balance, validOutputs, err := utxoSet.FindSpendableOutputs(pubKeyHash, total-accumulated)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if *balance == 0 {
+			continue
+		}
+
+		// The change goes back to the first address that pays
+		if change == "" {
+			change = address
+		}
+
+		accumulated += *balance
+
+		for txID, outputs := range validOutputs {
+			transactionID, err := hex.DecodeString(txID)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			for _, outIndex := range outputs {
+				inputs = append(inputs, TXInput{TransactionID: transactionID, OutputIndex: outIndex, PubKey: wallet.PublicKey})
+				privKeys = append(privKeys, wallet.PrivateKey)
+			}
+		}
+	}
+
+	if accumulated < total {
+		err := fmt.Errorf("account %s doesn't have enough funds", account)
+
+		return nil, utils.CatchErr(err)
+	}
+
+	tx, err := NewTransactionFromInputs(utxoSet.cfg, inputs, to, change, amount, accumulated, options)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = utxoSet.Blockchain.SignTransactionInputs(tx, privKeys)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return tx, nil
+}
+
+// NewTransactionFromInputs generates and returns an unsigned transaction spending the inputs, which may be locked to different keys, and sending the change to the change address
+func NewTransactionFromInputs(cfg *model.Config, inputs []TXInput, to string, change string, amount int, balance int, options TransactionOptions) (*Transaction, error) {
+	output, err := NewTXOutput(cfg, amount, to)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// Only the payment is locked, the change stays spendable
+	output.RelativeLock = options.RelativeLock
+
+	if options.Fee < 0 {
+		return nil, fmt.Errorf("fee cannot be negative")
+	}
+
+	if balance < amount+options.Fee {
+		return nil, fmt.Errorf("inputs of %d cannot pay %d and a fee of %d", balance, amount, options.Fee)
+	}
+
+	// The fee is whatever the outputs leave of the inputs, so it is held back from the change
+	tx, err := newTransactionWithOutput(cfg, inputs, *output, change, balance-options.Fee, options.LockTime)
 	if err != nil {
 		return nil, utils.CatchErr(err)
 	}
 
-	outputs = append(outputs, *output)
+	return tx, nil
+}
 
-	if *balance > amount {
-		outputChange, err := NewTXOutput(utxoSet.cfg, *balance-amount, from)
+// newTransactionWithOutput generates and returns an unsigned transaction spending the inputs into the output, sending what is left of the balance to the change address
+func newTransactionWithOutput(cfg *model.Config, inputs []TXInput, output TXOutput, change string, balance int, lockTime int64) (*Transaction, error) {
+	outputs := []TXOutput{output}
+
+	if balance > output.Value {
+		outputChange, err := NewTXOutput(cfg, balance-output.Value, change)
 		if err != nil {
 			return nil, utils.CatchErr(err)
 		}
@@ -151,6 +415,7 @@ func NewUTXOTransaction(utxoSet UTXOSet, from string, to string, amount int) (*T
 		ID:          nil,
 		InputValue:  inputs,
 		OutputValue: outputs,
+		LockTime:    lockTime,
 	}
 	hash, err := tx.Hash()
 	if err != nil {
@@ -159,11 +424,22 @@ func NewUTXOTransaction(utxoSet UTXOSet, from string, to string, amount int) (*T
 
 	tx.ID = hash
 
-	utxoSet.Blockchain.SignTransaction(&tx, wallet.PrivateKey)
-
 	return &tx, nil
 }
 
+// DeserializeTransaction deserializes a transaction
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+	var transaction Transaction
+
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&transaction)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &transaction, nil
+}
+
 // TrimmedCopy creates a trimmed copy of Transaction to be used in signing
 func (tx *Transaction) TrimmedCopy() Transaction {
 	var inputs []TXInput
@@ -174,20 +450,34 @@ func (tx *Transaction) TrimmedCopy() Transaction {
 	}
 
 	for _, vout := range tx.OutputValue {
-		outputs = append(outputs, TXOutput{Value: vout.Value, PubKeyHash: vout.PubKeyHash})
+		outputs = append(outputs, TXOutput{Value: vout.Value, PubKeyHash: vout.PubKeyHash, RelativeLock: vout.RelativeLock, HTLC: vout.HTLC, Data: vout.Data})
 	}
 
-	txCopy := Transaction{ID: tx.ID, InputValue: inputs, OutputValue: outputs}
+	txCopy := Transaction{ID: tx.ID, InputValue: inputs, OutputValue: outputs, LockTime: tx.LockTime}
 
 	return txCopy
 }
 
 // Sign signs each input of a Transaction
 func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction) error {
+	privKeys := make([]ecdsa.PrivateKey, len(tx.InputValue))
+	for i := range privKeys {
+		privKeys[i] = privKey
+	}
+
+	return tx.SignInputs(privKeys, prevTXs)
+}
+
+// SignInputs signs every input of a Transaction with its own key
+func (tx *Transaction) SignInputs(privKeys []ecdsa.PrivateKey, prevTXs map[string]Transaction) error {
 	if tx.IsCoinbase() {
 		return nil
 	}
 
+	if len(privKeys) != len(tx.InputValue) {
+		return fmt.Errorf("got %d keys for %d inputs", len(privKeys), len(tx.InputValue))
+	}
+
 	txCopy := tx.TrimmedCopy()
 
 	for inputIndex, vin := range txCopy.InputValue {
@@ -202,11 +492,12 @@ func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transac
 		txCopy.ID = hashValue
 		txCopy.InputValue[inputIndex].PubKey = nil
 
-		r, s, err := ecdsa.Sign(rand.Reader, &privKey, txCopy.ID)
+		r, s, err := ecdsa.Sign(rand.Reader, &privKeys[inputIndex], txCopy.ID)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
-		signature := append(r.Bytes(), s.Bytes()...)
+		// r and s are padded, as Verify splits the signature in halves
+		signature := append(r.FillBytes(make([]byte, signatureValueLength)), s.FillBytes(make([]byte, signatureValueLength))...)
 
 		tx.InputValue[inputIndex].Signature = signature
 
@@ -224,6 +515,20 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) (*bool, error) {
 
 	for inputIndex, vin := range tx.InputValue {
 		prevTX := prevTXs[hex.EncodeToString(vin.TransactionID)]
+		if vin.OutputIndex < 0 || vin.OutputIndex >= len(prevTX.OutputValue) {
+			return &verified, nil
+		}
+
+		// The signature only proves ownership of the key in the input, which must be a key the output is locked to
+		isUnlocked, err := prevTX.OutputValue[vin.OutputIndex].IsUnlockedBy(vin, tx.LockTime)
+		if err != nil {
+			return &verified, utils.CatchErr(err)
+		}
+
+		if !*isUnlocked {
+			return &verified, nil
+		}
+
 		txCopy.InputValue[inputIndex].Signature = nil
 		txCopy.InputValue[inputIndex].PubKey = prevTX.OutputValue[vin.OutputIndex].PubKeyHash
 
@@ -240,13 +545,12 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) (*bool, error) {
 		r.SetBytes(vin.Signature[:(sigLen / 2)])
 		s.SetBytes(vin.Signature[(sigLen / 2):])
 
-		x := big.Int{}
-		y := big.Int{}
-		keyLen := len(vin.PubKey)
-		x.SetBytes(vin.PubKey[:(keyLen / 2)])
-		y.SetBytes(vin.PubKey[(keyLen / 2):])
+		x, y := publicKeyPoint(vin.PubKey)
+		if x == nil {
+			return &verified, nil
+		}
 
-		rawPubKey := ecdsa.PublicKey{Curve: curve, X: &x, Y: &y}
+		rawPubKey := ecdsa.PublicKey{Curve: curve, X: x, Y: y}
 		if !ecdsa.Verify(&rawPubKey, txCopy.ID, &r, &s) {
 			return &verified, nil
 		}
@@ -11,6 +11,7 @@ type TXInput struct {
 	OutputIndex   int
 	Signature     []byte
 	PubKey        []byte
+	Secret        []byte
 }
 
 // UsesKey checks whether the address initiated the transaction
@@ -3,15 +3,19 @@ package core
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
 )
 
 // TXOutput represents a transaction output
 type TXOutput struct {
-	cfg        *model.Config
-	Value      int
-	PubKeyHash []byte
+	cfg          *model.Config
+	Value        int
+	PubKeyHash   []byte
+	RelativeLock int
+	HTLC         *HTLC
+	Data         []byte
 }
 
 // Lock signs the output
@@ -30,6 +34,27 @@ func (out *TXOutput) IsLockedWithKey(pubKeyHash []byte) bool {
 	return bytes.Equal(out.PubKeyHash, pubKeyHash)
 }
 
+// IsUnlockedBy checks whether the input presents the key, and for contracts the secret or an expired timeout, that the output is locked to
+func (out *TXOutput) IsUnlockedBy(in TXInput, lockTime int64) (*bool, error) {
+	if out.IsData() {
+		result := false
+
+		return &result, nil
+	}
+
+	if out.HTLC != nil {
+		return out.HTLC.IsUnlockedBy(in, lockTime)
+	}
+
+	if len(in.Secret) != 0 {
+		result := false
+
+		return &result, nil
+	}
+
+	return in.UsesKey(out.PubKeyHash)
+}
+
 // NewTXOutput create a new TXOutput
 func NewTXOutput(cfg *model.Config, value int, address string) (*TXOutput, error) {
 	txo := &TXOutput{cfg: cfg, Value: value, PubKeyHash: nil}
@@ -41,9 +66,31 @@ func NewTXOutput(cfg *model.Config, value int, address string) (*TXOutput, error
 	return txo, nil
 }
 
+// CheckOutputValues checks that the outputs of the transaction hold a positive value, or none for data outputs, so that
+// no output can take back what another one pays
+func (tx *Transaction) CheckOutputValues() error {
+	for outIndex, out := range tx.OutputValue {
+		if out.Value < 0 || (out.Value == 0 && !out.IsData()) {
+			return fmt.Errorf("%w: output %d holds %d", ErrInvalidTransaction, outIndex, out.Value)
+		}
+	}
+
+	return nil
+}
+
 // TXOutputs represent a list of transaction outputs
 type TXOutputs struct {
 	Outputs []TXOutput
+	Indexes []int
+}
+
+// Index returns the position of the i-th output in its transaction
+func (outs TXOutputs) Index(i int) int {
+	if len(outs.Indexes) != len(outs.Outputs) {
+		return i
+	}
+
+	return outs.Indexes[i]
 }
 
 // Serialize serializes TXOutputs
@@ -0,0 +1,300 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// versionZeroTransactions are transactions that use none of the fields added after the version 0 format, with their
+// serialization and hash as computed by the release before those fields were added
+var versionZeroTransactions = []struct {
+	name       string
+	tx         Transaction
+	serialized string
+	hash       string
+}{
+	{
+		name: "coinbase",
+		tx: Transaction{
+			ID:          []byte{1, 2},
+			InputValue:  []TXInput{{TransactionID: []byte{}, OutputIndex: -1, PubKey: []byte("hello")}},
+			OutputValue: []TXOutput{{Value: 10, PubKeyHash: []byte{9, 9}}},
+		},
+		serialized: "407f0301010b5472616e73616374696f6e01ff8000010301024944010a00010a496e70757456616c756501ff8400010b4f757470757456616c756501ff880000001dff830201010e5b5d636f72652e5458496e70757401ff840001ff82000050ff81030101075458496e70757401ff82000104010d5472616e73616374696f6e4944010a00010b4f7574707574496e64657801040001095369676e6174757265010a0001065075624b6579010a0000001eff870201010f5b5d636f72652e54584f757470757401ff880001ff8600002fff850301010854584f757470757401ff86000102010556616c7565010400010a5075624b657948617368010a0000001cff800102010201010201020568656c6c6f0001010114010209090000",
+		hash:       "501527334faa17117d74fda3c850d928318d7a2ed800e315ce03653904ecba84",
+	},
+	{
+		name: "spend",
+		tx: Transaction{
+			ID: []byte{3},
+			InputValue: []TXInput{
+				{TransactionID: []byte{1, 2}, OutputIndex: 0, Signature: []byte{7, 7, 7}, PubKey: []byte{8}},
+				{TransactionID: []byte{4}, OutputIndex: 3},
+			},
+			OutputValue: []TXOutput{{Value: 4, PubKeyHash: []byte{9}}, {Value: 0, PubKeyHash: []byte{5}}},
+		},
+		serialized: "407f0301010b5472616e73616374696f6e01ff8000010301024944010a00010a496e70757456616c756501ff8400010b4f757470757456616c756501ff880000001dff830201010e5b5d636f72652e5458496e70757401ff840001ff82000050ff81030101075458496e70757401ff82000104010d5472616e73616374696f6e4944010a00010b4f7574707574496e64657801040001095369676e6174757265010a0001065075624b6579010a0000001eff870201010f5b5d636f72652e54584f757470757401ff880001ff8600002fff850301010854584f757470757401ff86000102010556616c7565010400010a5075624b657948617368010a00000027ff8001010301020102010202030707070101080001010401060001020108010109000201050000",
+		hash:       "23acdfc92b3af11644958176fb0d7fdfcd26f0488f2a71629620d11b21267e63",
+	},
+}
+
+func TestSerializeVersionZeroTransaction(t *testing.T) {
+	// Encoding a block first must not change the numbers gob gives the transaction types
+	_, err := (&Block{Transactions: []*Transaction{&versionZeroTransactions[0].tx}}).SerializeBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range versionZeroTransactions {
+		t.Run(test.name, func(t *testing.T) {
+			serialized, err := test.tx.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hex.EncodeToString(serialized) != test.serialized {
+				t.Errorf("serialized to %x, expected %s", serialized, test.serialized)
+			}
+
+			hash, err := test.tx.Hash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hex.EncodeToString(hash) != test.hash {
+				t.Errorf("hashed to %x, expected %s", hash, test.hash)
+			}
+		})
+	}
+}
+
+func TestSerializeExtendedTransaction(t *testing.T) {
+	base := versionZeroTransactions[1].tx
+
+	baseHash, err := base.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withLockTime := base.TrimmedCopy()
+	withLockTime.LockTime = 100
+
+	withSecret := base.TrimmedCopy()
+	withSecret.InputValue[0].Secret = []byte{1}
+
+	withRelativeLock := base.TrimmedCopy()
+	withRelativeLock.OutputValue[0].RelativeLock = 5
+
+	withHTLC := base.TrimmedCopy()
+	withHTLC.OutputValue[0].HTLC = &HTLC{SecretHash: []byte{1}, RecipientPubKeyHash: []byte{2}, RefundPubKeyHash: []byte{3}, Timeout: 10}
+
+	withData := base.TrimmedCopy()
+	withData.OutputValue = append(withData.OutputValue, TXOutput{Data: []byte("data")})
+
+	tests := []struct {
+		name string
+		tx   Transaction
+	}{
+		{name: "lock time", tx: withLockTime},
+		{name: "secret", tx: withSecret},
+		{name: "relative lock", tx: withRelativeLock},
+		{name: "contract", tx: withHTLC},
+		{name: "data output", tx: withData},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := test.tx.Hash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bytes.Equal(hash, baseHash) {
+				t.Error("the hash does not cover the field")
+			}
+
+			serialized, err := test.tx.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := DeserializeTransaction(serialized)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decodedHash, err := decoded.Hash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(decodedHash, hash) {
+				t.Errorf("decoded transaction hashes to %x, expected %x", decodedHash, hash)
+			}
+		})
+	}
+}
+
+func TestDeserializeVersionZeroTransaction(t *testing.T) {
+	for _, test := range versionZeroTransactions {
+		serialized, err := hex.DecodeString(test.serialized)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := DeserializeTransaction(serialized)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(decoded.ID, test.tx.ID) || len(decoded.InputValue) != len(test.tx.InputValue) || len(decoded.OutputValue) != len(test.tx.OutputValue) {
+			t.Errorf("%s: decoded to %+v", test.name, decoded)
+		}
+	}
+}
+
+func TestCheckID(t *testing.T) {
+	cfg := testConfig()
+	wallet, address := testWallet(t, cfg)
+	coinbase := testCoinbase(t, cfg, address)
+	payment := testPayment(t, cfg, wallet, coinbase, address, 10)
+
+	reusedID := *payment
+	reusedID.ID = coinbase.ID
+
+	changedOutputs := *payment
+	changedOutputs.OutputValue = []TXOutput{{Value: 9, PubKeyHash: payment.OutputValue[0].PubKeyHash}}
+
+	tests := []struct {
+		name  string
+		tx    *Transaction
+		valid bool
+	}{
+		{name: "coinbase", tx: coinbase, valid: true},
+		{name: "signed payment", tx: payment, valid: true},
+		{name: "ID of another transaction", tx: &reusedID},
+		{name: "outputs changed after the ID", tx: &changedOutputs},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.tx.CheckID()
+			if test.valid && err != nil {
+				t.Errorf("rejected with %v", err)
+			}
+
+			if !test.valid && !errors.Is(err, ErrInvalidTransaction) {
+				t.Errorf("checked with %v, expected %v", err, ErrInvalidTransaction)
+			}
+		})
+	}
+}
+
+func TestRejectReusedTransactionID(t *testing.T) {
+	chain := newTestChain(t, 2)
+	cfg := chain.bc.cfg
+	payment := chain.blocks[1].Transactions[1]
+
+	// A coinbase taking the ID of the payment would replace the output it paid to the other address
+	coinbase := testCoinbase(t, cfg, chain.address)
+	coinbase.ID = payment.ID
+
+	_, err := chain.bc.MineBlock([]*Transaction{coinbase})
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("mined a coinbase reusing an ID with %v, expected %v", err, ErrInvalidTransaction)
+	}
+
+	spend := testPayment(t, cfg, chain.wallet, chain.blocks[2].Transactions[0], chain.address, 10)
+	spend.ID = payment.ID
+
+	_, err = NewMempool(cfg, chain.bc).Add(spend)
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("accepted a transaction reusing an ID with %v, expected %v", err, ErrInvalidTransaction)
+	}
+
+	isUnspent, err := NewUTXOSet(cfg, chain.bc).IsUnspent(payment.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !*isUnspent {
+		t.Error("the output of the payment was overwritten")
+	}
+}
+
+func TestCheckOutputValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []TXOutput
+		valid   bool
+	}{
+		{name: "positive values", outputs: []TXOutput{{Value: 1000}, {Value: 1}}, valid: true},
+		{name: "empty data output", outputs: []TXOutput{{Value: 10}, {Data: []byte("data")}}, valid: true},
+		{name: "negative value", outputs: []TXOutput{{Value: 1000}, {Value: -990}}},
+		{name: "empty output", outputs: []TXOutput{{Value: 10}, {Value: 0}}},
+		{name: "negative data output", outputs: []TXOutput{{Value: -1, Data: []byte("data")}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := Transaction{OutputValue: test.outputs}
+
+			err := tx.CheckOutputValues()
+			if test.valid && err != nil {
+				t.Errorf("rejected with %v", err)
+			}
+
+			if !test.valid && !errors.Is(err, ErrInvalidTransaction) {
+				t.Errorf("checked with %v, expected %v", err, ErrInvalidTransaction)
+			}
+		})
+	}
+}
+
+func TestRejectNegativeOutputs(t *testing.T) {
+	chain := newTestChain(t, 2)
+	cfg := chain.bc.cfg
+	pubKeyHash := DecodeAddress(cfg, chain.address)
+
+	// A coinbase paying more than the subsidy, taken back by a negative output, adds up to the subsidy
+	coinbase := testCoinbase(t, cfg, chain.address)
+	coinbase.OutputValue = []TXOutput{{Value: 1000, PubKeyHash: pubKeyHash}, {Value: cfg.TransactionConfig.Subsidy - 1000, PubKeyHash: pubKeyHash}}
+	coinbase.ID = nil
+
+	hash, err := coinbase.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase.ID = hash
+
+	_, err = chain.bc.MineBlock([]*Transaction{coinbase})
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("mined a coinbase with a negative output with %v, expected %v", err, ErrInvalidTransaction)
+	}
+
+	// A payment of the 10 coins of a reward as 1000 and -990
+	spend := testPayment(t, cfg, chain.wallet, chain.blocks[2].Transactions[0], chain.address, 1000)
+	spend.OutputValue = append(spend.OutputValue, TXOutput{Value: -990, PubKeyHash: pubKeyHash})
+
+	for i := range spend.InputValue {
+		spend.InputValue[i].Signature = nil
+	}
+
+	hash, err = spend.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend.ID = hash
+
+	err = spend.Sign(chain.wallet.PrivateKey, map[string]Transaction{hex.EncodeToString(chain.blocks[2].Transactions[0].ID): *chain.blocks[2].Transactions[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewMempool(cfg, chain.bc).Add(spend)
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("accepted a payment with a negative output with %v, expected %v", err, ErrInvalidTransaction)
+	}
+}
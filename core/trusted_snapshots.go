@@ -0,0 +1,57 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/utils"
+)
+
+// TrustedSnapshot is a UTXO snapshot whose content hash was checked by replaying the blocks below it
+type TrustedSnapshot struct {
+	Height  int
+	TipHash string
+	Hash    string
+}
+
+// TrustedSnapshots are compiled in, so that their snapshots can be loaded without trusting a hash from the configuration
+// or the command line. Add a snapshot only after validating it on a node that replayed the whole chain
+var TrustedSnapshots = []TrustedSnapshot{}
+
+// trustedSnapshotHash returns the compiled in content hash of the snapshot at the given tip, or nil when there is none
+func trustedSnapshotHash(tipHash []byte, height int) ([]byte, error) {
+	for _, trusted := range TrustedSnapshots {
+		if trusted.Height != height || trusted.TipHash != hex.EncodeToString(tipHash) {
+			continue
+		}
+
+		hash, err := hex.DecodeString(trusted.Hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		return hash, nil
+	}
+
+	return nil, nil
+}
+
+// resolveTrustedHash returns the hash the snapshot must have: the compiled in one for its tip, which a given hash must
+// match, or else the given hash
+func resolveTrustedHash(snapshot *UTXOSnapshot, givenHash []byte) ([]byte, error) {
+	compiledHash, err := trustedSnapshotHash(snapshot.TipHash, snapshot.Height)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	switch {
+	case compiledHash != nil && givenHash != nil && !bytes.Equal(compiledHash, givenHash):
+		return nil, fmt.Errorf("%w: the given hash %x differs from the compiled in hash %x of block %x", ErrSnapshotNotTrusted, givenHash, compiledHash, snapshot.TipHash)
+	case compiledHash != nil:
+		return compiledHash, nil
+	case givenHash != nil:
+		return givenHash, nil
+	default:
+		return nil, fmt.Errorf("%w: no hash is compiled in for block %x at height %d and none was given", ErrSnapshotNotTrusted, snapshot.TipHash, snapshot.Height)
+	}
+}
@@ -1,13 +1,23 @@
 package core
 
 import (
+	"bytes"
 	"encoding/hex"
+	"fmt"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
-
-	"go.etcd.io/bbolt"
 )
 
+// utxoTipKey is the key in the meta bucket of the hash of the block the UTXO set was last updated to
+var utxoTipKey = []byte("utxo_tip")
+
+// UTXO represents an unspent transaction output together with its location
+type UTXO struct {
+	TransactionID []byte
+	OutputIndex   int
+	Output        TXOutput
+}
+
 // UTXOSet represents UTXO set
 type UTXOSet struct {
 	cfg        *model.Config
@@ -19,57 +29,43 @@ func NewUTXOSet(cfg *model.Config, blockchain *Blockchain) *UTXOSet {
 	return &UTXOSet{cfg: cfg, Blockchain: blockchain}
 }
 
-// Reindex rebuilds the UTXO set
+// Reindex rebuilds the UTXO set by applying the blocks from the genesis block up to the tip, so that outputs spent by
+// later transactions of their own block are removed as they are when blocks are connected
 func (u *UTXOSet) Reindex() error {
-	utxoSetBucket := []byte(u.cfg.DatabaseConfig.UTXOSetBucket)
-	db := u.Blockchain.Db
-
-	err := db.Update(func(tx *bbolt.Tx) error {
-		err := tx.DeleteBucket(utxoSetBucket)
-		if err != nil && err != bbolt.ErrBucketNotFound {
+	err := u.Blockchain.Store.Update(func(tx ChainTx) error {
+		err := tx.ClearUTXOs()
+		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		_, err = tx.CreateBucket(utxoSetBucket)
+		bestHeight, err := tx.BlockHeight(u.Blockchain.Tip)
 		if err != nil {
 			return utils.CatchErr(err)
 		}
 
-		return nil
-	})
-
-	if err != nil {
-		return utils.CatchErr(err)
-	}
-
-	UTXO, err := u.Blockchain.FindUTXO()
-	if err != nil {
-		return utils.CatchErr(err)
-	}
-
-	err = db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(utxoSetBucket)
-
-		for txID, outputs := range UTXO {
-			key, err := hex.DecodeString(txID)
+		for height := 0; height <= *bestHeight; height++ {
+			hash, err := tx.BlockHash(height)
 			if err != nil {
 				return utils.CatchErr(err)
 			}
 
-			serializedOutputs, err := outputs.Serialize()
+			block, err := tx.Block(hash)
 			if err != nil {
 				return utils.CatchErr(err)
 			}
 
-			err = b.Put(key, serializedOutputs)
+			if block.IsPruned() {
+				return fmt.Errorf("%w: the UTXO set cannot be rebuilt without block %x", ErrBlockPruned, block.Hash)
+			}
+
+			err = applyBlockUTXOs(tx, block)
 			if err != nil {
 				return utils.CatchErr(err)
 			}
 		}
 
-		return nil
+		return putUTXOTip(u.cfg, tx, u.Blockchain.Tip)
 	})
-
 	if err != nil {
 		return utils.CatchErr(err)
 	}
@@ -77,130 +73,325 @@ func (u *UTXOSet) Reindex() error {
 	return nil
 }
 
-// Update updates the UTXO set with transactions from the Block (tip of the blockchain)
-func (u UTXOSet) Update(block *Block) error {
-	utxoSetBucket := []byte(u.cfg.DatabaseConfig.UTXOSetBucket)
-	db := u.Blockchain.Db
+// applyBlockUTXOs updates the UTXO set with the transactions of a block connected on top of the block it is at
+func applyBlockUTXOs(tx ChainTx, block *Block) error {
+	for _, transaction := range block.Transactions {
+		if !transaction.IsCoinbase() {
+			for _, vin := range transaction.InputValue {
+				updatedOuts := TXOutputs{}
+				outs, err := tx.UTXOs(vin.TransactionID)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
 
-	err := db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(utxoSetBucket)
+				if outs == nil {
+					return fmt.Errorf("%w: outputs of %x are not in the UTXO set", ErrTransactionNotFound, vin.TransactionID)
+				}
 
-		for _, tx := range block.Transactions {
-			if !tx.IsCoinbase() {
-				for _, vin := range tx.InputValue {
-					updatedOuts := TXOutputs{}
-					outsBytes := b.Get(vin.TransactionID)
-					outs, err := DeserializeOutputs(outsBytes)
-					if err != nil {
-						return utils.CatchErr(err)
+				for i, out := range outs.Outputs {
+					if outs.Index(i) != vin.OutputIndex {
+						updatedOuts.Outputs = append(updatedOuts.Outputs, out)
+						updatedOuts.Indexes = append(updatedOuts.Indexes, outs.Index(i))
 					}
+				}
 
-					for outIndex, out := range outs.Outputs {
-						if outIndex != vin.OutputIndex {
-							updatedOuts.Outputs = append(updatedOuts.Outputs, out)
-						}
+				if len(updatedOuts.Outputs) == 0 {
+					err := tx.DeleteUTXOs(vin.TransactionID)
+					if err != nil {
+						return utils.CatchErr(err)
 					}
-
-					if len(updatedOuts.Outputs) == 0 {
-						err := b.Delete(vin.TransactionID)
-						if err != nil {
-							return utils.CatchErr(err)
-						}
-					} else {
-						serializedOutputs, err := updatedOuts.Serialize()
-						if err != nil {
-							return utils.CatchErr(err)
-						}
-
-						err = b.Put(vin.TransactionID, serializedOutputs)
-						if err != nil {
-							return utils.CatchErr(err)
-						}
+				} else {
+					err := tx.PutUTXOs(vin.TransactionID, updatedOuts)
+					if err != nil {
+						return utils.CatchErr(err)
 					}
 				}
 			}
+		}
+
+		newOutputs := TXOutputs{}
+		for outIndex, out := range transaction.OutputValue {
+			// Data outputs can never be spent
+			if out.IsData() {
+				continue
+			}
+
+			newOutputs.Outputs = append(newOutputs.Outputs, out)
+			newOutputs.Indexes = append(newOutputs.Indexes, outIndex)
+		}
 
-			newOutputs := TXOutputs{}
-			newOutputs.Outputs = append(newOutputs.Outputs, tx.OutputValue...)
+		if len(newOutputs.Outputs) == 0 {
+			continue
+		}
 
-			serializedOutputs, err := newOutputs.Serialize()
+		err := tx.PutUTXOs(transaction.ID, newOutputs)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	return nil
+}
+
+// utxoTip returns the hash of the block the UTXO set was last updated to, or nil when it was never recorded
+func utxoTip(cfg *model.Config, tx ChainTx) []byte {
+	return tx.Record(cfg.DatabaseConfig.MetaBucket, utxoTipKey)
+}
+
+// putUTXOTip records the block the UTXO set was last updated to
+func putUTXOTip(cfg *model.Config, tx ChainTx, hash []byte) error {
+	return tx.PutRecord(cfg.DatabaseConfig.MetaBucket, utxoTipKey, hash)
+}
+
+// ensureUTXOSet checks that the UTXO set was updated up to the tip and repairs it otherwise, applying the blocks it is
+// missing when it is at a block of the chain, and rebuilding it from the blocks when it is not or they were pruned
+func (bc *Blockchain) ensureUTXOSet() error {
+	var tip []byte
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		tip = utxoTip(bc.cfg, tx)
+
+		return nil
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if bytes.Equal(tip, bc.Tip) {
+		return nil
+	}
+
+	fmt.Printf("The UTXO set is not at the tip %x, repairing it\n", bc.Tip)
+
+	if tip != nil {
+		err = bc.Store.Update(func(tx ChainTx) error {
+			height, err := tx.BlockHeight(tip)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			bestHeight, err := tx.BlockHeight(bc.Tip)
 			if err != nil {
 				return utils.CatchErr(err)
 			}
 
-			err = b.Put(tx.ID, serializedOutputs)
+			hash, err := tx.BlockHash(*height)
 			if err != nil {
 				return utils.CatchErr(err)
 			}
+
+			// A UTXO set ahead of the tip or at a block that left the chain cannot be moved forward
+			if *height > *bestHeight || !bytes.Equal(hash, tip) {
+				return ErrBlockNotFound
+			}
+
+			for blockHeight := *height + 1; blockHeight <= *bestHeight; blockHeight++ {
+				hash, err := tx.BlockHash(blockHeight)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+
+				block, err := tx.Block(hash)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+
+				if block.IsPruned() {
+					return ErrBlockPruned
+				}
+
+				err = applyBlockUTXOs(tx, block)
+				if err != nil {
+					return utils.CatchErr(err)
+				}
+			}
+
+			return putUTXOTip(bc.cfg, tx, bc.Tip)
+		})
+		if err == nil {
+			return nil
 		}
+	}
 
-		return nil
-	})
+	return NewUTXOSet(bc.cfg, bc).Reindex()
+}
 
+// recordUTXOTip records the tip as the block the UTXO set is at, rebuilding the set first unless blocks were pruned,
+// as the UTXO set of a database from before the tip was recorded may have missed the update of a block
+func (bc *Blockchain) recordUTXOTip() error {
+	pruneHeight, err := bc.PruneHeight()
 	if err != nil {
 		return utils.CatchErr(err)
 	}
 
-	return nil
+	if *pruneHeight < 0 {
+		return NewUTXOSet(bc.cfg, bc).Reindex()
+	}
+
+	return bc.Store.Update(func(tx ChainTx) error {
+		return putUTXOTip(bc.cfg, tx, bc.Tip)
+	})
 }
 
-// FindSpendableOutputs finds and returns unspent outputs in reference to an amount
+// FindSpendableOutputs finds and returns unspent outputs in reference to an amount, skipping outputs whose relative lock has not expired for the next block
 func (u *UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (*int, map[string][]int, error) {
-	utxoSetBucket := []byte(u.cfg.DatabaseConfig.UTXOSetBucket)
 	unspentOutputs := make(map[string][]int)
 	accumulated := 0
-	db := u.Blockchain.Db
 
-	err := db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(utxoSetBucket)
-		c := b.Cursor()
+	var lockedOutputs []UTXO
 
-		for k, v := c.First(); k != nil; k, v = c.Next() {
+	err := u.Blockchain.Store.View(func(tx ChainTx) error {
+		// Outputs already spent by a pending transaction would conflict with it
+		spent, err := pendingSpends(tx, u.cfg.DatabaseConfig.MempoolBucket)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		return tx.ForEachUTXOs(func(k []byte, outs *TXOutputs) error {
 			txID := hex.EncodeToString(k)
-			outs, err := DeserializeOutputs(v)
-			if err != nil {
-				return utils.CatchErr(err)
-			}
 
-			for outIndex, out := range outs.Outputs {
-				if out.IsLockedWithKey(pubKeyHash) && accumulated < amount {
-					accumulated += out.Value
-					unspentOutputs[txID] = append(unspentOutputs[txID], outIndex)
+			for i, out := range outs.Outputs {
+				if !out.IsLockedWithKey(pubKeyHash) || accumulated >= amount || spent[outpointKey(TXInput{TransactionID: k, OutputIndex: outs.Index(i)})] {
+					continue
+				}
+
+				// Locked outputs need the height of their block, which is looked up outside of this transaction
+				if out.RelativeLock > 0 {
+					lockedOutputs = append(lockedOutputs, UTXO{TransactionID: k, OutputIndex: outs.Index(i), Output: out})
+					continue
 				}
+
+				accumulated += out.Value
+				unspentOutputs[txID] = append(unspentOutputs[txID], outs.Index(i))
 			}
-		}
 
-		return nil
+			return nil
+		})
 	})
 
 	if err != nil {
 		return nil, nil, utils.CatchErr(err)
 	}
 
+	if len(lockedOutputs) == 0 || accumulated >= amount {
+		return &accumulated, unspentOutputs, nil
+	}
+
+	bestHeight, err := u.Blockchain.GetBestHeight()
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	for _, utxo := range lockedOutputs {
+		if accumulated >= amount {
+			break
+		}
+
+		confirmedHeight, err := u.Blockchain.confirmationHeight(utxo.TransactionID)
+		if err != nil {
+			return nil, nil, utils.CatchErr(err)
+		}
+
+		if !utxo.Output.IsMature(*confirmedHeight, *bestHeight+1) {
+			continue
+		}
+
+		txID := hex.EncodeToString(utxo.TransactionID)
+		accumulated += utxo.Output.Value
+		unspentOutputs[txID] = append(unspentOutputs[txID], utxo.OutputIndex)
+	}
+
 	return &accumulated, unspentOutputs, nil
 }
 
 // FindUTXOByPubKeyHash finds UTXO for a public key hash
 func (u *UTXOSet) FindUTXOByPubKeyHash(pubKeyHash []byte) (*TXOutputs, error) {
-	utxoSetBucket := []byte(u.cfg.DatabaseConfig.UTXOSetBucket)
 	var UTXOs TXOutputs
-	db := u.Blockchain.Db
-
-	err := db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(utxoSetBucket)
-		c := b.Cursor()
-
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			outs, err := DeserializeOutputs(v)
-			if err != nil {
-				return utils.CatchErr(err)
-			}
 
+	err := u.Blockchain.Store.View(func(tx ChainTx) error {
+		return tx.ForEachUTXOs(func(k []byte, outs *TXOutputs) error {
 			for _, out := range outs.Outputs {
 				if out.IsLockedWithKey(pubKeyHash) {
 					UTXOs.Outputs = append(UTXOs.Outputs, out)
 				}
 			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &UTXOs, nil
+}
+
+// FindUTXOsByPubKeyHash finds UTXO for a public key hash along with the transaction and index they belong to
+func (u *UTXOSet) FindUTXOsByPubKeyHash(pubKeyHash []byte) ([]UTXO, error) {
+	var UTXOs []UTXO
+
+	err := u.Blockchain.Store.View(func(tx ChainTx) error {
+		return tx.ForEachUTXOs(func(k []byte, outs *TXOutputs) error {
+			for i, out := range outs.Outputs {
+				if out.IsLockedWithKey(pubKeyHash) {
+					UTXOs = append(UTXOs, UTXO{TransactionID: k, OutputIndex: outs.Index(i), Output: out})
+				}
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return UTXOs, nil
+}
+
+// CountTransactions returns the number of transactions and outputs in the UTXO set and their total value
+func (u *UTXOSet) CountTransactions() (*int, *int, *int, error) {
+	transactions, outputs, value := 0, 0, 0
+
+	err := u.Blockchain.Store.View(func(tx ChainTx) error {
+		return tx.ForEachUTXOs(func(k []byte, outs *TXOutputs) error {
+			transactions++
+			outputs += len(outs.Outputs)
+
+			for _, out := range outs.Outputs {
+				value += out.Value
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, nil, nil, utils.CatchErr(err)
+	}
+
+	return &transactions, &outputs, &value, nil
+}
+
+// IsUnspent checks whether an output of a transaction is in the UTXO set
+func (u *UTXOSet) IsUnspent(transactionID []byte, outIndex int) (*bool, error) {
+	isUnspent := false
+
+	err := u.Blockchain.Store.View(func(tx ChainTx) error {
+		outs, err := tx.UTXOs(transactionID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if outs == nil {
+			return nil
+		}
+
+		for i := range outs.Outputs {
+			if outs.Index(i) == outIndex {
+				isUnspent = true
+			}
 		}
 
 		return nil
@@ -210,5 +401,5 @@ func (u *UTXOSet) FindUTXOByPubKeyHash(pubKeyHash []byte) (*TXOutputs, error) {
 		return nil, utils.CatchErr(err)
 	}
 
-	return &UTXOs, nil
+	return &isUnspent, nil
 }
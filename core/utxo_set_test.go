@@ -0,0 +1,167 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestReindexSpendsOutputsWithinABlock(t *testing.T) {
+	chain := newTestChain(t, 1)
+	cfg := chain.bc.cfg
+
+	// The child spends the output of its parent in the same block
+	parent := testPayment(t, cfg, chain.wallet, chain.blocks[1].Transactions[0], chain.address, 10)
+	child := testPayment(t, cfg, chain.wallet, parent, chain.otherAddress, 10)
+	chain.mine(t, testCoinbase(t, cfg, chain.address), parent, child)
+
+	utxoSet := NewUTXOSet(cfg, chain.bc)
+
+	err := utxoSet.Reindex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		tx       *Transaction
+		expected bool
+	}{
+		{name: "parent", tx: parent, expected: false},
+		{name: "child", tx: child, expected: true},
+		{name: "payment of block 1", tx: chain.blocks[1].Transactions[1], expected: true},
+		{name: "genesis coinbase", tx: chain.blocks[0].Transactions[0], expected: false},
+	}
+
+	for _, test := range tests {
+		isUnspent, err := utxoSet.IsUnspent(test.tx.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *isUnspent != test.expected {
+			t.Errorf("output of the %s is unspent %t after reindexing, expected %t", test.name, *isUnspent, test.expected)
+		}
+	}
+}
+
+func TestConnectBlockRollsBackFailedUTXOUpdates(t *testing.T) {
+	chain := newTestChain(t, 1)
+	cfg := chain.bc.cfg
+	tip := chain.bc.Tip
+
+	// The coinbase is applied to the UTXO set before the payment spending an unknown output fails
+	unknown := &Transaction{ID: []byte{1}, OutputValue: []TXOutput{{Value: 10}}}
+	payment := testPayment(t, cfg, chain.wallet, unknown, chain.otherAddress, 10)
+	coinbase := testCoinbase(t, cfg, chain.address)
+	block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{coinbase, payment}, tip)
+
+	err := chain.bc.ConnectBlock(block)
+	if !errors.Is(err, ErrTransactionNotFound) {
+		t.Fatalf("connected the block with %v, expected %v", err, ErrTransactionNotFound)
+	}
+
+	if !bytes.Equal(chain.bc.Tip, tip) {
+		t.Errorf("tip moved to %x, expected %x", chain.bc.Tip, tip)
+	}
+
+	err = chain.bc.Store.View(func(tx ChainTx) error {
+		if !bytes.Equal(tx.Tip(), tip) {
+			t.Errorf("stored tip moved to %x, expected %x", tx.Tip(), tip)
+		}
+
+		if !bytes.Equal(utxoTip(cfg, tx), tip) {
+			t.Errorf("UTXO tip moved to %x, expected %x", utxoTip(cfg, tx), tip)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = chain.bc.GetBlock(block.Hash)
+	if !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("got the failed block with %v, expected %v", err, ErrBlockNotFound)
+	}
+
+	isUnspent, err := NewUTXOSet(cfg, chain.bc).IsUnspent(coinbase.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *isUnspent {
+		t.Error("the coinbase of the failed block is in the UTXO set")
+	}
+}
+
+func TestEnsureUTXOSetRepairsLaggingSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		utxoTip []byte
+	}{
+		{name: "UTXO set at an earlier block"},
+		{name: "UTXO set at an unknown block", utxoTip: []byte{1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := newTestChain(t, 2)
+			cfg := chain.bc.cfg
+			staleTip := chain.bc.Tip
+
+			if test.utxoTip != nil {
+				staleTip = test.utxoTip
+			}
+
+			// The block is stored as the tip without updating the UTXO set, as if the node stopped in between
+			payment := testPayment(t, cfg, chain.wallet, chain.blocks[2].Transactions[0], chain.otherAddress, 10)
+			coinbase := testCoinbase(t, cfg, chain.address)
+			block := mineTestBlock(t, cfg, BlockVersion, []*Transaction{coinbase, payment}, chain.bc.Tip)
+
+			err := chain.bc.Store.Update(func(tx ChainTx) error {
+				err := chain.bc.putBlock(tx, block, 3)
+				if err != nil {
+					return err
+				}
+
+				return putUTXOTip(cfg, tx, staleTip)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bc, err := InitializeBlockchainWithStore(cfg, chain.bc.Store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = bc.Store.View(func(tx ChainTx) error {
+				if !bytes.Equal(utxoTip(cfg, tx), block.Hash) {
+					t.Errorf("UTXO tip is %x, expected %x", utxoTip(cfg, tx), block.Hash)
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			utxoSet := NewUTXOSet(cfg, bc)
+
+			for _, output := range []struct {
+				tx       *Transaction
+				expected bool
+			}{{tx: chain.blocks[2].Transactions[0]}, {tx: coinbase, expected: true}, {tx: payment, expected: true}} {
+				isUnspent, err := utxoSet.IsUnspent(output.tx.ID, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if *isUnspent != output.expected {
+					t.Errorf("output of %x is unspent %t after the repair, expected %t", output.tx.ID, *isUnspent, output.expected)
+				}
+			}
+		})
+	}
+}
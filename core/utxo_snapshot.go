@@ -0,0 +1,618 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"hash"
+	"io"
+	"slices"
+)
+
+var (
+	ErrSnapshotInvalid    = errors.New("UTXO snapshot is invalid")
+	ErrSnapshotNotTrusted = errors.New("UTXO snapshot is not trusted")
+)
+
+// pendingSnapshotKey is the key of the loaded snapshot in the snapshot bucket, which is deleted once the blocks below it are validated
+var pendingSnapshotKey = []byte("pending")
+
+// UTXOSnapshot is a copy of the UTXO set at a block, from which a node can start without replaying the blocks below it
+type UTXOSnapshot struct {
+	TipHash []byte
+	Height  int
+	// PowAlgorithm is the proof of work algorithm of the blockchain, which the headers below the tip are checked with
+	PowAlgorithm string
+	// Filter is the filter of the tip, which the filters of the next blocks are chained to
+	Filter  BlockFilter
+	Hash    []byte
+	Entries []UTXOSnapshotEntry
+}
+
+// UTXOSnapshotEntry holds the unspent outputs of a transaction
+type UTXOSnapshotEntry struct {
+	TransactionID []byte
+	Outputs       TXOutputs
+}
+
+// Snapshot copies the UTXO set along with the tip it was built up to
+func (u *UTXOSet) Snapshot() (*UTXOSnapshot, error) {
+	var snapshot UTXOSnapshot
+
+	// The tip is read in the same transaction as the UTXO set, so that both describe the same block
+	err := u.Blockchain.Store.View(func(tx ChainTx) error {
+		snapshot.TipHash = tx.Tip()
+		snapshot.PowAlgorithm = readPowAlgorithm(u.Blockchain.cfg, tx)
+
+		height, err := tx.BlockHeight(snapshot.TipHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+		snapshot.Height = *height
+
+		filter, err := tx.Filter(snapshot.TipHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+		snapshot.Filter = *filter
+
+		return tx.ForEachUTXOs(func(k []byte, outs *TXOutputs) error {
+			snapshot.Entries = append(snapshot.Entries, UTXOSnapshotEntry{TransactionID: k, Outputs: *outs})
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	snapshot.Hash, err = snapshot.ContentHash()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &snapshot, nil
+}
+
+// ContentHash hashes the tip, height, proof of work algorithm and filter header of the snapshot and its entries, which
+// are sorted by transaction ID as in the UTXO bucket, so that a trusted hash also pins the block the UTXO set belongs to
+func (s *UTXOSnapshot) ContentHash() ([]byte, error) {
+	for i := 1; i < len(s.Entries); i++ {
+		if bytes.Compare(s.Entries[i-1].TransactionID, s.Entries[i].TransactionID) >= 0 {
+			return nil, fmt.Errorf("%w: entries are not sorted by transaction ID", ErrSnapshotInvalid)
+		}
+	}
+
+	h := sha256.New()
+
+	fields := [][]byte{s.TipHash, binary.BigEndian.AppendUint64(nil, uint64(s.Height)), []byte(s.PowAlgorithm), s.Filter.Header}
+
+	for _, field := range fields {
+		err := writeHashField(h, field)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	for _, entry := range s.Entries {
+		err := hashUTXOEntry(h, entry.TransactionID, entry.Outputs)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Write encodes the snapshot to w
+func (s *UTXOSnapshot) Write(w io.Writer) error {
+	err := gob.NewEncoder(w).Encode(s)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// ReadUTXOSnapshot decodes a snapshot from r
+func ReadUTXOSnapshot(r io.Reader) (*UTXOSnapshot, error) {
+	var snapshot UTXOSnapshot
+
+	err := gob.NewDecoder(r).Decode(&snapshot)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &snapshot, nil
+}
+
+// hashUTXOEntry writes the unspent outputs of a transaction to the hash, listing every output with its index
+// and in index order so that the hash does not depend on how the outputs were stored
+func hashUTXOEntry(h hash.Hash, transactionID []byte, outs TXOutputs) error {
+	positions := make([]int, len(outs.Outputs))
+	for i := range positions {
+		positions[i] = i
+	}
+
+	slices.SortFunc(positions, func(a, b int) int {
+		return cmp.Compare(outs.Index(a), outs.Index(b))
+	})
+
+	var normalized TXOutputs
+	for _, i := range positions {
+		normalized.Outputs = append(normalized.Outputs, outs.Outputs[i])
+		normalized.Indexes = append(normalized.Indexes, outs.Index(i))
+	}
+
+	serializedOutputs, err := normalized.Serialize()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	for _, field := range [][]byte{transactionID, serializedOutputs} {
+		err = writeHashField(h, field)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+	}
+
+	return nil
+}
+
+// writeHashField writes a field to the hash prefixed with its length, so that fields cannot run into each other
+func writeHashField(h hash.Hash, field []byte) error {
+	err := binary.Write(h, binary.BigEndian, uint32(len(field)))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	h.Write(field)
+
+	return nil
+}
+
+// LoadUTXOSnapshot creates a blockchain from a snapshot whose content hash is trusted and the headers from the genesis
+// block up to its tip, which are stored as pruned blocks until the blocks below the snapshot are validated. The trusted
+// hash is the one compiled in for the tip of the snapshot, or the given one when none is compiled in
+func LoadUTXOSnapshot(cfg *model.Config, snapshot *UTXOSnapshot, trustedHash []byte, headers []*BlockHeader) (*Blockchain, error) {
+	databaseName := cfg.DatabaseConfig.DbName
+
+	if utils.DbExists(databaseName) {
+		return nil, fmt.Errorf("blockchain already exists")
+	}
+
+	trustedHash, err := resolveTrustedHash(snapshot, trustedHash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	contentHash, err := snapshot.ContentHash()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !bytes.Equal(contentHash, trustedHash) || !bytes.Equal(contentHash, snapshot.Hash) {
+		return nil, fmt.Errorf("%w: content hash %x is not the trusted hash %x", ErrSnapshotInvalid, contentHash, trustedHash)
+	}
+
+	err = checkSnapshotHeaders(cfg, snapshot, headers)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// Only the state the blocks below the snapshot must lead to is kept until they are validated
+	pendingSnapshot := UTXOSnapshot{
+		TipHash:      snapshot.TipHash,
+		Height:       snapshot.Height,
+		PowAlgorithm: snapshot.PowAlgorithm,
+		Filter:       snapshot.Filter,
+		Hash:         snapshot.Hash,
+	}
+
+	encodedSnapshot, err := gobEncode(pendingSnapshot)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	store, err := OpenBoltChainStore(cfg)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = store.Update(func(tx ChainTx) error {
+		for height, header := range headers {
+			block := Block{
+				Version:       header.Version,
+				Timestamp:     header.Timestamp,
+				PrevBlockHash: header.PrevBlockHash,
+				Hash:          header.Hash,
+				Nonce:         header.Nonce,
+				MerkleRoot:    header.MerkleRoot,
+			}
+
+			err := tx.PutBlock(&block)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			err = tx.PutBlockIndex(block.Hash, height)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		err := tx.SetTip(snapshot.TipHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.PutFilter(snapshot.TipHash, &snapshot.Filter)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		for _, entry := range snapshot.Entries {
+			err = tx.PutUTXOs(entry.TransactionID, entry.Outputs)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+		}
+
+		err = putUTXOTip(cfg, tx, snapshot.TipHash)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = putSchemaVersion(cfg, tx, SchemaVersion)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = putPowAlgorithm(cfg, tx, snapshot.PowAlgorithm)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		return tx.PutRecord(cfg.DatabaseConfig.SnapshotBucket, pendingSnapshotKey, encodedSnapshot)
+	})
+	if err != nil {
+		store.Close()
+		return nil, utils.CatchErr(err)
+	}
+
+	blockchain := Blockchain{cfg: cfg, Tip: snapshot.TipHash, Store: store, PowAlgorithm: snapshot.PowAlgorithm}
+
+	return &blockchain, nil
+}
+
+// checkSnapshotHeaders checks that the headers link the genesis block to the tip of the snapshot with a valid proof of
+// work of the algorithm of the snapshot
+func checkSnapshotHeaders(cfg *model.Config, snapshot *UTXOSnapshot, headers []*BlockHeader) error {
+	if snapshot.Height < 0 || len(headers) != snapshot.Height+1 {
+		return fmt.Errorf("expected %d headers up to the snapshot, got %d", snapshot.Height+1, len(headers))
+	}
+
+	for height, header := range headers {
+		var prevHash []byte
+		parentVersion := 0
+		if height > 0 {
+			prevHash = headers[height-1].Hash
+			parentVersion = headers[height-1].Version
+		}
+
+		if !bytes.Equal(header.PrevBlockHash, prevHash) {
+			return fmt.Errorf("header %x at height %d does not extend the header before it", header.Hash, height)
+		}
+
+		err := checkBlockVersion(header.Version, parentVersion)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		isValid, err := NewHeaderProofOfWork(cfg, snapshot.PowAlgorithm, header).Validate()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if !*isValid {
+			return fmt.Errorf("header %x has an invalid proof of work", header.Hash)
+		}
+	}
+
+	if !bytes.Equal(headers[snapshot.Height].Hash, snapshot.TipHash) {
+		return fmt.Errorf("%w: its tip %x is not the block at height %d", ErrSnapshotInvalid, snapshot.TipHash, snapshot.Height)
+	}
+
+	return nil
+}
+
+// PendingSnapshot returns the snapshot the blockchain was loaded from while the blocks below it are not validated, or nil
+func (bc *Blockchain) PendingSnapshot() (*UTXOSnapshot, error) {
+	var snapshot *UTXOSnapshot
+
+	err := bc.Store.View(func(tx ChainTx) error {
+		encodedSnapshot := tx.Record(bc.cfg.DatabaseConfig.SnapshotBucket, pendingSnapshotKey)
+		if encodedSnapshot == nil {
+			return nil
+		}
+
+		var decodedSnapshot UTXOSnapshot
+
+		err := gobDecode(encodedSnapshot, &decodedSnapshot)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		snapshot = &decodedSnapshot
+
+		return nil
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return snapshot, nil
+}
+
+// SnapshotValidation replays the blocks below a loaded snapshot, from the genesis block up, to prove that the snapshot
+// is the UTXO set they lead to. The blocks are only checked, the node keeps their headers and filters but not their transactions
+type SnapshotValidation struct {
+	cfg      *model.Config
+	snapshot *UTXOSnapshot
+	// utxos and heights are the unspent outputs left by the replayed blocks and the height of the block of their transaction
+	utxos        map[string]TXOutputs
+	heights      map[string]int
+	filterHeader []byte
+	height       int
+}
+
+// NewSnapshotValidation generates and returns a validation of the blocks below the snapshot, starting at the genesis block
+func NewSnapshotValidation(cfg *model.Config, snapshot *UTXOSnapshot) *SnapshotValidation {
+	return &SnapshotValidation{
+		cfg:      cfg,
+		snapshot: snapshot,
+		utxos:    make(map[string]TXOutputs),
+		heights:  make(map[string]int),
+	}
+}
+
+// Height returns the height of the next block to validate
+func (v *SnapshotValidation) Height() int {
+	return v.height
+}
+
+// Done checks whether every block up to the tip of the snapshot was validated
+func (v *SnapshotValidation) Done() bool {
+	return v.height > v.snapshot.Height
+}
+
+// ConnectBlock validates the next block against its stored header and the outputs left by the blocks before it and stores its
+// filter. Once the tip of the snapshot is reached, the replayed UTXO set is compared with the snapshot, which stops being pending
+func (v *SnapshotValidation) ConnectBlock(bc *Blockchain, block *Block) error {
+	if v.Done() {
+		return fmt.Errorf("every block below the snapshot was already validated")
+	}
+
+	blockHash, err := bc.GetBlockHash(v.height)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	storedHeader, err := bc.GetBlockHeader(blockHash)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	header, err := block.Header()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if block.IsPruned() || header.Version != storedHeader.Version || !bytes.Equal(header.Hash, storedHeader.Hash) || !bytes.Equal(header.PrevBlockHash, storedHeader.PrevBlockHash) ||
+		!bytes.Equal(header.MerkleRoot, storedHeader.MerkleRoot) || header.Timestamp != storedHeader.Timestamp || header.Nonce != storedHeader.Nonce {
+		return fmt.Errorf("block %x does not match the header at height %d", block.Hash, v.height)
+	}
+
+	err = v.connectTransactions(block)
+	if err != nil {
+		return fmt.Errorf("%w: block %x at height %d: %w", ErrSnapshotInvalid, block.Hash, v.height, err)
+	}
+
+	filter := NewBlockFilter(block).Serialize()
+	filterHeader := FilterHeader(filter, v.filterHeader)
+
+	if v.height < v.snapshot.Height {
+		err = bc.Store.Update(func(tx ChainTx) error {
+			return tx.PutFilter(blockHash, &BlockFilter{Filter: filter, Header: filterHeader})
+		})
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		v.filterHeader = filterHeader
+		v.height++
+
+		return nil
+	}
+
+	if !bytes.Equal(filterHeader, v.snapshot.Filter.Header) {
+		return fmt.Errorf("%w: the filter header of its tip does not match the blocks", ErrSnapshotInvalid)
+	}
+
+	var entries []UTXOSnapshotEntry
+
+	for txID, outs := range v.utxos {
+		transactionID, err := hex.DecodeString(txID)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		entries = append(entries, UTXOSnapshotEntry{TransactionID: transactionID, Outputs: outs})
+	}
+
+	slices.SortFunc(entries, func(a, b UTXOSnapshotEntry) int {
+		return bytes.Compare(a.TransactionID, b.TransactionID)
+	})
+
+	replayed := UTXOSnapshot{
+		TipHash:      v.snapshot.TipHash,
+		Height:       v.snapshot.Height,
+		PowAlgorithm: v.snapshot.PowAlgorithm,
+		Filter:       BlockFilter{Header: filterHeader},
+		Entries:      entries,
+	}
+
+	contentHash, err := replayed.ContentHash()
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !bytes.Equal(contentHash, v.snapshot.Hash) {
+		return fmt.Errorf("%w: the blocks below it lead to a UTXO set with content hash %x instead of %x", ErrSnapshotInvalid, contentHash, v.snapshot.Hash)
+	}
+
+	err = bc.Store.Update(func(tx ChainTx) error {
+		return tx.DeleteRecord(v.cfg.DatabaseConfig.SnapshotBucket, pendingSnapshotKey)
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	v.height++
+
+	return nil
+}
+
+// connectTransactions checks the transactions of the block as MineBlock does and applies them to the replayed outputs
+func (v *SnapshotValidation) connectTransactions(block *Block) error {
+	fees := 0
+	coinbaseValue := 0
+
+	for _, tx := range block.Transactions {
+		err := tx.CheckID()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.CheckDataOutputs(v.cfg.TransactionConfig.MaxDataSize)
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		err = tx.CheckOutputValues()
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		if tx.IsCoinbase() {
+			for _, out := range tx.OutputValue {
+				coinbaseValue += out.Value
+			}
+		} else {
+			fee, err := v.spendInputs(tx, block.Timestamp)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			fees += *fee
+		}
+
+		txID := hex.EncodeToString(tx.ID)
+
+		var outs TXOutputs
+		for outIndex, out := range tx.OutputValue {
+			// Data outputs can never be spent
+			if out.IsData() {
+				continue
+			}
+
+			outs.Outputs = append(outs.Outputs, out)
+			outs.Indexes = append(outs.Indexes, outIndex)
+		}
+
+		if len(outs.Outputs) != 0 {
+			v.utxos[txID] = outs
+			v.heights[txID] = v.height
+		}
+	}
+
+	if coinbaseValue > v.cfg.TransactionConfig.Subsidy+fees {
+		return fmt.Errorf("%w: coinbase pays more than the subsidy and fees", ErrInvalidTransaction)
+	}
+
+	return nil
+}
+
+// spendInputs verifies the inputs of the transaction against the replayed outputs, removes the outputs they spend
+// and returns the fee of the transaction
+func (v *SnapshotValidation) spendInputs(tx *Transaction, timestamp int64) (*int, error) {
+	if !tx.IsFinal(v.height, timestamp) {
+		return nil, fmt.Errorf("%w: lock time %d of %x is not reached", ErrTransactionLocked, tx.LockTime, tx.ID)
+	}
+
+	prevTXs := make(map[string]Transaction)
+	spent := make(map[string]bool)
+
+	for _, vin := range tx.InputValue {
+		prevTXID := hex.EncodeToString(vin.TransactionID)
+		outpoint := outpointKey(vin)
+
+		outs, ok := v.utxos[prevTXID]
+		if !ok || spent[outpoint] || !slices.Contains(outs.Indexes, vin.OutputIndex) {
+			return nil, fmt.Errorf("%w: %x spends the missing output %x:%d", ErrInvalidTransaction, tx.ID, vin.TransactionID, vin.OutputIndex)
+		}
+
+		spent[outpoint] = true
+
+		prevTX := outputsTransaction(vin.TransactionID, outs)
+		prevTXs[prevTXID] = *prevTX
+
+		if !prevTX.OutputValue[vin.OutputIndex].IsMature(v.heights[prevTXID], v.height) {
+			return nil, fmt.Errorf("%w: %x spends the immature output %x:%d", ErrTransactionLocked, tx.ID, vin.TransactionID, vin.OutputIndex)
+		}
+	}
+
+	verified, err := tx.Verify(prevTXs)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if !*verified {
+		return nil, fmt.Errorf("%w: %x has an invalid signature", ErrInvalidTransaction, tx.ID)
+	}
+
+	fee := tx.Fee(prevTXs)
+	if fee < 0 {
+		return nil, fmt.Errorf("%w: outputs of %x spend more than its inputs", ErrInvalidTransaction, tx.ID)
+	}
+
+	for _, vin := range tx.InputValue {
+		prevTXID := hex.EncodeToString(vin.TransactionID)
+		outs := v.utxos[prevTXID]
+
+		var updatedOuts TXOutputs
+		for i, out := range outs.Outputs {
+			if outs.Index(i) != vin.OutputIndex {
+				updatedOuts.Outputs = append(updatedOuts.Outputs, out)
+				updatedOuts.Indexes = append(updatedOuts.Indexes, outs.Index(i))
+			}
+		}
+
+		if len(updatedOuts.Outputs) == 0 {
+			delete(v.utxos, prevTXID)
+			delete(v.heights, prevTXID)
+			continue
+		}
+
+		v.utxos[prevTXID] = updatedOuts
+	}
+
+	return &fee, nil
+}
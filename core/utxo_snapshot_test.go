@@ -0,0 +1,106 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// testSnapshot returns a snapshot of two transactions at height 5
+func testSnapshot() *UTXOSnapshot {
+	return &UTXOSnapshot{
+		TipHash:      bytes.Repeat([]byte{0xAB}, 32),
+		Height:       5,
+		PowAlgorithm: PowSHA256,
+		Filter:       BlockFilter{Filter: []byte{1}, Header: bytes.Repeat([]byte{0xCD}, 32)},
+		Entries: []UTXOSnapshotEntry{
+			{TransactionID: []byte{1}, Outputs: TXOutputs{Outputs: []TXOutput{{Value: 10, PubKeyHash: []byte{2}}}, Indexes: []int{0}}},
+			{TransactionID: []byte{3}, Outputs: TXOutputs{Outputs: []TXOutput{{Value: 5, PubKeyHash: []byte{4}}}, Indexes: []int{1}}},
+		},
+	}
+}
+
+func TestSnapshotContentHashCoversTip(t *testing.T) {
+	hash, err := testSnapshot().ContentHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []struct {
+		name   string
+		change func(snapshot *UTXOSnapshot)
+	}{
+		{name: "tip hash", change: func(snapshot *UTXOSnapshot) { snapshot.TipHash[0] = 0 }},
+		{name: "height", change: func(snapshot *UTXOSnapshot) { snapshot.Height++ }},
+		{name: "proof of work algorithm", change: func(snapshot *UTXOSnapshot) { snapshot.PowAlgorithm = PowScrypt }},
+		{name: "filter header", change: func(snapshot *UTXOSnapshot) { snapshot.Filter.Header[0] = 0 }},
+		{name: "output", change: func(snapshot *UTXOSnapshot) { snapshot.Entries[0].Outputs.Outputs[0].Value++ }},
+		{name: "missing entry", change: func(snapshot *UTXOSnapshot) { snapshot.Entries = snapshot.Entries[:1] }},
+	}
+
+	for _, test := range changes {
+		t.Run(test.name, func(t *testing.T) {
+			snapshot := testSnapshot()
+			test.change(snapshot)
+
+			changedHash, err := snapshot.ContentHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bytes.Equal(changedHash, hash) {
+				t.Errorf("content hash does not change with the %s", test.name)
+			}
+		})
+	}
+
+	unsorted := testSnapshot()
+	unsorted.Entries[0], unsorted.Entries[1] = unsorted.Entries[1], unsorted.Entries[0]
+
+	_, err = unsorted.ContentHash()
+	if !errors.Is(err, ErrSnapshotInvalid) {
+		t.Errorf("hashed unsorted entries with %v, expected %v", err, ErrSnapshotInvalid)
+	}
+}
+
+func TestResolveTrustedHash(t *testing.T) {
+	snapshot := testSnapshot()
+	compiledHash := bytes.Repeat([]byte{0x11}, 32)
+	givenHash := bytes.Repeat([]byte{0x22}, 32)
+
+	trustedSnapshots := TrustedSnapshots
+	defer func() { TrustedSnapshots = trustedSnapshots }()
+
+	TrustedSnapshots = []TrustedSnapshot{{Height: snapshot.Height, TipHash: hex.EncodeToString(snapshot.TipHash), Hash: hex.EncodeToString(compiledHash)}}
+
+	otherTip := testSnapshot()
+	otherTip.Height++
+
+	tests := []struct {
+		name      string
+		snapshot  *UTXOSnapshot
+		givenHash []byte
+		expected  []byte
+		err       error
+	}{
+		{name: "compiled in hash", snapshot: snapshot, expected: compiledHash},
+		{name: "given hash matching the compiled in hash", snapshot: snapshot, givenHash: compiledHash, expected: compiledHash},
+		{name: "given hash differing from the compiled in hash", snapshot: snapshot, givenHash: givenHash, err: ErrSnapshotNotTrusted},
+		{name: "given hash without a compiled in hash", snapshot: otherTip, givenHash: givenHash, expected: givenHash},
+		{name: "no hash", snapshot: otherTip, err: ErrSnapshotNotTrusted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := resolveTrustedHash(test.snapshot, test.givenHash)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("resolved with %v, expected %v", err, test.err)
+			}
+
+			if !bytes.Equal(hash, test.expected) {
+				t.Errorf("resolved %x, expected %x", hash, test.expected)
+			}
+		})
+	}
+}
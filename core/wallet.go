@@ -2,6 +2,7 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -17,6 +18,12 @@ import (
 
 const version = byte(0x00)
 
+const (
+	privKeyVersion = byte(0x80)
+	compressedFlag = byte(0x01)
+	privKeyLength  = 32
+)
+
 // Wallet stores private and public keys
 type Wallet struct {
 	cfg        *model.Config
@@ -48,11 +55,122 @@ func newKeyPair() (*ecdsa.PrivateKey, []byte, error) {
 		return nil, nil, utils.CatchErr(err)
 	}
 
-	pubKey := append(privKey.PublicKey.X.Bytes(), privKey.PublicKey.Y.Bytes()...)
+	pubKey := uncompressedPubKey(&privKey.PublicKey)
 
 	return privKey, pubKey, nil
 }
 
+// IsCompressed checks whether the wallet address is derived from the compressed form of its public key
+func (w *Wallet) IsCompressed() bool {
+	return isCompressedPubKey(w.PublicKey)
+}
+
+// EncodePrivateKey encodes the private key with the network prefix, the compressed flag and a checksum into Base58
+func (w *Wallet) EncodePrivateKey() []byte {
+	payload := append([]byte{privKeyVersion}, w.PrivateKey.D.FillBytes(make([]byte, privKeyLength))...)
+	if w.IsCompressed() {
+		payload = append(payload, compressedFlag)
+	}
+
+	fullPayload := append(payload, checkSum(payload, w.cfg.WalletConfig.CheckSumLength)...)
+
+	return utils.Base58Encode(fullPayload)
+}
+
+// DecodePrivateKey decodes a private key encoded by EncodePrivateKey and returns its wallet
+func DecodePrivateKey(cfg *model.Config, encoded string) (*Wallet, error) {
+	checkSumLength := cfg.WalletConfig.CheckSumLength
+
+	if !utils.IsBase58([]byte(encoded)) {
+		return nil, fmt.Errorf("private key is not Base58 encoded")
+	}
+
+	fullPayload := utils.Base58Decode([]byte(encoded))
+	if len(fullPayload) != 1+privKeyLength+checkSumLength && len(fullPayload) != 2+privKeyLength+checkSumLength {
+		return nil, fmt.Errorf("private key has an invalid length")
+	}
+
+	payload := fullPayload[:len(fullPayload)-checkSumLength]
+	if !bytes.Equal(fullPayload[len(payload):], checkSum(payload, checkSumLength)) {
+		return nil, fmt.Errorf("private key checksum does not match")
+	}
+
+	if payload[0] != privKeyVersion {
+		return nil, fmt.Errorf("private key belongs to another network")
+	}
+
+	compressed := len(payload) == 2+privKeyLength
+	if compressed && payload[len(payload)-1] != compressedFlag {
+		return nil, fmt.Errorf("private key has an invalid compressed flag")
+	}
+
+	ecdhKey, err := ecdh.P256().NewPrivateKey(payload[1 : 1+privKeyLength])
+	if err != nil {
+		return nil, fmt.Errorf("private key is not a valid P-256 scalar")
+	}
+
+	// The public key is serialized as 0x04 followed by the padded X and Y coordinates
+	point := ecdhKey.PublicKey().Bytes()
+
+	privKey := ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1 : 1+privKeyLength]),
+			Y:     new(big.Int).SetBytes(point[1+privKeyLength:]),
+		},
+		D: new(big.Int).SetBytes(payload[1 : 1+privKeyLength]),
+	}
+
+	pubKey := uncompressedPubKey(&privKey.PublicKey)
+	if compressed {
+		pubKey = elliptic.MarshalCompressed(privKey.Curve, privKey.PublicKey.X, privKey.PublicKey.Y)
+	}
+
+	wallet := Wallet{
+		cfg:        cfg,
+		PrivateKey: privKey,
+		PublicKey:  pubKey,
+	}
+
+	return &wallet, nil
+}
+
+// uncompressedPubKey returns the X and Y coordinates of the public key, each padded to the length of a private key
+func uncompressedPubKey(pubKey *ecdsa.PublicKey) []byte {
+	return append(pubKey.X.FillBytes(make([]byte, privKeyLength)), pubKey.Y.FillBytes(make([]byte, privKeyLength))...)
+}
+
+// isCompressedPubKey checks whether a public key is in the compressed form of a prefix byte and the X coordinate
+func isCompressedPubKey(pubKey []byte) bool {
+	return len(pubKey) == 1+privKeyLength && (pubKey[0] == 0x02 || pubKey[0] == 0x03)
+}
+
+// publicKeyPoint returns the coordinates of a public key in either form, or nil if a compressed key is not on the curve
+func publicKeyPoint(pubKey []byte) (*big.Int, *big.Int) {
+	if isCompressedPubKey(pubKey) {
+		return elliptic.UnmarshalCompressed(elliptic.P256(), pubKey)
+	}
+
+	keyLen := len(pubKey)
+	x := new(big.Int).SetBytes(pubKey[:(keyLen / 2)])
+	y := new(big.Int).SetBytes(pubKey[(keyLen / 2):])
+
+	// Keys were once written without the leading zero bytes of their coordinates, so a shorter key is split where it
+	// gives a point on the curve
+	if keyLen > privKeyLength && keyLen < 2*privKeyLength && !elliptic.P256().IsOnCurve(x, y) {
+		for xLen := keyLen - privKeyLength; xLen <= privKeyLength; xLen++ {
+			x.SetBytes(pubKey[:xLen])
+			y.SetBytes(pubKey[xLen:])
+
+			if elliptic.P256().IsOnCurve(x, y) {
+				break
+			}
+		}
+	}
+
+	return x, y
+}
+
 // HashPubKey hashes public key
 func HashPubKey(pubKey []byte) ([]byte, error) {
 	publicSHA256 := sha256.Sum256(pubKey)
@@ -82,29 +200,48 @@ func (w *Wallet) GetAddress() ([]byte, error) {
 		return nil, utils.CatchErr(err)
 	}
 
+	return EncodeAddress(w.cfg, pubKeyHash), nil
+}
+
+// EncodeAddress encodes a public key hash into an address
+func EncodeAddress(cfg *model.Config, pubKeyHash []byte) []byte {
 	versionPayload := append([]byte{version}, pubKeyHash...)
 
-	checkSumLength := w.cfg.WalletConfig.CheckSumLength
+	checkSumLength := cfg.WalletConfig.CheckSumLength
 
 	checkSum := checkSum(versionPayload, checkSumLength)
 
 	fullPayload := append(versionPayload, checkSum...)
 	address := utils.Base58Encode(fullPayload)
 
-	return address, nil
+	return address
+}
+
+// DecodeAddress decodes an address into its public key hash
+func DecodeAddress(cfg *model.Config, address string) []byte {
+	checkSumLength := cfg.WalletConfig.CheckSumLength
+
+	pubKeyHash := utils.Base58Decode([]byte(address))
+
+	return pubKeyHash[1 : len(pubKeyHash)-checkSumLength]
 }
 
 // ValidateAddress checks if address if valid
 func ValidateAddress(cfg *model.Config, address string) (*bool, error) {
 	checkSumLength := cfg.WalletConfig.CheckSumLength
 
+	result := false
+	if !utils.IsBase58([]byte(address)) || len(utils.Base58Decode([]byte(address))) <= checkSumLength+1 {
+		return &result, nil
+	}
+
 	pubKeyHash := utils.Base58Decode([]byte(address))
 	actualChecksum := pubKeyHash[len(pubKeyHash)-checkSumLength:]
 	version := pubKeyHash[0]
 	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-checkSumLength]
 	targetChecksum := checkSum(append([]byte{version}, pubKeyHash...), checkSumLength)
 
-	result := bytes.Equal(actualChecksum, targetChecksum)
+	result = bytes.Equal(actualChecksum, targetChecksum)
 
 	return &result, nil
 }
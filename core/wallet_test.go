@@ -0,0 +1,149 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"go-burrokuchen/utils"
+	"testing"
+)
+
+func TestAddressRoundTrip(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		name       string
+		pubKeyHash []byte
+	}{
+		{name: "no leading zero", pubKeyHash: bytes.Repeat([]byte{0xab}, 20)},
+		{name: "leading zero", pubKeyHash: append([]byte{0x00}, bytes.Repeat([]byte{0xab}, 19)...)},
+		{name: "leading zeros", pubKeyHash: append([]byte{0x00, 0x00, 0x00}, bytes.Repeat([]byte{0xab}, 17)...)},
+		{name: "only zeros", pubKeyHash: make([]byte, 20)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address := string(EncodeAddress(cfg, test.pubKeyHash))
+
+			isValid, err := ValidateAddress(cfg, address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !*isValid {
+				t.Errorf("address %s is not valid", address)
+			}
+
+			if decoded := DecodeAddress(cfg, address); !bytes.Equal(decoded, test.pubKeyHash) {
+				t.Errorf("address %s decodes to %x, expected %x", address, decoded, test.pubKeyHash)
+			}
+		})
+	}
+}
+
+// shortCoordinateKey returns a new key whose X or Y coordinate, as chosen, starts with a zero byte
+func shortCoordinateKey(t *testing.T, shortX bool) *ecdsa.PrivateKey {
+	t.Helper()
+
+	for {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		coordinate := privKey.PublicKey.Y
+		if shortX {
+			coordinate = privKey.PublicKey.X
+		}
+
+		if coordinate.BitLen() <= 8*(privKeyLength-1) {
+			return privKey
+		}
+	}
+}
+
+func TestPublicKeyPoint(t *testing.T) {
+	for _, shortX := range []bool{true, false} {
+		privKey := shortCoordinateKey(t, shortX)
+		pubKey := privKey.PublicKey
+
+		padded := uncompressedPubKey(&pubKey)
+		if len(padded) != 2*privKeyLength {
+			t.Errorf("public key is %d bytes, expected %d", len(padded), 2*privKeyLength)
+		}
+
+		// Keys written before the coordinates were padded are still read
+		unpadded := append(pubKey.X.Bytes(), pubKey.Y.Bytes()...)
+
+		for _, key := range [][]byte{padded, unpadded} {
+			x, y := publicKeyPoint(key)
+			if x.Cmp(pubKey.X) != 0 || y.Cmp(pubKey.Y) != 0 {
+				t.Errorf("%d byte key with a short X %t read as the wrong point", len(key), shortX)
+			}
+		}
+	}
+}
+
+func TestPrivateKeyRoundTrip(t *testing.T) {
+	cfg := testConfig()
+	wallet, _ := testWallet(t, cfg)
+
+	compressed := &Wallet{
+		cfg:        cfg,
+		PrivateKey: wallet.PrivateKey,
+		PublicKey:  elliptic.MarshalCompressed(elliptic.P256(), wallet.PrivateKey.X, wallet.PrivateKey.Y),
+	}
+
+	for _, wallet := range []*Wallet{wallet, compressed} {
+		decoded, err := DecodePrivateKey(cfg, string(wallet.EncodePrivateKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.PrivateKey.D.Cmp(wallet.PrivateKey.D) != 0 {
+			t.Errorf("decoded private key %x, expected %x", decoded.PrivateKey.D, wallet.PrivateKey.D)
+		}
+
+		if !bytes.Equal(decoded.PublicKey, wallet.PublicKey) {
+			t.Errorf("decoded public key %x, expected %x", decoded.PublicKey, wallet.PublicKey)
+		}
+	}
+}
+
+func TestDecodePrivateKeyRejections(t *testing.T) {
+	cfg := testConfig()
+	wallet, _ := testWallet(t, cfg)
+
+	checkSumLength := cfg.WalletConfig.CheckSumLength
+	key := wallet.PrivateKey.D.FillBytes(make([]byte, privKeyLength))
+
+	// encode returns the Base58 encoding of the payload followed by its checksum
+	encode := func(payload []byte) string {
+		return string(utils.Base58Encode(append(payload, checkSum(payload, checkSumLength)...)))
+	}
+
+	badCheckSum := append(append([]byte{privKeyVersion}, key...), checkSum(append([]byte{privKeyVersion}, key...), checkSumLength)...)
+	badCheckSum[len(badCheckSum)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "bad checksum", encoded: string(utils.Base58Encode(badCheckSum))},
+		{name: "bad version", encoded: encode(append([]byte{0xef}, key...))},
+		{name: "bad compressed flag", encoded: encode(append(append([]byte{privKeyVersion}, key...), 0x02))},
+		{name: "bad length", encoded: encode(append([]byte{privKeyVersion}, key[1:]...))},
+		{name: "zero key", encoded: encode(append([]byte{privKeyVersion}, make([]byte, privKeyLength)...))},
+		{name: "not Base58", encoded: "0OIl"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodePrivateKey(cfg, test.encoded)
+			if err == nil {
+				t.Error("decoded the private key without an error")
+			}
+		})
+	}
+}
@@ -2,26 +2,43 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdh"
 	"crypto/elliptic"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"go-burrokuchen/model"
 	"go-burrokuchen/utils"
 	"io/fs"
 	"os"
+	"slices"
 )
 
-// Wallets stores a collection of wallets
+// DefaultAccount is the account of addresses that were not assigned to one
+const DefaultAccount = "default"
+
+// ErrWatchOnly is returned when signing is requested for an address whose private key is not in the wallet
+var ErrWatchOnly = errors.New("address is watch-only, its private key is not in the wallet")
+
+// Wallets stores a collection of wallets, watch-only addresses, the labels and accounts of their addresses and an address book of contacts
 type Wallets struct {
-	cfg     *model.Config
-	Wallets map[string]*Wallet
+	cfg       *model.Config
+	Wallets   map[string]*Wallet
+	WatchOnly map[string][]byte
+	Labels    map[string]string
+	Accounts  map[string]string
+	Contacts  map[string]string
 }
 
 // NewWallets creates Wallets and retrieves it from a file if it exists
 func NewWallets(cfg *model.Config) (*Wallets, error) {
 	wallets := Wallets{
-		cfg:     cfg,
-		Wallets: make(map[string]*Wallet),
+		cfg:       cfg,
+		Wallets:   make(map[string]*Wallet),
+		WatchOnly: make(map[string][]byte),
+		Labels:    make(map[string]string),
+		Accounts:  make(map[string]string),
+		Contacts:  make(map[string]string),
 	}
 
 	err := wallets.LoadFromFile()
@@ -61,7 +78,7 @@ func (ws *Wallets) LoadFromFile() error {
 
 	fileContent, err := os.ReadFile(walletFile)
 	if err != nil {
-		utils.CatchErr(err)
+		return utils.CatchErr(err)
 	}
 
 	var wallets Wallets
@@ -70,11 +87,30 @@ func (ws *Wallets) LoadFromFile() error {
 	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
 	err = decoder.Decode(&wallets)
 	if err != nil {
-		utils.CatchErr(err)
+		return utils.CatchErr(err)
 	}
 
 	ws.Wallets = wallets.Wallets
 
+	// The config is not part of the encoded wallets
+	for _, wallet := range ws.Wallets {
+		wallet.cfg = ws.cfg
+	}
+
+	// Wallet files written before watch-only addresses, labels, accounts and contacts existed do not contain them
+	if wallets.WatchOnly != nil {
+		ws.WatchOnly = wallets.WatchOnly
+	}
+	if wallets.Labels != nil {
+		ws.Labels = wallets.Labels
+	}
+	if wallets.Accounts != nil {
+		ws.Accounts = wallets.Accounts
+	}
+	if wallets.Contacts != nil {
+		ws.Contacts = wallets.Contacts
+	}
+
 	return nil
 }
 
@@ -83,6 +119,243 @@ func (ws *Wallets) GetWallet(address string) Wallet {
 	return *ws.Wallets[address]
 }
 
+// GetSigningWallet returns the Wallet of an address, refusing watch-only addresses
+func (ws *Wallets) GetSigningWallet(address string) (*Wallet, error) {
+	if ws.IsWatchOnly(address) {
+		return nil, fmt.Errorf("cannot sign for %s: %w", address, ErrWatchOnly)
+	}
+
+	wallet := ws.Wallets[address]
+	if wallet == nil {
+		return nil, fmt.Errorf("address %s is not in the wallet", address)
+	}
+
+	return wallet, nil
+}
+
+// HasAddress reports whether the address belongs to the wallets, including watch-only addresses
+func (ws *Wallets) HasAddress(address string) bool {
+	_, watchOnly := ws.WatchOnly[address]
+
+	return ws.Wallets[address] != nil || watchOnly
+}
+
+// IsWatchOnly reports whether the address was imported without its private key
+func (ws *Wallets) IsWatchOnly(address string) bool {
+	_, watchOnly := ws.WatchOnly[address]
+
+	return watchOnly
+}
+
+// ImportAddress adds a watch-only address, whose balance and history are tracked without being able to spend it
+func (ws *Wallets) ImportAddress(address string) error {
+	isValid, err := ValidateAddress(ws.cfg, address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		return fmt.Errorf("address %s is not valid", address)
+	}
+
+	if ws.HasAddress(address) {
+		return fmt.Errorf("address %s is already in the wallet", address)
+	}
+
+	ws.WatchOnly[address] = nil
+
+	return nil
+}
+
+// ImportPubKey adds the address of a public key as watch-only, keeping the public key, and returns the address
+func (ws *Wallets) ImportPubKey(pubKey []byte) (*string, error) {
+	if isCompressedPubKey(pubKey) {
+		x, _ := publicKeyPoint(pubKey)
+		if x == nil {
+			return nil, fmt.Errorf("public key is not a valid P-256 point")
+		}
+	} else {
+		if len(pubKey) == 0 || len(pubKey)%2 != 0 || len(pubKey) > 64 {
+			return nil, fmt.Errorf("public key must be the X and Y coordinates of a P-256 point or its compressed form")
+		}
+
+		// Coordinates are stored without leading zeros, so pad them back to check that the point is on the curve
+		point := make([]byte, 65)
+		point[0] = 4
+		copy(point[33-len(pubKey)/2:33], pubKey[:len(pubKey)/2])
+		copy(point[65-len(pubKey)/2:], pubKey[len(pubKey)/2:])
+
+		_, err := ecdh.P256().NewPublicKey(point)
+		if err != nil {
+			return nil, fmt.Errorf("public key is not a valid P-256 point")
+		}
+	}
+
+	pubKeyHash, err := HashPubKey(pubKey)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	address := string(EncodeAddress(ws.cfg, pubKeyHash))
+
+	if ws.Wallets[address] != nil || ws.WatchOnly[address] != nil {
+		return nil, fmt.Errorf("address %s is already in the wallet", address)
+	}
+
+	// An address imported earlier without its public key gets the key added
+	ws.WatchOnly[address] = pubKey
+
+	return &address, nil
+}
+
+// ImportPrivateKey adds the wallet of a decoded private key, turning a watch-only address into a spendable one, and returns its address
+func (ws *Wallets) ImportPrivateKey(wallet *Wallet) (*string, error) {
+	addressBytes, err := wallet.GetAddress()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	address := string(addressBytes)
+
+	if ws.Wallets[address] != nil {
+		return nil, fmt.Errorf("address %s is already in the wallet", address)
+	}
+
+	delete(ws.WatchOnly, address)
+	ws.Wallets[address] = wallet
+
+	return &address, nil
+}
+
+// GetAddresses returns the addresses of the wallets, including watch-only addresses, in sorted order
+func (ws *Wallets) GetAddresses() []string {
+	var addresses []string
+	for address := range ws.Wallets {
+		addresses = append(addresses, address)
+	}
+	for address := range ws.WatchOnly {
+		addresses = append(addresses, address)
+	}
+
+	slices.Sort(addresses)
+
+	return addresses
+}
+
+// SetLabel labels one of the addresses of the wallets, removing the label when it is empty
+func (ws *Wallets) SetLabel(address string, label string) error {
+	if !ws.HasAddress(address) {
+		return fmt.Errorf("address %s is not in the wallet", address)
+	}
+
+	if label == "" {
+		delete(ws.Labels, address)
+	} else {
+		ws.Labels[address] = label
+	}
+
+	return nil
+}
+
+// GetLabel returns the label of an address
+func (ws *Wallets) GetLabel(address string) string {
+	return ws.Labels[address]
+}
+
+// SetAccount moves one of the addresses of the wallets into an account
+func (ws *Wallets) SetAccount(address string, account string) error {
+	if !ws.HasAddress(address) {
+		return fmt.Errorf("address %s is not in the wallet", address)
+	}
+
+	if account == "" || account == DefaultAccount {
+		delete(ws.Accounts, address)
+	} else {
+		ws.Accounts[address] = account
+	}
+
+	return nil
+}
+
+// GetAccount returns the account of an address
+func (ws *Wallets) GetAccount(address string) string {
+	account, ok := ws.Accounts[address]
+	if !ok {
+		return DefaultAccount
+	}
+
+	return account
+}
+
+// GetAccounts returns the accounts that have at least one address, in sorted order
+func (ws *Wallets) GetAccounts() []string {
+	var accounts []string
+
+	for _, address := range ws.GetAddresses() {
+		account := ws.GetAccount(address)
+		if !slices.Contains(accounts, account) {
+			accounts = append(accounts, account)
+		}
+	}
+
+	slices.Sort(accounts)
+
+	return accounts
+}
+
+// GetAccountAddresses returns the addresses of an account in sorted order
+func (ws *Wallets) GetAccountAddresses(account string) ([]string, error) {
+	var addresses []string
+
+	for _, address := range ws.GetAddresses() {
+		if ws.GetAccount(address) == account {
+			addresses = append(addresses, address)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("account %s has no addresses", account)
+	}
+
+	return addresses, nil
+}
+
+// AddContact stores an external address in the address book under a name
+func (ws *Wallets) AddContact(name string, address string) error {
+	isValid, err := ValidateAddress(ws.cfg, address)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	if !*isValid {
+		return fmt.Errorf("address %s is not valid", address)
+	}
+
+	ws.Contacts[name] = address
+
+	return nil
+}
+
+// RemoveContact removes a contact from the address book
+func (ws *Wallets) RemoveContact(name string) error {
+	if _, ok := ws.Contacts[name]; !ok {
+		return fmt.Errorf("contact %s is not in the address book", name)
+	}
+
+	delete(ws.Contacts, name)
+
+	return nil
+}
+
+// ResolveAddress returns the address of a contact when given its name, or the given value otherwise
+func (ws *Wallets) ResolveAddress(nameOrAddress string) string {
+	if address, ok := ws.Contacts[nameOrAddress]; ok {
+		return address
+	}
+
+	return nameOrAddress
+}
+
 // SaveToFile saves wallets to a file
 func (ws *Wallets) SaveToFile() error {
 	walletFile := ws.cfg.WalletConfig.WalletFile
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"errors"
+	"go-burrokuchen/model"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testWallets returns empty wallets stored in a temporary wallet file
+func testWallets(t *testing.T) (*model.Config, *Wallets) {
+	t.Helper()
+
+	cfg := testConfig()
+	cfg.WalletConfig.WalletFile = filepath.Join(t.TempDir(), "wallet.dat")
+
+	wallets, err := NewWallets(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg, wallets
+}
+
+// createTestWallet adds a new wallet to the wallets and returns its address
+func createTestWallet(t *testing.T, wallets *Wallets) string {
+	t.Helper()
+
+	address, err := wallets.CreateWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *address
+}
+
+func TestWalletsPersistLabelsAndAccounts(t *testing.T) {
+	cfg, wallets := testWallets(t)
+
+	address := createTestWallet(t, wallets)
+	unlabeled := createTestWallet(t, wallets)
+	_, watchOnly := testWallet(t, cfg)
+	_, contact := testWallet(t, cfg)
+
+	err := wallets.ImportAddress(watchOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range []error{
+		wallets.SetLabel(address, "rent"),
+		wallets.SetAccount(address, "savings"),
+		wallets.SetLabel(unlabeled, "removed"),
+		wallets.SetLabel(unlabeled, ""),
+		wallets.SetLabel(watchOnly, "cold storage"),
+		wallets.SetAccount(watchOnly, "savings"),
+		wallets.AddContact("alice", contact),
+		wallets.SaveToFile(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, outsider := testWallet(t, cfg)
+	err = wallets.SetLabel(outsider, "unknown")
+	if err == nil {
+		t.Error("labeled an address that is not in the wallet")
+	}
+
+	loaded, err := NewWallets(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address         string
+		expectedLabel   string
+		expectedAccount string
+	}{
+		{address: address, expectedLabel: "rent", expectedAccount: "savings"},
+		{address: unlabeled, expectedAccount: DefaultAccount},
+		{address: watchOnly, expectedLabel: "cold storage", expectedAccount: "savings"},
+	}
+
+	for _, test := range tests {
+		if label := loaded.GetLabel(test.address); label != test.expectedLabel {
+			t.Errorf("label of %s is %q, expected %q", test.address, label, test.expectedLabel)
+		}
+
+		if account := loaded.GetAccount(test.address); account != test.expectedAccount {
+			t.Errorf("account of %s is %q, expected %q", test.address, account, test.expectedAccount)
+		}
+	}
+
+	if !loaded.IsWatchOnly(watchOnly) {
+		t.Errorf("address %s is no longer watch-only", watchOnly)
+	}
+
+	if resolved := loaded.ResolveAddress("alice"); resolved != contact {
+		t.Errorf("contact resolves to %s, expected %s", resolved, contact)
+	}
+
+	if accounts := loaded.GetAccounts(); !slices.Equal(accounts, []string{DefaultAccount, "savings"}) {
+		t.Errorf("accounts are %v, expected %v", accounts, []string{DefaultAccount, "savings"})
+	}
+}
+
+func TestAccountBalances(t *testing.T) {
+	cfg, wallets := testWallets(t)
+
+	first := createTestWallet(t, wallets)
+	second := createTestWallet(t, wallets)
+	spending := createTestWallet(t, wallets)
+	_, watchOnly := testWallet(t, cfg)
+
+	err := wallets.ImportAddress(watchOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, address := range []string{first, second, watchOnly} {
+		err := wallets.SetAccount(address, "savings")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bc, err := NewBlockchainWithStore(cfg, NewMemoryChainStore(cfg), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, address := range []string{second, second, spending, watchOnly} {
+		_, err := bc.MineBlock([]*Transaction{testCoinbase(t, cfg, address)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	utxoSet := NewUTXOSet(cfg, bc)
+	subsidy := cfg.TransactionConfig.Subsidy
+
+	tests := []struct {
+		account  string
+		expected int
+	}{
+		{account: "savings", expected: 4 * subsidy},
+		{account: DefaultAccount, expected: subsidy},
+	}
+
+	for _, test := range tests {
+		addresses, err := wallets.GetAccountAddresses(test.account)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		balance := 0
+		for _, address := range addresses {
+			UTXOs, err := utxoSet.FindUTXOByPubKeyHash(DecodeAddress(cfg, address))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, out := range UTXOs.Outputs {
+				balance += out.Value
+			}
+		}
+
+		if balance != test.expected {
+			t.Errorf("balance of account %s is %d, expected %d", test.account, balance, test.expected)
+		}
+	}
+
+	_, err = wallets.GetAccountAddresses("unknown")
+	if err == nil {
+		t.Error("got the addresses of an account without any")
+	}
+}
+
+func TestGetSigningWalletRefusesWatchOnly(t *testing.T) {
+	cfg, wallets := testWallets(t)
+
+	spendable := createTestWallet(t, wallets)
+	watchedWallet, watchedAddress := testWallet(t, cfg)
+	pubKeyWallet, _ := testWallet(t, cfg)
+
+	err := wallets.ImportAddress(watchedAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubKeyAddress, err := wallets.ImportPubKey(pubKeyWallet.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, address := range []string{watchedAddress, *pubKeyAddress} {
+		_, err := wallets.GetSigningWallet(address)
+		if !errors.Is(err, ErrWatchOnly) {
+			t.Errorf("got the signing wallet of %s with %v, expected %v", address, err, ErrWatchOnly)
+		}
+	}
+
+	_, err = wallets.GetSigningWallet(spendable)
+	if err != nil {
+		t.Errorf("refused the signing wallet of %s: %v", spendable, err)
+	}
+
+	// Importing the private key of a watch-only address makes it spendable
+	_, err = wallets.ImportPrivateKey(watchedWallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wallets.GetSigningWallet(watchedAddress)
+	if err != nil {
+		t.Errorf("refused the signing wallet of %s after importing its key: %v", watchedAddress, err)
+	}
+}
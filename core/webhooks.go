@@ -0,0 +1,290 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"time"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a URL notified when payments to an address reach a number of confirmations
+type Webhook struct {
+	ID            string
+	Address       string
+	URL           string
+	Secret        string
+	Confirmations int
+	ScannedHeight int
+	CreatedAt     int64
+}
+
+// Delivery is the notification of a single payment to a webhook
+type Delivery struct {
+	WebhookID     string
+	TransactionID []byte
+	OutputIndex   int
+	Value         int
+	BlockHash     []byte
+	Status        string
+	Attempts      int
+	NextAttempt   int64
+	LastError     string
+	DeliveredAt   int64
+}
+
+// Key returns the key of the delivery, which is unique per webhook and payment
+func (d *Delivery) Key() string {
+	return fmt.Sprintf("%s:%s:%d", d.WebhookID, hex.EncodeToString(d.TransactionID), d.OutputIndex)
+}
+
+// Webhooks stores webhooks and the state of their deliveries
+type Webhooks struct {
+	cfg        *model.Config
+	Blockchain *Blockchain
+}
+
+// NewWebhooks generates and returns a new webhooks object
+func NewWebhooks(cfg *model.Config, blockchain *Blockchain) *Webhooks {
+	return &Webhooks{cfg: cfg, Blockchain: blockchain}
+}
+
+// Add registers a new webhook, generating its ID and, when empty, its secret
+func (ws *Webhooks) Add(address string, url string, confirmations int, secret string) (*Webhook, error) {
+	id := make([]byte, 8)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if secret == "" {
+		randomSecret := make([]byte, 32)
+
+		_, err = rand.Read(randomSecret)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		secret = hex.EncodeToString(randomSecret)
+	}
+
+	height, err := ws.Blockchain.GetBestHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// Payments confirmed before the webhook existed are not notified
+	webhook := &Webhook{
+		ID:            hex.EncodeToString(id),
+		Address:       address,
+		URL:           url,
+		Secret:        secret,
+		Confirmations: confirmations,
+		ScannedHeight: *height - confirmations + 1,
+		CreatedAt:     time.Now().Unix(),
+	}
+
+	err = ws.Put(webhook)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return webhook, nil
+}
+
+// Put stores a webhook
+func (ws *Webhooks) Put(webhook *Webhook) error {
+	encoded, err := gobEncode(webhook)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = ws.Blockchain.Store.Update(func(tx ChainTx) error {
+		return tx.PutRecord(ws.cfg.DatabaseConfig.WebhookBucket, []byte(webhook.ID), encoded)
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// Remove deletes a webhook along with its deliveries
+func (ws *Webhooks) Remove(id string) error {
+	webhookBucket := ws.cfg.DatabaseConfig.WebhookBucket
+	deliveryBucket := ws.cfg.DatabaseConfig.WebhookDeliveryBucket
+
+	err := ws.Blockchain.Store.Update(func(tx ChainTx) error {
+		if tx.Record(webhookBucket, []byte(id)) == nil {
+			return ErrWebhookNotFound
+		}
+
+		err := tx.DeleteRecord(webhookBucket, []byte(id))
+		if err != nil {
+			return utils.CatchErr(err)
+		}
+
+		prefix := []byte(id + ":")
+
+		return tx.ForEachRecord(deliveryBucket, func(k, v []byte) error {
+			if !bytes.HasPrefix(k, prefix) {
+				return nil
+			}
+
+			return tx.DeleteRecord(deliveryBucket, k)
+		})
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// List returns every registered webhook
+func (ws *Webhooks) List() ([]*Webhook, error) {
+	var webhooks []*Webhook
+
+	err := ws.Blockchain.Store.View(func(tx ChainTx) error {
+		return tx.ForEachRecord(ws.cfg.DatabaseConfig.WebhookBucket, func(k, v []byte) error {
+			var webhook Webhook
+
+			err := gobDecode(v, &webhook)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			webhooks = append(webhooks, &webhook)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return webhooks, nil
+}
+
+// AddDelivery stores a new pending delivery unless one already exists for the same payment
+func (ws *Webhooks) AddDelivery(delivery *Delivery) (*bool, error) {
+	deliveryBucket := ws.cfg.DatabaseConfig.WebhookDeliveryBucket
+
+	added := false
+
+	encoded, err := gobEncode(delivery)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = ws.Blockchain.Store.Update(func(tx ChainTx) error {
+		if tx.Record(deliveryBucket, []byte(delivery.Key())) != nil {
+			return nil
+		}
+
+		added = true
+
+		return tx.PutRecord(deliveryBucket, []byte(delivery.Key()), encoded)
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &added, nil
+}
+
+// PutDelivery stores the state of a delivery
+func (ws *Webhooks) PutDelivery(delivery *Delivery) error {
+	encoded, err := gobEncode(delivery)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	err = ws.Blockchain.Store.Update(func(tx ChainTx) error {
+		return tx.PutRecord(ws.cfg.DatabaseConfig.WebhookDeliveryBucket, []byte(delivery.Key()), encoded)
+	})
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
+
+// DueDeliveries returns the pending deliveries whose next attempt is due at the given time
+func (ws *Webhooks) DueDeliveries(now int64) ([]*Delivery, error) {
+	deliveries, err := ws.Deliveries()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var due []*Delivery
+
+	for _, delivery := range deliveries {
+		if delivery.Status == DeliveryPending && delivery.NextAttempt <= now {
+			due = append(due, delivery)
+		}
+	}
+
+	return due, nil
+}
+
+// Deliveries returns every stored delivery
+func (ws *Webhooks) Deliveries() ([]*Delivery, error) {
+	var deliveries []*Delivery
+
+	err := ws.Blockchain.Store.View(func(tx ChainTx) error {
+		return tx.ForEachRecord(ws.cfg.DatabaseConfig.WebhookDeliveryBucket, func(k, v []byte) error {
+			var delivery Delivery
+
+			err := gobDecode(v, &delivery)
+			if err != nil {
+				return utils.CatchErr(err)
+			}
+
+			deliveries = append(deliveries, &delivery)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return deliveries, nil
+}
+
+// gobEncode serializes a value with gob
+func gobEncode(v any) ([]byte, error) {
+	var buff bytes.Buffer
+
+	err := gob.NewEncoder(&buff).Encode(v)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return buff.Bytes(), nil
+}
+
+// gobDecode deserializes a gob encoded value
+func gobDecode(data []byte, v any) error {
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ require (
 	github.com/spf13/cobra v1.8.1
 	github.com/spf13/viper v1.20.1
 	go.etcd.io/bbolt v1.3.11
+	golang.org/x/term v0.28.0
 )
 
 require (
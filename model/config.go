@@ -1,26 +1,47 @@
 package model
 
+import "time"
+
 type Config struct {
 	DatabaseConfig    DatabaseConfig
 	ProofOfWorkConfig ProofOfWorkConfig
 	TransactionConfig TransactionConfig
 	WalletConfig      WalletConfig
 	ServerConfig      ServerConfig
+	APIConfig         APIConfig
+	WebhookConfig     WebhookConfig
+	LightClientConfig LightClientConfig
+	MempoolConfig     MempoolConfig
+	SnapshotConfig    SnapshotConfig
+	BackupConfig      BackupConfig
 }
 
 type DatabaseConfig struct {
-	DbName        string
-	BlocksBucket  string
-	UTXOSetBucket string
+	DbName                string
+	BlocksBucket          string
+	UTXOSetBucket         string
+	IndexBucket           string
+	WebhookBucket         string
+	WebhookDeliveryBucket string
+	HeadersBucket         string
+	FiltersBucket         string
+	MempoolBucket         string
+	SnapshotBucket        string
+	MetaBucket            string
+	PruneDepth            int
+	AutoMigrate           bool
+	LockTimeout           time.Duration
 }
 
 type ProofOfWorkConfig struct {
 	TargetBits int
+	Algorithm  string
 }
 
 type TransactionConfig struct {
 	Subsidy             int
 	GenesisCoinbaseData string
+	MaxDataSize         int
 }
 
 type WalletConfig struct {
@@ -33,4 +54,46 @@ type ServerConfig struct {
 	Protocol           string
 	NodeVersion        int
 	CommandLength      int
+	Network            string
+}
+
+type APIConfig struct {
+	Address            string
+	DefaultPageLimit   int
+	MaxPageLimit       int
+	PollInterval       time.Duration
+	Socket             string
+	AllowedOrigins     []string
+	AllowMissingOrigin bool
+}
+
+type WebhookConfig struct {
+	Timeout        time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+type LightClientConfig struct {
+	Enabled    bool
+	FullNode   string
+	DbName     string
+	UseFilters bool
+}
+
+type MempoolConfig struct {
+	MaxAge       time.Duration
+	MaxSize      int
+	MaxBlockSize int
+}
+
+type SnapshotConfig struct {
+	Source      string
+	TrustedHash string
+}
+
+type BackupConfig struct {
+	Dir       string
+	Interval  time.Duration
+	Retention int
 }
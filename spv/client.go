@@ -0,0 +1,348 @@
+package spv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/model"
+	"go-burrokuchen/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRollback is how many headers the client disconnects while looking for a common ancestor with the full node
+const maxRollback = 100
+
+// VerifiedUTXO is an unspent output whose transaction was proven to be included in a stored header
+type VerifiedUTXO struct {
+	core.UTXO
+	Transaction *core.Transaction
+	Height      int
+}
+
+// Client is a light client that keeps only block headers and verifies everything else with Merkle proofs
+type Client struct {
+	cfg        *model.Config
+	httpClient *http.Client
+	Headers    *core.HeaderStore
+}
+
+// NewClient generates and returns a light client backed by the header store
+func NewClient(cfg *model.Config, headers *core.HeaderStore) *Client {
+	return &Client{cfg: cfg, httpClient: &http.Client{Timeout: 30 * time.Second}, Headers: headers}
+}
+
+// SyncHeaders downloads the headers the full node has above the local tip, checking their linkage and proof of work.
+// When the full node is on another branch, the client only switches to it if that branch has more work
+func (c *Client) SyncHeaders() (*int, error) {
+	limit := c.cfg.APIConfig.DefaultPageLimit
+	synced := 0
+
+	var stats api.StatsResponse
+
+	err := c.get("/stats", &stats)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if stats.PowAlgorithm != c.Headers.PowAlgorithm {
+		return nil, fmt.Errorf("%w: the full node mines with %s, the headers are checked with %s", core.ErrPowAlgorithmMismatch, stats.PowAlgorithm, c.Headers.PowAlgorithm)
+	}
+
+	for {
+		height, err := c.Headers.Height()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		headers, err := c.getHeaders(*height+1, limit)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if len(headers) == 0 {
+			return &synced, nil
+		}
+
+		if !bytes.Equal(headers[0].PrevBlockHash, c.Headers.Tip) {
+			switched, err := c.switchBranch(*height)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			if *switched == 0 {
+				log.WithField("tip", hex.EncodeToString(c.Headers.Tip)).Warn("Full node is on a branch without more work, keeping the stored headers")
+				return &synced, nil
+			}
+
+			synced += *switched
+			continue
+		}
+
+		for _, header := range headers {
+			err = c.Headers.AddHeader(header)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			synced++
+		}
+
+		if len(headers) < limit {
+			return &synced, nil
+		}
+	}
+}
+
+// switchBranch looks for the last header the full node has in common with the stored headers up to the given height,
+// downloads the branch of the full node above it and switches to it when it has more work. It returns how many headers
+// of the branch were stored, none when the stored headers were kept
+func (c *Client) switchBranch(height int) (*int, error) {
+	limit := c.cfg.APIConfig.DefaultPageLimit
+	ancestorHeight := height
+
+	var ancestor []byte
+
+	for ; ancestorHeight >= 0; ancestorHeight-- {
+		if height-ancestorHeight == maxRollback {
+			return nil, fmt.Errorf("no common ancestor with the full node in the last %d headers", maxRollback)
+		}
+
+		hash, err := c.Headers.GetHash(ancestorHeight)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		nodeHeaders, err := c.getHeaders(ancestorHeight, 1)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if len(nodeHeaders) == 1 && bytes.Equal(nodeHeaders[0].Hash, hash) {
+			ancestor = hash
+			break
+		}
+	}
+
+	// The branch is downloaded until it is longer than the stored one above the ancestor, the rest is synced once switched
+	var branch []*core.BlockHeader
+
+	for len(branch) <= height-ancestorHeight {
+		headers, err := c.getHeaders(ancestorHeight+1+len(branch), limit)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		branch = append(branch, headers...)
+
+		if len(headers) < limit {
+			break
+		}
+	}
+
+	switched := 0
+
+	if len(branch) == 0 {
+		return &switched, nil
+	}
+
+	isSwitched, err := c.Headers.SwitchBranch(ancestor, branch)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if isSwitched {
+		log.WithFields(log.Fields{"ancestor": hex.EncodeToString(ancestor), "disconnected": height - ancestorHeight}).Warn("Switched to the branch of the full node, which has more work")
+
+		switched = len(branch)
+	}
+
+	return &switched, nil
+}
+
+// getHeaders downloads up to limit headers of the full node starting at the given height
+func (c *Client) getHeaders(from int, limit int) ([]*core.BlockHeader, error) {
+	var response api.HeadersResponse
+
+	err := c.get(fmt.Sprintf("/headers?from=%d&limit=%d", from, limit), &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var headers []*core.BlockHeader
+
+	for _, headerResponse := range response.Headers {
+		header, err := api.ParseBlockHeader(headerResponse)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+// FindUTXOs asks the full node for the unspent outputs of an address and keeps those whose inclusion is proven
+func (c *Client) FindUTXOs(address string) ([]VerifiedUTXO, error) {
+	pubKeyHash := core.DecodeAddress(c.cfg, address)
+
+	var response api.AddressUTXOsResponse
+
+	err := c.get("/address/"+url.PathEscape(address)+"/utxos", &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var UTXOs []VerifiedUTXO
+
+	for _, utxo := range response.UTXOs {
+		transaction, height, err := c.GetVerifiedTransaction(utxo.TransactionID)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if utxo.OutputIndex < 0 || utxo.OutputIndex >= len(transaction.OutputValue) {
+			return nil, fmt.Errorf("full node reported a missing output %s:%d", utxo.TransactionID, utxo.OutputIndex)
+		}
+
+		out := transaction.OutputValue[utxo.OutputIndex]
+		if !out.IsLockedWithKey(pubKeyHash) {
+			return nil, fmt.Errorf("full node reported output %s:%d which does not belong to %s", utxo.TransactionID, utxo.OutputIndex, address)
+		}
+
+		UTXOs = append(UTXOs, VerifiedUTXO{
+			UTXO:        core.UTXO{TransactionID: transaction.ID, OutputIndex: utxo.OutputIndex, Output: out},
+			Transaction: transaction,
+			Height:      *height,
+		})
+	}
+
+	return UTXOs, nil
+}
+
+// GetVerifiedTransaction fetches a transaction with its Merkle proof, checks it against the stored headers and returns it with the height of its block
+func (c *Client) GetVerifiedTransaction(transactionID string) (*core.Transaction, *int, error) {
+	var response api.TransactionProofResponse
+
+	err := c.get("/tx/"+url.PathEscape(transactionID)+"/proof", &response)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	rawTransaction, err := hex.DecodeString(response.RawTransaction)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	transaction, err := core.DeserializeTransaction(rawTransaction)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	if hex.EncodeToString(transaction.ID) != transactionID {
+		return nil, nil, fmt.Errorf("full node returned another transaction than %s", transactionID)
+	}
+
+	blockHash, err := hex.DecodeString(response.Header.Hash)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	header, err := c.Headers.GetHeader(blockHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("block %s of transaction %s is not in the synced headers", response.Header.Hash, transactionID)
+	}
+
+	proof, err := api.ParseTransactionProof(response)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	// The proof is checked against the synced header rather than the one the full node sent
+	proof.Header = *header
+
+	if !proof.Verify() {
+		return nil, nil, fmt.Errorf("invalid Merkle proof for transaction %s", transactionID)
+	}
+
+	height, err := c.Headers.GetHeight(blockHash)
+	if err != nil {
+		return nil, nil, utils.CatchErr(err)
+	}
+
+	return transaction, height, nil
+}
+
+// SubmitTransaction sends a signed transaction to the full node, which adds it to its mempool
+func (c *Client) SubmitTransaction(transaction *core.Transaction) (*api.SubmitTransactionResponse, error) {
+	rawTransaction, err := transaction.Serialize()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	request := api.SubmitTransactionRequest{RawTransaction: hex.EncodeToString(rawTransaction)}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	httpResponse, err := c.httpClient.Post(c.endpoint("/tx"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+	defer httpResponse.Body.Close()
+
+	var response api.SubmitTransactionResponse
+
+	err = decodeResponse(httpResponse, &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return &response, nil
+}
+
+// get fetches a JSON document from the full node
+func (c *Client) get(path string, v any) error {
+	httpResponse, err := c.httpClient.Get(c.endpoint(path))
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+	defer httpResponse.Body.Close()
+
+	return decodeResponse(httpResponse, v)
+}
+
+// endpoint returns the URL of a path on the full node
+func (c *Client) endpoint(path string) string {
+	return strings.TrimRight(c.cfg.LightClientConfig.FullNode, "/") + path
+}
+
+// decodeResponse decodes a JSON response, turning error documents into errors
+func decodeResponse(httpResponse *http.Response, v any) error {
+	if httpResponse.StatusCode != http.StatusOK && httpResponse.StatusCode != http.StatusAccepted {
+		var errResponse struct {
+			Error string `json:"error"`
+		}
+
+		json.NewDecoder(httpResponse.Body).Decode(&errResponse)
+
+		return fmt.Errorf("full node responded with %d: %s", httpResponse.StatusCode, errResponse.Error)
+	}
+
+	err := json.NewDecoder(httpResponse.Body).Decode(v)
+	if err != nil {
+		return utils.CatchErr(err)
+	}
+
+	return nil
+}
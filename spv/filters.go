@@ -0,0 +1,251 @@
+package spv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/utils"
+)
+
+// SyncFilters downloads the filters of the synced headers, checking that they extend the filter header chain
+func (c *Client) SyncFilters() (*int, error) {
+	limit := c.cfg.APIConfig.DefaultPageLimit
+	synced := 0
+
+	for {
+		filterHeight, err := c.Headers.FilterHeight()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		height, err := c.Headers.Height()
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if *filterHeight >= *height {
+			return &synced, nil
+		}
+
+		var response api.FiltersResponse
+
+		err = c.get(fmt.Sprintf("/filters?from=%d&limit=%d", *filterHeight+1, limit), &response)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if len(response.Filters) == 0 {
+			return &synced, nil
+		}
+
+		for _, filterResponse := range response.Filters {
+			if filterResponse.Height > *height {
+				return &synced, nil
+			}
+
+			hash, err := c.Headers.GetHash(filterResponse.Height)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			if filterResponse.BlockHash != hex.EncodeToString(hash) {
+				return nil, fmt.Errorf("full node served the filter of another block at height %d", filterResponse.Height)
+			}
+
+			blockFilter, err := parseBlockFilter(filterResponse)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			err = c.Headers.AddFilter(hash, blockFilter)
+			if err != nil {
+				return nil, utils.CatchErr(err)
+			}
+
+			synced++
+		}
+	}
+}
+
+// ScanUTXOs finds the unspent outputs of an address by matching the stored filters locally, downloading only the matching blocks
+func (c *Client) ScanUTXOs(address string) ([]VerifiedUTXO, error) {
+	tracker, err := c.ScanAddresses([]string{address})
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	var UTXOs []VerifiedUTXO
+
+	for _, utxo := range tracker.UTXOs {
+		UTXOs = append(UTXOs, VerifiedUTXO{UTXO: utxo, Transaction: tracker.Transaction(utxo.TransactionID), Height: tracker.Height(utxo.TransactionID)})
+	}
+
+	return UTXOs, nil
+}
+
+// ScanAddresses applies the blocks whose stored filter matches the addresses to a tracker, downloading only those blocks
+func (c *Client) ScanAddresses(addresses []string) (*core.AddressTracker, error) {
+	var pubKeyHashes [][]byte
+	for _, address := range addresses {
+		pubKeyHashes = append(pubKeyHashes, core.DecodeAddress(c.cfg, address))
+	}
+
+	tracker := core.NewAddressTracker(pubKeyHashes)
+
+	filterHeight, err := c.Headers.FilterHeight()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	for height := 0; height <= *filterHeight; height++ {
+		hash, err := c.Headers.GetHash(height)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		blockFilter, err := c.Headers.GetFilter(hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		filter, err := core.DeserializeGCSFilter(blockFilter.Filter)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		matched, err := filter.MatchAny(hash, tracker.FilterItems())
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if !matched {
+			continue
+		}
+
+		block, err := c.GetVerifiedBlock(hash)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		if !core.VerifyBlockFilter(block, blockFilter.Filter) {
+			return nil, fmt.Errorf("filter of block %x does not match its transactions", hash)
+		}
+
+		tracker.Apply(block, height)
+	}
+
+	return tracker, nil
+}
+
+// FindData finds the earliest block whose stored filter matches the data and that carries it in a data output,
+// downloading only the matching blocks, and returns the transaction, the block and its height
+func (c *Client) FindData(data []byte) (*core.Transaction, *core.Block, *int, error) {
+	filterHeight, err := c.Headers.FilterHeight()
+	if err != nil {
+		return nil, nil, nil, utils.CatchErr(err)
+	}
+
+	for height := 0; height <= *filterHeight; height++ {
+		hash, err := c.Headers.GetHash(height)
+		if err != nil {
+			return nil, nil, nil, utils.CatchErr(err)
+		}
+
+		blockFilter, err := c.Headers.GetFilter(hash)
+		if err != nil {
+			return nil, nil, nil, utils.CatchErr(err)
+		}
+
+		filter, err := core.DeserializeGCSFilter(blockFilter.Filter)
+		if err != nil {
+			return nil, nil, nil, utils.CatchErr(err)
+		}
+
+		matched, err := filter.Match(hash, data)
+		if err != nil {
+			return nil, nil, nil, utils.CatchErr(err)
+		}
+
+		if !matched {
+			continue
+		}
+
+		block, err := c.GetVerifiedBlock(hash)
+		if err != nil {
+			return nil, nil, nil, utils.CatchErr(err)
+		}
+
+		if !core.VerifyBlockFilter(block, blockFilter.Filter) {
+			return nil, nil, nil, fmt.Errorf("filter of block %x does not match its transactions", hash)
+		}
+
+		// Filters have false positives, so the block may not carry the data
+		for _, tx := range block.Transactions {
+			if tx.CarriesData(data) {
+				return tx, block, &height, nil
+			}
+		}
+	}
+
+	return nil, nil, nil, core.ErrTransactionNotFound
+}
+
+// GetVerifiedBlock downloads a block and checks it against the stored header
+func (c *Client) GetVerifiedBlock(hash []byte) (*core.Block, error) {
+	var response api.RawBlockResponse
+
+	err := c.get("/blocks/raw/"+hex.EncodeToString(hash), &response)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	rawBlock, err := hex.DecodeString(response.RawBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	block, err := core.DeserializeBlock(rawBlock)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	storedHeader, err := c.Headers.GetHeader(hash)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	header, err := block.Header()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	if header.Version != storedHeader.Version ||
+		!bytes.Equal(header.Hash, storedHeader.Hash) ||
+		!bytes.Equal(header.PrevBlockHash, storedHeader.PrevBlockHash) ||
+		!bytes.Equal(header.MerkleRoot, storedHeader.MerkleRoot) ||
+		header.Timestamp != storedHeader.Timestamp ||
+		header.Nonce != storedHeader.Nonce {
+		return nil, fmt.Errorf("full node returned a block that does not match header %x", hash)
+	}
+
+	return block, nil
+}
+
+// parseBlockFilter converts the JSON representation of a block filter back into a block filter
+func parseBlockFilter(response api.BlockFilterResponse) (*core.BlockFilter, error) {
+	filter, err := hex.DecodeString(response.Filter)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	header, err := hex.DecodeString(response.FilterHeader)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	blockFilter := core.BlockFilter{Filter: filter, Header: header}
+
+	return &blockFilter, nil
+}
@@ -0,0 +1,137 @@
+package spv
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"go-burrokuchen/api"
+	"go-burrokuchen/core"
+	"go-burrokuchen/utils"
+)
+
+// walletUTXOs finds the unspent outputs of an address, matching block filters locally unless they are disabled
+func (c *Client) walletUTXOs(address string) ([]VerifiedUTXO, error) {
+	if c.cfg.LightClientConfig.UseFilters {
+		return c.ScanUTXOs(address)
+	}
+
+	return c.FindUTXOs(address)
+}
+
+// GetBalance returns the sum of the proven unspent outputs of an address
+func (c *Client) GetBalance(address string) (*int, error) {
+	UTXOs, err := c.walletUTXOs(address)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	balance := 0
+	for _, utxo := range UTXOs {
+		balance += utxo.Output.Value
+	}
+
+	return &balance, nil
+}
+
+// Send spends proven outputs of the wallet, signs the transaction locally and submits it to the mempool of the full node
+func (c *Client) Send(wallet core.Wallet, from string, to string, amount int, options core.TransactionOptions) (*api.SubmitTransactionResponse, error) {
+	response, err := c.sendFrom([]string{from}, map[string]*core.Wallet{from: &wallet}, from, to, amount, options)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return response, nil
+}
+
+// SendFromAccount spends proven outputs of the addresses of an account, signing every input with its own key
+func (c *Client) SendFromAccount(wallets *core.Wallets, account string, to string, amount int, options core.TransactionOptions) (*api.SubmitTransactionResponse, error) {
+	accountAddresses, err := wallets.GetAccountAddresses(account)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	// Watch-only addresses count towards the balance of the account but cannot pay
+	var addresses []string
+	for _, address := range accountAddresses {
+		if !wallets.IsWatchOnly(address) {
+			addresses = append(addresses, address)
+		}
+	}
+
+	response, err := c.sendFrom(addresses, wallets.Wallets, "account "+account, to, amount, options)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return response, nil
+}
+
+// sendFrom spends outputs of the addresses in order until the amount is reached, sending the change to the first address that pays
+func (c *Client) sendFrom(addresses []string, wallets map[string]*core.Wallet, payer string, to string, amount int, options core.TransactionOptions) (*api.SubmitTransactionResponse, error) {
+	var inputs []core.TXInput
+	var privKeys []ecdsa.PrivateKey
+	var change string
+
+	accumulated := 0
+	total := amount + options.Fee
+	prevTXs := make(map[string]core.Transaction)
+
+	height, err := c.Headers.Height()
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	for _, address := range addresses {
+		if accumulated >= total {
+			break
+		}
+
+		UTXOs, err := c.walletUTXOs(address)
+		if err != nil {
+			return nil, utils.CatchErr(err)
+		}
+
+		wallet := wallets[address]
+
+		for _, utxo := range UTXOs {
+			if accumulated >= total {
+				break
+			}
+
+			// Outputs whose relative lock has not expired cannot go into the next block
+			if !utxo.Output.IsMature(utxo.Height, *height+1) {
+				continue
+			}
+
+			if change == "" {
+				change = address
+			}
+
+			accumulated += utxo.Output.Value
+			inputs = append(inputs, core.TXInput{TransactionID: utxo.TransactionID, OutputIndex: utxo.OutputIndex, PubKey: wallet.PublicKey})
+			privKeys = append(privKeys, wallet.PrivateKey)
+			prevTXs[hex.EncodeToString(utxo.TransactionID)] = *utxo.Transaction
+		}
+	}
+
+	if accumulated < total {
+		return nil, fmt.Errorf("%s doesn't have enough funds", payer)
+	}
+
+	transaction, err := core.NewTransactionFromInputs(c.cfg, inputs, to, change, amount, accumulated, options)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	err = transaction.SignInputs(privKeys, prevTXs)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	response, err := c.SubmitTransaction(transaction)
+	if err != nil {
+		return nil, utils.CatchErr(err)
+	}
+
+	return response, nil
+}
@@ -22,7 +22,12 @@ func Base58Encode(input []byte) []byte {
 		result = append(result, b58Alphabet[mod.Int64()])
 	}
 
-	if input[0] == 0x00 {
+	// Every leading zero byte is written as a leading 1, as the number alone drops them
+	for _, b := range input {
+		if b != 0x00 {
+			break
+		}
+
 		result = append(result, b58Alphabet[0])
 	}
 
@@ -43,9 +48,28 @@ func Base58Decode(input []byte) []byte {
 
 	decoded := result.Bytes()
 
-	if input[0] == b58Alphabet[0] {
+	for _, b := range input {
+		if b != b58Alphabet[0] {
+			break
+		}
+
 		decoded = append([]byte{0x00}, decoded...)
 	}
 
 	return decoded
 }
+
+// IsBase58 checks whether the input is a non-empty Base58 string
+func IsBase58(input []byte) bool {
+	if len(input) == 0 {
+		return false
+	}
+
+	for _, b := range input {
+		if bytes.IndexByte(b58Alphabet, b) == -1 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -12,6 +12,45 @@ func LoadConfg() (*model.Config, error) {
 	vip.SetConfigType("yaml")
 
 	vip.AddConfigPath(".")
+
+	vip.SetDefault("database.index_bucket", "index")
+	vip.SetDefault("database.webhook_bucket", "webhooks")
+	vip.SetDefault("database.webhook_delivery_bucket", "webhook_deliveries")
+	vip.SetDefault("database.headers_bucket", "headers")
+	vip.SetDefault("database.filters_bucket", "filters")
+	vip.SetDefault("database.mempool_bucket", "mempool")
+	vip.SetDefault("database.snapshot_bucket", "snapshot")
+	vip.SetDefault("database.meta_bucket", "meta")
+	vip.SetDefault("database.prune_depth", 0)
+	vip.SetDefault("database.auto_migrate", true)
+	vip.SetDefault("database.lock_timeout", "10s")
+	vip.SetDefault("proof_of_work.algorithm", "")
+	vip.SetDefault("transaction.max_data_size", 80)
+	vip.SetDefault("server.network", "main")
+	vip.SetDefault("api.address", "localhost:8080")
+	vip.SetDefault("api.default_page_limit", 10)
+	vip.SetDefault("api.max_page_limit", 100)
+	vip.SetDefault("api.poll_interval", "2s")
+	vip.SetDefault("api.socket", "node.sock")
+	vip.SetDefault("api.allowed_origins", []string{})
+	vip.SetDefault("api.allow_missing_origin", false)
+	vip.SetDefault("webhook.timeout", "10s")
+	vip.SetDefault("webhook.max_attempts", 10)
+	vip.SetDefault("webhook.retry_base_delay", "5s")
+	vip.SetDefault("webhook.retry_max_delay", "1h")
+	vip.SetDefault("light_client.enabled", false)
+	vip.SetDefault("light_client.full_node", "http://localhost:8080")
+	vip.SetDefault("light_client.db_name", "headers.db")
+	vip.SetDefault("light_client.use_filters", true)
+	vip.SetDefault("mempool.max_age", "336h")
+	vip.SetDefault("mempool.max_size", 5000000)
+	vip.SetDefault("mempool.max_block_size", 1000000)
+	vip.SetDefault("snapshot.source", "http://localhost:8080")
+	vip.SetDefault("snapshot.trusted_hash", "")
+	vip.SetDefault("backup.dir", "backups")
+	vip.SetDefault("backup.interval", "0s")
+	vip.SetDefault("backup.retention", 7)
+
 	err := vip.ReadInConfig()
 	if err != nil {
 		return nil, CatchErr(err)
@@ -20,26 +59,76 @@ func LoadConfg() (*model.Config, error) {
 	dbName := vip.GetString("database.name")
 	blocksBucket := vip.GetString("database.blocks_bucket")
 	utxoSetBucket := vip.GetString("database.utxo_set_bucket")
+	indexBucket := vip.GetString("database.index_bucket")
+	webhookBucket := vip.GetString("database.webhook_bucket")
+	webhookDeliveryBucket := vip.GetString("database.webhook_delivery_bucket")
+	headersBucket := vip.GetString("database.headers_bucket")
+	filtersBucket := vip.GetString("database.filters_bucket")
+	mempoolBucket := vip.GetString("database.mempool_bucket")
+	snapshotBucket := vip.GetString("database.snapshot_bucket")
+	metaBucket := vip.GetString("database.meta_bucket")
+	pruneDepth := vip.GetInt("database.prune_depth")
+	autoMigrate := vip.GetBool("database.auto_migrate")
+	lockTimeout := vip.GetDuration("database.lock_timeout")
 	targetBits := vip.GetInt("proof_of_work.target_bits")
+	powAlgorithm := vip.GetString("proof_of_work.algorithm")
 	subsidy := vip.GetInt("transaction.subsidy")
 	genesisCoinbaseData := vip.GetString("transaction.genesis_coinbase_data")
+	maxDataSize := vip.GetInt("transaction.max_data_size")
 	walletFile := vip.GetString("wallet.file")
 	checkSumLength := vip.GetInt("wallet.check_sum_length")
 	centralNodeAddress := vip.GetString("server.central_node")
 	protocol := vip.GetString("server.protocol")
 	nodeVersion := vip.GetInt("server.node_version")
 	commandLength := vip.GetInt("server.command_length")
+	network := vip.GetString("server.network")
+	apiAddress := vip.GetString("api.address")
+	defaultPageLimit := vip.GetInt("api.default_page_limit")
+	maxPageLimit := vip.GetInt("api.max_page_limit")
+	pollInterval := vip.GetDuration("api.poll_interval")
+	apiSocket := vip.GetString("api.socket")
+	apiAllowedOrigins := vip.GetStringSlice("api.allowed_origins")
+	apiAllowMissingOrigin := vip.GetBool("api.allow_missing_origin")
+	webhookTimeout := vip.GetDuration("webhook.timeout")
+	webhookMaxAttempts := vip.GetInt("webhook.max_attempts")
+	webhookRetryBaseDelay := vip.GetDuration("webhook.retry_base_delay")
+	webhookRetryMaxDelay := vip.GetDuration("webhook.retry_max_delay")
+	lightClientEnabled := vip.GetBool("light_client.enabled")
+	lightClientFullNode := vip.GetString("light_client.full_node")
+	lightClientDbName := vip.GetString("light_client.db_name")
+	lightClientUseFilters := vip.GetBool("light_client.use_filters")
+	mempoolMaxAge := vip.GetDuration("mempool.max_age")
+	mempoolMaxSize := vip.GetInt("mempool.max_size")
+	mempoolMaxBlockSize := vip.GetInt("mempool.max_block_size")
+	snapshotSource := vip.GetString("snapshot.source")
+	snapshotTrustedHash := vip.GetString("snapshot.trusted_hash")
+	backupDir := vip.GetString("backup.dir")
+	backupInterval := vip.GetDuration("backup.interval")
+	backupRetention := vip.GetInt("backup.retention")
 
 	cfg := &model.Config{
 		DatabaseConfig: model.DatabaseConfig{
-			DbName:        dbName,
-			BlocksBucket:  blocksBucket,
-			UTXOSetBucket: utxoSetBucket,
+			DbName:                dbName,
+			BlocksBucket:          blocksBucket,
+			UTXOSetBucket:         utxoSetBucket,
+			IndexBucket:           indexBucket,
+			WebhookBucket:         webhookBucket,
+			WebhookDeliveryBucket: webhookDeliveryBucket,
+			HeadersBucket:         headersBucket,
+			FiltersBucket:         filtersBucket,
+			MempoolBucket:         mempoolBucket,
+			SnapshotBucket:        snapshotBucket,
+			MetaBucket:            metaBucket,
+			PruneDepth:            pruneDepth,
+			AutoMigrate:           autoMigrate,
+			LockTimeout:           lockTimeout,
 		}, ProofOfWorkConfig: model.ProofOfWorkConfig{
 			TargetBits: targetBits,
+			Algorithm:  powAlgorithm,
 		}, TransactionConfig: model.TransactionConfig{
 			Subsidy:             subsidy,
 			GenesisCoinbaseData: genesisCoinbaseData,
+			MaxDataSize:         maxDataSize,
 		}, WalletConfig: model.WalletConfig{
 			WalletFile:     walletFile,
 			CheckSumLength: checkSumLength,
@@ -48,6 +137,36 @@ func LoadConfg() (*model.Config, error) {
 			Protocol:           protocol,
 			NodeVersion:        nodeVersion,
 			CommandLength:      commandLength,
+			Network:            network,
+		}, APIConfig: model.APIConfig{
+			Address:            apiAddress,
+			DefaultPageLimit:   defaultPageLimit,
+			MaxPageLimit:       maxPageLimit,
+			PollInterval:       pollInterval,
+			Socket:             apiSocket,
+			AllowedOrigins:     apiAllowedOrigins,
+			AllowMissingOrigin: apiAllowMissingOrigin,
+		}, WebhookConfig: model.WebhookConfig{
+			Timeout:        webhookTimeout,
+			MaxAttempts:    webhookMaxAttempts,
+			RetryBaseDelay: webhookRetryBaseDelay,
+			RetryMaxDelay:  webhookRetryMaxDelay,
+		}, LightClientConfig: model.LightClientConfig{
+			Enabled:    lightClientEnabled,
+			FullNode:   lightClientFullNode,
+			DbName:     lightClientDbName,
+			UseFilters: lightClientUseFilters,
+		}, MempoolConfig: model.MempoolConfig{
+			MaxAge:       mempoolMaxAge,
+			MaxSize:      mempoolMaxSize,
+			MaxBlockSize: mempoolMaxBlockSize,
+		}, SnapshotConfig: model.SnapshotConfig{
+			Source:      snapshotSource,
+			TrustedHash: snapshotTrustedHash,
+		}, BackupConfig: model.BackupConfig{
+			Dir:       backupDir,
+			Interval:  backupInterval,
+			Retention: backupRetention,
 		},
 	}
 